@@ -0,0 +1,330 @@
+// Package postgres provides PostgreSQL database utilities for the Txova platform.
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"github.com/Dorico-Dynamics/txova-go-core/logging"
+)
+
+// Default advisory lock settings.
+const (
+	// DefaultAdvisoryLockTimeout is the default maximum time to wait for a blocking acquire.
+	DefaultAdvisoryLockTimeout = 5 * time.Second
+	// DefaultAdvisoryLockReleaseTimeout bounds the unlock issued after a failed or
+	// cancelled acquire, and on release when the caller's context is already done.
+	DefaultAdvisoryLockReleaseTimeout = 5 * time.Second
+)
+
+// AdvisoryKey identifies a PostgreSQL advisory lock.
+// PostgreSQL supports either a single bigint key or a pair of int keys;
+// the two key spaces do not overlap.
+type AdvisoryKey struct {
+	id      int64
+	classID int32
+	objID   int32
+	pair    bool
+}
+
+// AdvisoryKeyFromInt64 creates a single bigint advisory lock key.
+func AdvisoryKeyFromInt64(id int64) AdvisoryKey {
+	return AdvisoryKey{id: id}
+}
+
+// AdvisoryKeyFromPair creates a two-part advisory lock key, e.g. (table oid, row id).
+func AdvisoryKeyFromPair(classID, objID int32) AdvisoryKey {
+	return AdvisoryKey{classID: classID, objID: objID, pair: true}
+}
+
+// AdvisoryKeyFromString creates a bigint advisory lock key by hashing s with 64-bit FNV-1a.
+func AdvisoryKeyFromString(s string) AdvisoryKey {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(s))                //nolint:errcheck // hash.Hash.Write never returns an error.
+	return AdvisoryKey{id: int64(h.Sum64())} // #nosec G115 -- wrap-around is intended for hashing
+}
+
+// String returns a human-readable representation of the key for logging.
+func (k AdvisoryKey) String() string {
+	if k.pair {
+		return fmt.Sprintf("%d:%d", k.classID, k.objID)
+	}
+	return fmt.Sprintf("%d", k.id)
+}
+
+// sql builds a SELECT calling the given advisory lock function with this key.
+func (k AdvisoryKey) sql(function string) (string, []any) {
+	if k.pair {
+		return fmt.Sprintf("SELECT %s($1, $2)", function), []any{k.classID, k.objID}
+	}
+	return fmt.Sprintf("SELECT %s($1)", function), []any{k.id}
+}
+
+// AdvisoryLock represents a session-level advisory lock held on a dedicated connection.
+type AdvisoryLock struct {
+	conn   Conn
+	key    AdvisoryKey
+	logger *logging.Logger
+	held   bool
+}
+
+// AdvisoryLocker provides PostgreSQL advisory locks.
+// Its WithLock method has the same shape as redis.Locker.WithLock so the two can be swapped.
+type AdvisoryLocker struct {
+	pool        Pool
+	logger      *logging.Logger
+	keyPrefix   string
+	lockTimeout time.Duration
+}
+
+// AdvisoryLockerOption is a functional option for configuring the AdvisoryLocker.
+type AdvisoryLockerOption func(*AdvisoryLocker)
+
+// WithAdvisoryKeyPrefix sets a prefix hashed together with every string resource name.
+func WithAdvisoryKeyPrefix(prefix string) AdvisoryLockerOption {
+	return func(l *AdvisoryLocker) {
+		l.keyPrefix = prefix
+	}
+}
+
+// WithAdvisoryLockTimeout sets the maximum time to wait for a blocking acquire.
+// Set to 0 to wait until the context is done.
+func WithAdvisoryLockTimeout(timeout time.Duration) AdvisoryLockerOption {
+	return func(l *AdvisoryLocker) {
+		l.lockTimeout = timeout
+	}
+}
+
+// WithAdvisoryLockerLogger sets the logger for the locker.
+func WithAdvisoryLockerLogger(logger *logging.Logger) AdvisoryLockerOption {
+	return func(l *AdvisoryLocker) {
+		l.logger = logger
+	}
+}
+
+// NewAdvisoryLocker creates a new AdvisoryLocker using connections from pool.
+func NewAdvisoryLocker(pool Pool, opts ...AdvisoryLockerOption) *AdvisoryLocker {
+	locker := &AdvisoryLocker{
+		pool:        pool,
+		logger:      logging.Default(),
+		keyPrefix:   "lock",
+		lockTimeout: DefaultAdvisoryLockTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(locker)
+	}
+
+	return locker
+}
+
+// Key returns the advisory key for a string resource name.
+func (l *AdvisoryLocker) Key(resource string) AdvisoryKey {
+	if l.keyPrefix == "" {
+		return AdvisoryKeyFromString(resource)
+	}
+	return AdvisoryKeyFromString(l.keyPrefix + ":" + resource)
+}
+
+// Acquire acquires a session-level lock on the given resource, waiting up to the
+// configured lock timeout. The lock holds a pooled connection until released.
+func (l *AdvisoryLocker) Acquire(ctx context.Context, resource string) (*AdvisoryLock, error) {
+	return l.AcquireKey(ctx, l.Key(resource))
+}
+
+// AcquireKey acquires a session-level lock on the given key, waiting up to the
+// configured lock timeout. Returns a CodeLockFailed error if the lock timeout
+// expires, or a CodeTimeout error if ctx is done first.
+func (l *AdvisoryLocker) AcquireKey(ctx context.Context, key AdvisoryKey) (*AdvisoryLock, error) {
+	conn, err := l.pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	lockCtx := ctx
+	if l.lockTimeout > 0 {
+		var cancel context.CancelFunc
+		lockCtx, cancel = context.WithTimeout(ctx, l.lockTimeout)
+		defer cancel()
+	}
+
+	sql, args := key.sql("pg_advisory_lock")
+	if _, err := conn.Exec(lockCtx, sql, args...); err != nil {
+		l.abandon(ctx, conn, key)
+		return nil, l.acquireError(ctx, lockCtx, key, err)
+	}
+
+	l.logger.DebugContext(ctx, "advisory lock acquired", "key", key.String())
+	return &AdvisoryLock{conn: conn, key: key, logger: l.logger, held: true}, nil
+}
+
+// TryAcquire attempts to acquire a session-level lock without blocking.
+// Returns (nil, nil) if the lock is already held.
+func (l *AdvisoryLocker) TryAcquire(ctx context.Context, resource string) (*AdvisoryLock, error) {
+	return l.TryAcquireKey(ctx, l.Key(resource))
+}
+
+// TryAcquireKey attempts to acquire a session-level lock on key without blocking.
+// Returns (nil, nil) if lock is already held (not an error condition for try-acquire).
+//
+//nolint:nilnil // Intentional: (nil, nil) means "lock not acquired, no error" for try semantics.
+func (l *AdvisoryLocker) TryAcquireKey(ctx context.Context, key AdvisoryKey) (*AdvisoryLock, error) {
+	conn, err := l.pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ok, err := tryAdvisoryLock(ctx, conn, key, "pg_try_advisory_lock")
+	if err != nil {
+		l.abandon(ctx, conn, key)
+		return nil, err
+	}
+	if !ok {
+		conn.Release()
+		l.logger.DebugContext(ctx, "advisory lock acquisition failed", "key", key.String(), "reason", "already held")
+		return nil, nil
+	}
+
+	l.logger.DebugContext(ctx, "advisory lock acquired", "key", key.String())
+	return &AdvisoryLock{conn: conn, key: key, logger: l.logger, held: true}, nil
+}
+
+// WithLock executes a function while holding a session-level lock on resource.
+// The lock is automatically released after the function completes.
+func (l *AdvisoryLocker) WithLock(ctx context.Context, resource string, fn func(ctx context.Context) error) error {
+	return l.WithLockKey(ctx, l.Key(resource), fn)
+}
+
+// WithLockKey executes a function while holding a session-level lock on key.
+func (l *AdvisoryLocker) WithLockKey(ctx context.Context, key AdvisoryKey, fn func(ctx context.Context) error) error {
+	lock, err := l.AcquireKey(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if releaseErr := lock.Release(ctx); releaseErr != nil {
+			l.logger.WarnContext(ctx, "failed to release advisory lock", "key", key.String(), "error", releaseErr.Error())
+		}
+	}()
+
+	return fn(ctx)
+}
+
+// AcquireXact acquires a transaction-level lock on resource within tx,
+// waiting up to the configured lock timeout.
+// The lock is released automatically when tx commits or rolls back.
+func (l *AdvisoryLocker) AcquireXact(ctx context.Context, tx Tx, resource string) error {
+	return l.AcquireXactKey(ctx, tx, l.Key(resource))
+}
+
+// AcquireXactKey acquires a transaction-level lock on key within tx, with the
+// same errors as AcquireKey. If the wait times out the statement is cancelled,
+// which aborts tx.
+func (l *AdvisoryLocker) AcquireXactKey(ctx context.Context, tx Tx, key AdvisoryKey) error {
+	lockCtx := ctx
+	if l.lockTimeout > 0 {
+		var cancel context.CancelFunc
+		lockCtx, cancel = context.WithTimeout(ctx, l.lockTimeout)
+		defer cancel()
+	}
+
+	sql, args := key.sql("pg_advisory_xact_lock")
+	if _, err := tx.Exec(lockCtx, sql, args...); err != nil {
+		return l.acquireError(ctx, lockCtx, key, err)
+	}
+
+	l.logger.DebugContext(ctx, "transaction advisory lock acquired", "key", key.String())
+	return nil
+}
+
+// TryAcquireXact attempts to acquire a transaction-level lock on resource without blocking.
+// Returns false if the lock is already held.
+func (l *AdvisoryLocker) TryAcquireXact(ctx context.Context, tx Tx, resource string) (bool, error) {
+	return l.TryAcquireXactKey(ctx, tx, l.Key(resource))
+}
+
+// TryAcquireXactKey attempts to acquire a transaction-level lock on key without blocking.
+func (l *AdvisoryLocker) TryAcquireXactKey(ctx context.Context, tx Tx, key AdvisoryKey) (bool, error) {
+	return tryAdvisoryLock(ctx, tx, key, "pg_try_advisory_xact_lock")
+}
+
+// acquireError maps the error of a blocking acquire. If the caller's ctx is done,
+// a CodeTimeout error wrapping its cause is returned; if only lockCtx is done, the
+// lock timeout expired and a CodeLockFailed error is returned.
+func (l *AdvisoryLocker) acquireError(ctx, lockCtx context.Context, key AdvisoryKey, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return Wrap(CodeTimeout, "context cancelled during advisory lock acquisition", ctxErr)
+	}
+	if lockCtx.Err() != nil {
+		l.logger.DebugContext(ctx, "advisory lock acquisition timed out", "key", key.String())
+		return Wrap(CodeLockFailed, "advisory lock acquisition timed out", err)
+	}
+	return err
+}
+
+// abandon releases a connection after a failed acquire. The acquire may have been
+// granted just before the error surfaced, so a best-effort unlock is issued first
+// to avoid returning a connection to the pool that still holds the lock. The unlock
+// usually fails because the cancelled acquire broke the connection, so it is only
+// logged at debug level.
+func (l *AdvisoryLocker) abandon(ctx context.Context, conn Conn, key AdvisoryKey) {
+	unlockCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), DefaultAdvisoryLockReleaseTimeout)
+	defer cancel()
+
+	sql, args := key.sql("pg_advisory_unlock")
+	if _, err := conn.Exec(unlockCtx, sql, args...); err != nil {
+		l.logger.DebugContext(ctx, "advisory unlock after failed acquire failed", "key", key.String(), "error", err.Error())
+	}
+	conn.Release()
+}
+
+// tryAdvisoryLock calls a pg_try_advisory_* function and returns its result.
+func tryAdvisoryLock(ctx context.Context, q Querier, key AdvisoryKey, function string) (bool, error) {
+	sql, args := key.sql(function)
+	var ok bool
+	if err := q.QueryRow(ctx, sql, args...).Scan(&ok); err != nil {
+		return false, FromPgError(err)
+	}
+	return ok, nil
+}
+
+// Release releases the lock and returns its connection to the pool.
+// Returns an error if the lock is not held.
+func (lock *AdvisoryLock) Release(ctx context.Context) error {
+	if !lock.held {
+		return LockNotHeld("advisory lock is not held")
+	}
+
+	// Unlock even if the caller's context is already done, so the
+	// connection is not returned to the pool still holding the lock.
+	unlockCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), DefaultAdvisoryLockReleaseTimeout)
+	defer cancel()
+
+	lock.held = false
+	defer lock.conn.Release()
+
+	released, err := tryAdvisoryLock(unlockCtx, lock.conn, lock.key, "pg_advisory_unlock")
+	if err != nil {
+		lock.logger.ErrorContext(ctx, "advisory lock release error", "key", lock.key.String(), "error", err.Error())
+		return err
+	}
+	if !released {
+		lock.logger.WarnContext(ctx, "advisory lock release failed", "key", lock.key.String(), "reason", "not held by session")
+		return LockNotHeld("advisory lock is not held by this session")
+	}
+
+	lock.logger.DebugContext(ctx, "advisory lock released", "key", lock.key.String())
+	return nil
+}
+
+// Key returns the lock key.
+func (lock *AdvisoryLock) Key() AdvisoryKey {
+	return lock.key
+}
+
+// IsHeld returns whether the lock is currently held.
+func (lock *AdvisoryLock) IsHeld() bool {
+	return lock.held
+}
@@ -0,0 +1,347 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+// mockConnPool extends mockPool with an Acquire that hands out connections
+// backed by the same pgxmock instance.
+type mockConnPool struct {
+	*mockPool
+	released int
+}
+
+func newMockConnPool(t *testing.T) (*mockConnPool, pgxmock.PgxPoolIface) {
+	t.Helper()
+	pool, mock := newMockPool(t)
+	return &mockConnPool{mockPool: pool}, mock
+}
+
+func (m *mockConnPool) Acquire(ctx context.Context) (Conn, error) {
+	return &mockConn{pool: m}, nil
+}
+
+// mockConn implements Conn on top of mockConnPool.
+type mockConn struct {
+	pool *mockConnPool
+}
+
+func (c *mockConn) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	return c.pool.Exec(ctx, sql, args...)
+}
+
+func (c *mockConn) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	return c.pool.Query(ctx, sql, args...)
+}
+
+func (c *mockConn) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return c.pool.QueryRow(ctx, sql, args...)
+}
+
+func (c *mockConn) Begin(ctx context.Context) (Tx, error) {
+	return c.pool.Begin(ctx)
+}
+
+func (c *mockConn) BeginTx(ctx context.Context, opts pgx.TxOptions) (Tx, error) {
+	return c.pool.BeginTx(ctx, opts)
+}
+
+func (c *mockConn) Ping(ctx context.Context) error {
+	return c.pool.Ping(ctx)
+}
+
+func (c *mockConn) Release() {
+	c.pool.released++
+}
+
+func TestAdvisoryKey(t *testing.T) {
+	t.Parallel()
+
+	t.Run("string keys are deterministic", func(t *testing.T) {
+		t.Parallel()
+		if AdvisoryKeyFromString("payments") != AdvisoryKeyFromString("payments") {
+			t.Error("expected equal keys for equal strings")
+		}
+		if AdvisoryKeyFromString("payments") == AdvisoryKeyFromString("ledger") {
+			t.Error("expected different keys for different strings")
+		}
+	})
+
+	t.Run("single key sql", func(t *testing.T) {
+		t.Parallel()
+		sql, args := AdvisoryKeyFromInt64(42).sql("pg_advisory_lock")
+		if sql != "SELECT pg_advisory_lock($1)" {
+			t.Errorf("sql = %q", sql)
+		}
+		if len(args) != 1 || args[0] != int64(42) {
+			t.Errorf("args = %v, want [42]", args)
+		}
+	})
+
+	t.Run("pair key sql", func(t *testing.T) {
+		t.Parallel()
+		key := AdvisoryKeyFromPair(7, 99)
+		sql, args := key.sql("pg_try_advisory_xact_lock")
+		if sql != "SELECT pg_try_advisory_xact_lock($1, $2)" {
+			t.Errorf("sql = %q", sql)
+		}
+		if len(args) != 2 || args[0] != int32(7) || args[1] != int32(99) {
+			t.Errorf("args = %v, want [7 99]", args)
+		}
+		if key.String() != "7:99" {
+			t.Errorf("String() = %q, want %q", key.String(), "7:99")
+		}
+	})
+
+	t.Run("prefix changes key", func(t *testing.T) {
+		t.Parallel()
+		pool, mock := newMockPool(t)
+		defer mock.Close()
+
+		plain := NewAdvisoryLocker(pool, WithAdvisoryKeyPrefix(""))
+		prefixed := NewAdvisoryLocker(pool, WithAdvisoryKeyPrefix("billing"))
+		if plain.Key("job") != AdvisoryKeyFromString("job") {
+			t.Error("expected empty prefix to hash the bare resource")
+		}
+		if prefixed.Key("job") != AdvisoryKeyFromString("billing:job") {
+			t.Error("expected prefix to be hashed with the resource")
+		}
+	})
+}
+
+func TestAdvisoryLocker_WithLock(t *testing.T) {
+	t.Parallel()
+
+	pool, mock := newMockConnPool(t)
+	defer mock.Close()
+
+	locker := NewAdvisoryLocker(pool)
+	key := locker.Key("settlement")
+	ctx := context.Background()
+
+	mock.ExpectExec("SELECT pg_advisory_lock").
+		WithArgs(key.id).
+		WillReturnResult(pgxmock.NewResult("SELECT", 1))
+	mock.ExpectQuery("SELECT pg_advisory_unlock").
+		WithArgs(key.id).
+		WillReturnRows(pgxmock.NewRows([]string{"pg_advisory_unlock"}).AddRow(true))
+
+	called := false
+	err := locker.WithLock(ctx, "settlement", func(ctx context.Context) error {
+		called = true
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WithLock() error = %v", err)
+	}
+	if !called {
+		t.Error("expected fn to be called")
+	}
+	if pool.released != 1 {
+		t.Errorf("released = %d, want 1", pool.released)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestAdvisoryLocker_AcquireTimeout(t *testing.T) {
+	t.Parallel()
+
+	pool, mock := newMockConnPool(t)
+	defer mock.Close()
+
+	locker := NewAdvisoryLocker(pool, WithAdvisoryLockTimeout(20*time.Millisecond))
+	key := AdvisoryKeyFromPair(1, 2)
+	ctx := context.Background()
+
+	mock.ExpectExec("SELECT pg_advisory_lock").
+		WithArgs(int32(1), int32(2)).
+		WillReturnResult(pgxmock.NewResult("SELECT", 1)).
+		WillDelayFor(time.Second)
+	mock.ExpectExec("SELECT pg_advisory_unlock").
+		WithArgs(int32(1), int32(2)).
+		WillReturnResult(pgxmock.NewResult("SELECT", 1))
+
+	lock, err := locker.AcquireKey(ctx, key)
+	if !IsLockFailed(err) {
+		t.Fatalf("expected lock failed error, got: %v", err)
+	}
+	if lock != nil {
+		t.Error("expected nil lock")
+	}
+	if pool.released != 1 {
+		t.Errorf("released = %d, want 1", pool.released)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestAdvisoryLocker_AcquireCallerDeadline(t *testing.T) {
+	t.Parallel()
+
+	key := AdvisoryKeyFromPair(1, 2)
+
+	t.Run("session", func(t *testing.T) {
+		t.Parallel()
+		pool, mock := newMockConnPool(t)
+		defer mock.Close()
+
+		locker := NewAdvisoryLocker(pool, WithAdvisoryLockTimeout(time.Minute))
+		mock.ExpectExec("SELECT pg_advisory_lock").
+			WithArgs(int32(1), int32(2)).
+			WillReturnResult(pgxmock.NewResult("SELECT", 1)).
+			WillDelayFor(time.Second)
+		mock.ExpectExec("SELECT pg_advisory_unlock").
+			WithArgs(int32(1), int32(2)).
+			WillReturnResult(pgxmock.NewResult("SELECT", 1))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		_, err := locker.AcquireKey(ctx, key)
+		if !IsTimeout(err) || IsLockFailed(err) || !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("AcquireKey() error = %v, want caller deadline error", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("transaction", func(t *testing.T) {
+		t.Parallel()
+		pool, mock := newMockPool(t)
+		defer mock.Close()
+
+		locker := NewAdvisoryLocker(pool, WithAdvisoryLockTimeout(time.Minute))
+		mock.ExpectBegin()
+		mock.ExpectExec("SELECT pg_advisory_xact_lock").
+			WithArgs(int32(1), int32(2)).
+			WillReturnResult(pgxmock.NewResult("SELECT", 1)).
+			WillDelayFor(time.Second)
+
+		tx, err := pool.Begin(context.Background())
+		if err != nil {
+			t.Fatalf("Begin() error = %v", err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(20*time.Millisecond, cancel)
+		err = locker.AcquireXactKey(ctx, tx, key)
+		if !IsTimeout(err) || IsLockFailed(err) || !errors.Is(err, context.Canceled) {
+			t.Fatalf("AcquireXactKey() error = %v, want caller cancellation error", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unfulfilled expectations: %v", err)
+		}
+	})
+}
+
+func TestAdvisoryLocker_TryAcquire(t *testing.T) {
+	t.Parallel()
+
+	t.Run("already held", func(t *testing.T) {
+		t.Parallel()
+		pool, mock := newMockConnPool(t)
+		defer mock.Close()
+
+		locker := NewAdvisoryLocker(pool)
+		mock.ExpectQuery("SELECT pg_try_advisory_lock").
+			WithArgs(pgxmock.AnyArg()).
+			WillReturnRows(pgxmock.NewRows([]string{"pg_try_advisory_lock"}).AddRow(false))
+
+		lock, err := locker.TryAcquire(context.Background(), "dispatch")
+		if err != nil {
+			t.Fatalf("TryAcquire() error = %v", err)
+		}
+		if lock != nil {
+			t.Error("expected nil lock when already held")
+		}
+		if pool.released != 1 {
+			t.Errorf("released = %d, want 1", pool.released)
+		}
+	})
+
+	t.Run("acquired and released twice", func(t *testing.T) {
+		t.Parallel()
+		pool, mock := newMockConnPool(t)
+		defer mock.Close()
+
+		locker := NewAdvisoryLocker(pool)
+		mock.ExpectQuery("SELECT pg_try_advisory_lock").
+			WithArgs(pgxmock.AnyArg()).
+			WillReturnRows(pgxmock.NewRows([]string{"pg_try_advisory_lock"}).AddRow(true))
+		mock.ExpectQuery("SELECT pg_advisory_unlock").
+			WithArgs(pgxmock.AnyArg()).
+			WillReturnRows(pgxmock.NewRows([]string{"pg_advisory_unlock"}).AddRow(true))
+
+		ctx := context.Background()
+		lock, err := locker.TryAcquire(ctx, "dispatch")
+		if err != nil {
+			t.Fatalf("TryAcquire() error = %v", err)
+		}
+		if lock == nil || !lock.IsHeld() {
+			t.Fatal("expected held lock")
+		}
+		if err := lock.Release(ctx); err != nil {
+			t.Fatalf("Release() error = %v", err)
+		}
+		if err := lock.Release(ctx); !IsLockNotHeld(err) {
+			t.Errorf("second Release() error = %v, want lock not held", err)
+		}
+
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unfulfilled expectations: %v", err)
+		}
+	})
+}
+
+func TestAdvisoryLocker_Xact(t *testing.T) {
+	t.Parallel()
+
+	pool, mock := newMockPool(t)
+	defer mock.Close()
+
+	locker := NewAdvisoryLocker(pool)
+	txMgr := NewTxManager(pool)
+	ctx := context.Background()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT pg_advisory_xact_lock").
+		WithArgs(locker.Key("payouts").id).
+		WillReturnResult(pgxmock.NewResult("SELECT", 1))
+	mock.ExpectQuery("SELECT pg_try_advisory_xact_lock").
+		WithArgs(locker.Key("ledger").id).
+		WillReturnRows(pgxmock.NewRows([]string{"pg_try_advisory_xact_lock"}).AddRow(false))
+	mock.ExpectRollback()
+
+	errBusy := errors.New("ledger busy")
+	err := txMgr.WithTx(ctx, func(tx Tx) error {
+		if err := locker.AcquireXact(ctx, tx, "payouts"); err != nil {
+			return err
+		}
+		ok, err := locker.TryAcquireXact(ctx, tx, "ledger")
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errBusy
+		}
+		return nil
+	})
+	if !errors.Is(err, errBusy) {
+		t.Fatalf("WithTx() error = %v, want %v", err, errBusy)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
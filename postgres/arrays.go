@@ -0,0 +1,204 @@
+// Package postgres provides PostgreSQL database utilities.
+package postgres
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// inList represents a column IN (...) or NOT IN (...) condition. It is rendered
+// at build time so the builder's array threshold applies to all WhereIn calls.
+type inList struct {
+	column    string
+	values    []any
+	negate    bool
+	threshold *int
+}
+
+// render returns the condition with ? placeholders and its args.
+// Lists at or above the threshold are bound as a single array parameter:
+// column = ANY(?) or column <> ALL(?).
+func (l *inList) render() (string, []any) {
+	if l.threshold != nil && *l.threshold > 0 && len(l.values) >= *l.threshold {
+		if l.negate {
+			return quoteIdent(l.column) + " <> ALL(?)", []any{typedSlice(l.values)}
+		}
+		return quoteIdent(l.column) + " = ANY(?)", []any{typedSlice(l.values)}
+	}
+	placeholders := make([]string, len(l.values))
+	for i := range l.values {
+		placeholders[i] = "?"
+	}
+	operator := "IN"
+	if l.negate {
+		operator = "NOT IN"
+	}
+	return fmt.Sprintf("%s %s (%s)", quoteIdent(l.column), operator, strings.Join(placeholders, ", ")), l.values
+}
+
+// typedSlice converts values to a slice of their common type, e.g. []int64,
+// so it can be encoded as a PostgreSQL array. Mixed types are returned as is.
+func typedSlice(values []any) any {
+	if len(values) == 0 || values[0] == nil {
+		return values
+	}
+	elemType := reflect.TypeOf(values[0])
+	slice := reflect.MakeSlice(reflect.SliceOf(elemType), len(values), len(values))
+	for i, v := range values {
+		if v == nil || reflect.TypeOf(v) != elemType {
+			return values
+		}
+		slice.Index(i).Set(reflect.ValueOf(v))
+	}
+	return slice.Interface()
+}
+
+// newInClause creates a where clause for WhereIn and WhereNotIn.
+func newInClause(column string, values []any, negate bool, threshold *int) whereClause {
+	return whereClause{
+		in:  &inList{column: column, values: values, negate: negate, threshold: threshold},
+		err: validateColumnIdentifier(column),
+	}
+}
+
+// newArrayClause creates a where clause comparing a column with an array parameter.
+// A []any is converted to a slice of its element type.
+func newArrayClause(column, operator string, values any) whereClause {
+	if list, ok := values.([]any); ok {
+		values = typedSlice(list)
+	}
+	return whereClause{
+		condition: quoteIdent(column) + " " + operator,
+		args:      []any{values},
+		isArray:   true,
+		err:       validateColumnIdentifier(column),
+	}
+}
+
+// validateArrayArg checks that an array parameter is a slice or array.
+func validateArrayArg(w whereClause) error {
+	if len(w.args) != 1 {
+		return nil
+	}
+	v := reflect.ValueOf(w.args[0])
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return fmt.Errorf("array condition %q requires a slice, got %T", w.condition, w.args[0])
+	}
+	return nil
+}
+
+// ArrayThreshold makes WhereIn and WhereNotIn bind lists of at least n values
+// as a single array parameter (column = ANY($1), column <> ALL($1)) instead of
+// one placeholder per value. This keeps the SQL text stable for statement caching
+// and avoids the 65535 parameter limit. Zero disables it, which is the default.
+func (s *SelectBuilder) ArrayThreshold(n int) *SelectBuilder {
+	s.arrayThreshold = n
+	return s
+}
+
+// WhereInArray adds a WHERE column = ANY(?) condition binding values, a slice, as one array parameter.
+// Unlike WhereIn, an empty slice matches no rows.
+func (s *SelectBuilder) WhereInArray(column string, values any) *SelectBuilder {
+	s.where = append(s.where, newArrayClause(column, "= ANY(?)", values))
+	return s
+}
+
+// WhereNotInArray adds a WHERE column <> ALL(?) condition binding values, a slice, as one array parameter.
+// An empty slice matches all rows.
+func (s *SelectBuilder) WhereNotInArray(column string, values any) *SelectBuilder {
+	s.where = append(s.where, newArrayClause(column, "<> ALL(?)", values))
+	return s
+}
+
+// WhereArrayContains adds a WHERE column @> ? condition: the array column contains all values.
+func (s *SelectBuilder) WhereArrayContains(column string, values any) *SelectBuilder {
+	s.where = append(s.where, newArrayClause(column, "@> ?", values))
+	return s
+}
+
+// WhereArrayOverlaps adds a WHERE column && ? condition: the array column shares a value with values.
+func (s *SelectBuilder) WhereArrayOverlaps(column string, values any) *SelectBuilder {
+	s.where = append(s.where, newArrayClause(column, "&& ?", values))
+	return s
+}
+
+// WhereArrayContainedBy adds a WHERE column <@ ? condition: all elements of the array column are in values.
+func (s *SelectBuilder) WhereArrayContainedBy(column string, values any) *SelectBuilder {
+	s.where = append(s.where, newArrayClause(column, "<@ ?", values))
+	return s
+}
+
+// ArrayThreshold makes WhereIn bind lists of at least n values as a single array parameter.
+// See SelectBuilder.ArrayThreshold.
+func (u *UpdateBuilder) ArrayThreshold(n int) *UpdateBuilder {
+	u.arrayThreshold = n
+	return u
+}
+
+// WhereInArray adds a WHERE column = ANY(?) condition binding values, a slice, as one array parameter.
+func (u *UpdateBuilder) WhereInArray(column string, values any) *UpdateBuilder {
+	u.where = append(u.where, newArrayClause(column, "= ANY(?)", values))
+	return u
+}
+
+// WhereNotInArray adds a WHERE column <> ALL(?) condition binding values, a slice, as one array parameter.
+func (u *UpdateBuilder) WhereNotInArray(column string, values any) *UpdateBuilder {
+	u.where = append(u.where, newArrayClause(column, "<> ALL(?)", values))
+	return u
+}
+
+// WhereArrayContains adds a WHERE column @> ? condition.
+func (u *UpdateBuilder) WhereArrayContains(column string, values any) *UpdateBuilder {
+	u.where = append(u.where, newArrayClause(column, "@> ?", values))
+	return u
+}
+
+// WhereArrayOverlaps adds a WHERE column && ? condition.
+func (u *UpdateBuilder) WhereArrayOverlaps(column string, values any) *UpdateBuilder {
+	u.where = append(u.where, newArrayClause(column, "&& ?", values))
+	return u
+}
+
+// WhereArrayContainedBy adds a WHERE column <@ ? condition.
+func (u *UpdateBuilder) WhereArrayContainedBy(column string, values any) *UpdateBuilder {
+	u.where = append(u.where, newArrayClause(column, "<@ ?", values))
+	return u
+}
+
+// ArrayThreshold makes WhereIn bind lists of at least n values as a single array parameter.
+// See SelectBuilder.ArrayThreshold.
+func (d *DeleteBuilder) ArrayThreshold(n int) *DeleteBuilder {
+	d.arrayThreshold = n
+	return d
+}
+
+// WhereInArray adds a WHERE column = ANY(?) condition binding values, a slice, as one array parameter.
+func (d *DeleteBuilder) WhereInArray(column string, values any) *DeleteBuilder {
+	d.where = append(d.where, newArrayClause(column, "= ANY(?)", values))
+	return d
+}
+
+// WhereNotInArray adds a WHERE column <> ALL(?) condition binding values, a slice, as one array parameter.
+func (d *DeleteBuilder) WhereNotInArray(column string, values any) *DeleteBuilder {
+	d.where = append(d.where, newArrayClause(column, "<> ALL(?)", values))
+	return d
+}
+
+// WhereArrayContains adds a WHERE column @> ? condition.
+func (d *DeleteBuilder) WhereArrayContains(column string, values any) *DeleteBuilder {
+	d.where = append(d.where, newArrayClause(column, "@> ?", values))
+	return d
+}
+
+// WhereArrayOverlaps adds a WHERE column && ? condition.
+func (d *DeleteBuilder) WhereArrayOverlaps(column string, values any) *DeleteBuilder {
+	d.where = append(d.where, newArrayClause(column, "&& ?", values))
+	return d
+}
+
+// WhereArrayContainedBy adds a WHERE column <@ ? condition.
+func (d *DeleteBuilder) WhereArrayContainedBy(column string, values any) *DeleteBuilder {
+	d.where = append(d.where, newArrayClause(column, "<@ ?", values))
+	return d
+}
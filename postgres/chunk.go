@@ -0,0 +1,206 @@
+// Package postgres provides PostgreSQL database utilities.
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// MaxQueryParameters is the maximum number of bind parameters in a PostgreSQL statement.
+const MaxQueryParameters = 65535
+
+// Statement is a built SQL statement and its arguments.
+type Statement struct {
+	SQL  string
+	Args []any
+}
+
+// txBeginner is implemented by queriers that can start a transaction, such as Pool and Conn.
+type txBeginner interface {
+	Querier
+	Begin(ctx context.Context) (Tx, error)
+}
+
+// ChunkedResult is the combined result of InsertBuilder.ExecChunked.
+type ChunkedResult struct {
+	// RowsAffected is the total number of rows inserted.
+	RowsAffected int64
+
+	// Returning holds the RETURNING rows of all chunks in insert order.
+	Returning [][]any
+
+	// Statements is the number of INSERT statements executed, or 1 for COPY.
+	Statements int
+
+	// Copied reports whether the rows were loaded with COPY.
+	Copied bool
+}
+
+// ChunkSize limits the number of rows per statement built by BuildChunks and ExecChunked.
+// Zero, the default, fits as many rows as the parameter limit allows.
+func (i *InsertBuilder) ChunkSize(rows int) *InsertBuilder {
+	i.chunkSize = rows
+	return i
+}
+
+// CopyThreshold makes ExecChunked load at least rows rows with COPY instead of INSERT
+// statements. COPY is only used without RETURNING, ON CONFLICT and WITH, and when the
+// querier implements CopyFromer. Zero disables it, which is the default.
+func (i *InsertBuilder) CopyThreshold(rows int) *InsertBuilder {
+	i.copyThreshold = rows
+	return i
+}
+
+// BuildChunks generates one INSERT statement per chunk of rows, keeping each statement
+// under MaxQueryParameters. All statements share the WITH, ON CONFLICT and RETURNING clauses.
+func (i *InsertBuilder) BuildChunks() ([]Statement, error) {
+	if err := i.validateInsert(); err != nil {
+		return nil, err
+	}
+	size, err := i.rowsPerChunk()
+	if err != nil {
+		return nil, err
+	}
+	statements := make([]Statement, 0, (len(i.values)+size-1)/size)
+	for start := 0; start < len(i.values); start += size {
+		chunk := *i
+		chunk.values = i.values[start:min(start+size, len(i.values))]
+		sql, args, _ := chunk.build(1)
+		statements = append(statements, Statement{SQL: sql, Args: args})
+	}
+	return statements, nil
+}
+
+// rowsPerChunk returns the number of rows that fit in one statement.
+// The builder must have been validated.
+func (i *InsertBuilder) rowsPerChunk() (int, error) {
+	if i.chunkSize < 0 {
+		return 0, fmt.Errorf("chunk size cannot be negative: %d", i.chunkSize)
+	}
+	single := *i
+	single.values = i.values[:1]
+	_, args, _ := single.build(1)
+	fixed := len(args) - len(i.columns)
+	size := (MaxQueryParameters - fixed) / len(i.columns)
+	if size < 1 {
+		return 0, fmt.Errorf("insert has %d parameters outside VALUES, more than the limit of %d", fixed, MaxQueryParameters)
+	}
+	if i.chunkSize > 0 && i.chunkSize < size {
+		size = i.chunkSize
+	}
+	return size, nil
+}
+
+// ExecChunked executes the insert as one or more statements that each stay under
+// the parameter limit, or with COPY above the CopyThreshold, and combines their results.
+// Statements run on the transaction in the context, if any, and on q otherwise.
+// If there is no transaction and q is a Pool or Conn, the statements run in a
+// transaction that ExecChunked commits, so a failure inserts no rows.
+func (i *InsertBuilder) ExecChunked(ctx context.Context, q Querier) (ChunkedResult, error) {
+	var result ChunkedResult
+	q = QuerierFromContext(ctx, q)
+	if q == nil {
+		return result, New(CodeInvalidInput, "querier is required")
+	}
+	if err := i.validateInsert(); err != nil {
+		return result, Wrap(CodeInvalidInput, "invalid insert query", err)
+	}
+
+	if copier, ok := q.(CopyFromer); ok && i.useCopy() {
+		table, _ := parseIdentifier(i.table, maxTableParts)
+		n, err := copier.CopyFrom(ctx, pgx.Identifier(table), copyColumns(i.columns), pgx.CopyFromRows(i.values))
+		if err != nil {
+			return result, asDBError(err)
+		}
+		return ChunkedResult{RowsAffected: n, Statements: 1, Copied: true}, nil
+	}
+
+	statements, err := i.BuildChunks()
+	if err != nil {
+		return result, Wrap(CodeInvalidInput, "invalid insert query", err)
+	}
+	if _, isTx := q.(Tx); !isTx && len(statements) > 1 {
+		if beginner, ok := q.(txBeginner); ok {
+			return i.execChunksInTx(ctx, beginner, statements)
+		}
+	}
+	return i.execChunks(ctx, q, statements)
+}
+
+// execChunksInTx executes the chunk statements in a new transaction.
+// On failure the transaction is rolled back and an empty result is returned.
+func (i *InsertBuilder) execChunksInTx(ctx context.Context, beginner txBeginner, statements []Statement) (ChunkedResult, error) {
+	tx, err := beginner.Begin(ctx)
+	if err != nil {
+		return ChunkedResult{}, asDBError(err)
+	}
+	result, err := i.execChunks(ctx, tx, statements)
+	if err != nil {
+		_ = tx.Rollback(ctx) //nolint:errcheck // Best-effort rollback, chunk error is returned.
+		return ChunkedResult{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return ChunkedResult{}, asDBError(err)
+	}
+	return result, nil
+}
+
+// execChunks executes the chunk statements in order, stopping at the first error.
+func (i *InsertBuilder) execChunks(ctx context.Context, q Querier, statements []Statement) (ChunkedResult, error) {
+	var result ChunkedResult
+	for _, stmt := range statements {
+		if err := i.execChunk(ctx, q, stmt, &result); err != nil {
+			return result, err
+		}
+		result.Statements++
+	}
+	return result, nil
+}
+
+// useCopy reports whether ExecChunked should load the rows with COPY.
+func (i *InsertBuilder) useCopy() bool {
+	return i.copyThreshold > 0 && len(i.values) >= i.copyThreshold &&
+		len(i.returning) == 0 && i.onConflict == nil && len(i.with.ctes) == 0
+}
+
+// execChunk executes one chunk statement and adds its results to result.
+func (i *InsertBuilder) execChunk(ctx context.Context, q Querier, stmt Statement, result *ChunkedResult) error {
+	if len(i.returning) == 0 {
+		tag, err := q.Exec(ctx, stmt.SQL, stmt.Args...)
+		if err != nil {
+			return asDBError(err)
+		}
+		result.RowsAffected += tag.RowsAffected()
+		return nil
+	}
+
+	rows, err := q.Query(ctx, stmt.SQL, stmt.Args...)
+	if err != nil {
+		return asDBError(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		values, err := rows.Values()
+		if err != nil {
+			return asDBError(err)
+		}
+		result.Returning = append(result.Returning, values)
+	}
+	if err := rows.Err(); err != nil {
+		return asDBError(err)
+	}
+	result.RowsAffected += rows.CommandTag().RowsAffected()
+	return nil
+}
+
+// copyColumns returns the column names for COPY, which quotes them itself.
+func copyColumns(columns []string) []string {
+	names := make([]string, len(columns))
+	for i, col := range columns {
+		parts, _ := parseIdentifier(col, maxColumnParts)
+		names[i] = parts[len(parts)-1]
+	}
+	return names
+}
@@ -0,0 +1,246 @@
+package postgres
+
+import (
+	"context"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+func TestInsertBuilder_BuildChunks(t *testing.T) {
+	t.Parallel()
+
+	t.Run("chunk size", func(t *testing.T) {
+		t.Parallel()
+		insert := Insert("events").Columns("id", "kind").ChunkSize(2).
+			OnConflictDoUpdate("id").Set("kind", "dup").
+			Returning("id")
+		for n := 1; n <= 5; n++ {
+			insert.Values(n, "k")
+		}
+
+		statements, err := insert.BuildChunks()
+		if err != nil {
+			t.Fatalf("BuildChunks() error = %v", err)
+		}
+		want := []Statement{
+			{
+				SQL:  "INSERT INTO events (id, kind) VALUES ($1, $2), ($3, $4) ON CONFLICT (id) DO UPDATE SET kind = $5 RETURNING id",
+				Args: []any{1, "k", 2, "k", "dup"},
+			},
+			{
+				SQL:  "INSERT INTO events (id, kind) VALUES ($1, $2), ($3, $4) ON CONFLICT (id) DO UPDATE SET kind = $5 RETURNING id",
+				Args: []any{3, "k", 4, "k", "dup"},
+			},
+			{
+				SQL:  "INSERT INTO events (id, kind) VALUES ($1, $2) ON CONFLICT (id) DO UPDATE SET kind = $3 RETURNING id",
+				Args: []any{5, "k", "dup"},
+			},
+		}
+		if !reflect.DeepEqual(statements, want) {
+			t.Errorf("BuildChunks() =\n%v\nwant\n%v", statements, want)
+		}
+	})
+
+	t.Run("parameter limit", func(t *testing.T) {
+		t.Parallel()
+		insert := Insert("points").Columns("x", "y", "z").OnConflictDoUpdate("x").Set("y", 0)
+		for n := 0; n < 30000; n++ {
+			insert.Values(n, n, n)
+		}
+
+		if _, _, err := insert.Build(); err == nil || !strings.Contains(err.Error(), "use BuildChunks") {
+			t.Errorf("Build() error = %v, want parameter limit error", err)
+		}
+		statements, err := insert.BuildChunks()
+		if err != nil {
+			t.Fatalf("BuildChunks() error = %v", err)
+		}
+		if len(statements) != 2 {
+			t.Fatalf("BuildChunks() returned %d statements, want 2", len(statements))
+		}
+		// (65535 - 1 conflict parameter) / 3 columns = 21844 rows.
+		if got := len(statements[0].Args); got != 21844*3+1 {
+			t.Errorf("first chunk has %d args, want %d", got, 21844*3+1)
+		}
+		if got := len(statements[1].Args); got != (30000-21844)*3+1 {
+			t.Errorf("second chunk has %d args, want %d", got, (30000-21844)*3+1)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+		if _, err := Insert("events").Columns("id").BuildChunks(); err == nil {
+			t.Error("BuildChunks() without values should fail")
+		}
+		if _, err := Insert("events").Columns("id").Values(1).ChunkSize(-1).BuildChunks(); err == nil {
+			t.Error("BuildChunks() with negative chunk size should fail")
+		}
+	})
+}
+
+func TestInsertBuilder_ExecChunked(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	t.Run("exec sums affected rows in one transaction", func(t *testing.T) {
+		t.Parallel()
+		pool, mock := newMockPool(t)
+		defer mock.Close()
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO events (id) VALUES ($1), ($2)")).
+			WithArgs(1, 2).WillReturnResult(pgxmock.NewResult("INSERT", 2))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO events (id) VALUES ($1)")).
+			WithArgs(3).WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mock.ExpectCommit()
+
+		result, err := Insert("events").Columns("id").Values(1).Values(2).Values(3).
+			ChunkSize(2).ExecChunked(ctx, pool)
+		if err != nil {
+			t.Fatalf("ExecChunked() error = %v", err)
+		}
+		if result.RowsAffected != 3 || result.Statements != 2 || result.Copied {
+			t.Errorf("ExecChunked() = %+v", result)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("returning rows are aggregated", func(t *testing.T) {
+		t.Parallel()
+		mock, err := pgxmock.NewPool()
+		if err != nil {
+			t.Fatalf("failed to create mock: %v", err)
+		}
+		defer mock.Close()
+		mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO events (kind) VALUES ($1), ($2) RETURNING id")).
+			WithArgs("a", "b").
+			WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int64(1)).AddRow(int64(2)))
+		mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO events (kind) VALUES ($1) RETURNING id")).
+			WithArgs("c").
+			WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int64(3)))
+
+		result, err := Insert("events").Columns("kind").Values("a").Values("b").Values("c").
+			Returning("id").ChunkSize(2).CopyThreshold(1).ExecChunked(ctx, mock)
+		if err != nil {
+			t.Fatalf("ExecChunked() error = %v", err)
+		}
+		want := [][]any{{int64(1)}, {int64(2)}, {int64(3)}}
+		if !reflect.DeepEqual(result.Returning, want) || result.Copied {
+			t.Errorf("ExecChunked() = %+v, want returning %v", result, want)
+		}
+	})
+
+	t.Run("copy above threshold", func(t *testing.T) {
+		t.Parallel()
+		mock, err := pgxmock.NewPool()
+		if err != nil {
+			t.Fatalf("failed to create mock: %v", err)
+		}
+		defer mock.Close()
+		mock.ExpectCopyFrom(pgx.Identifier{"audit", "events"}, []string{"id", "kind"}).
+			WillReturnResult(3)
+
+		result, err := Insert("audit.events").Columns("id", "kind").
+			Values(1, "a").Values(2, "b").Values(3, "c").
+			CopyThreshold(3).ExecChunked(ctx, mock)
+		if err != nil {
+			t.Fatalf("ExecChunked() error = %v", err)
+		}
+		if !result.Copied || result.RowsAffected != 3 || result.Statements != 1 {
+			t.Errorf("ExecChunked() = %+v", result)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("runs in context transaction", func(t *testing.T) {
+		t.Parallel()
+		pool, mock := newMockPool(t)
+		defer mock.Close()
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO events").WithArgs(1).WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mock.ExpectExec("INSERT INTO events").WithArgs(2).WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+		tx, err := pool.Begin(ctx)
+		if err != nil {
+			t.Fatalf("Begin() error = %v", err)
+		}
+		result, err := Insert("events").Columns("id").Values(1).Values(2).
+			ChunkSize(1).ExecChunked(ContextWithTx(ctx, tx), nil)
+		if err != nil {
+			t.Fatalf("ExecChunked() error = %v", err)
+		}
+		if result.RowsAffected != 2 {
+			t.Errorf("RowsAffected = %d, want 2", result.RowsAffected)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("chunk error rolls back", func(t *testing.T) {
+		t.Parallel()
+		pool, mock := newMockPool(t)
+		defer mock.Close()
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO events").WithArgs(1).WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mock.ExpectExec("INSERT INTO events").WithArgs(2).
+			WillReturnError(&pgconn.PgError{Code: "23505", Message: "duplicate key"})
+		mock.ExpectRollback()
+
+		result, err := Insert("events").Columns("id").Values(1).Values(2).Values(3).
+			ChunkSize(1).ExecChunked(ctx, pool)
+		if !IsDuplicate(err) {
+			t.Errorf("ExecChunked() error = %v, want duplicate", err)
+		}
+		if result.RowsAffected != 0 || result.Statements != 0 {
+			t.Errorf("ExecChunked() = %+v, want an empty result", result)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("chunk error stops execution in context transaction", func(t *testing.T) {
+		t.Parallel()
+		pool, mock := newMockPool(t)
+		defer mock.Close()
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO events").WithArgs(1).WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mock.ExpectExec("INSERT INTO events").WithArgs(2).
+			WillReturnError(&pgconn.PgError{Code: "23505", Message: "duplicate key"})
+
+		tx, err := pool.Begin(ctx)
+		if err != nil {
+			t.Fatalf("Begin() error = %v", err)
+		}
+		result, err := Insert("events").Columns("id").Values(1).Values(2).Values(3).
+			ChunkSize(1).ExecChunked(ContextWithTx(ctx, tx), pool)
+		if !IsDuplicate(err) {
+			t.Errorf("ExecChunked() error = %v, want duplicate", err)
+		}
+		if result.RowsAffected != 1 || result.Statements != 1 {
+			t.Errorf("ExecChunked() = %+v, want the first chunk counted", result)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+		if _, err := Insert("events").Columns("id").Values(1).ExecChunked(ctx, nil); !IsCode(err, CodeInvalidInput) {
+			t.Errorf("ExecChunked() without querier error = %v, want invalid input", err)
+		}
+		pool, mock := newMockPool(t)
+		defer mock.Close()
+		if _, err := Insert("events").ExecChunked(ctx, pool); !IsCode(err, CodeInvalidInput) {
+			t.Errorf("ExecChunked() without columns error = %v, want invalid input", err)
+		}
+	})
+}
@@ -0,0 +1,311 @@
+// Package postgres provides PostgreSQL database utilities.
+package postgres
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Condition is a node of a boolean condition tree used in WHERE and HAVING.
+// Conditions are created with Expr, And, Or, Not and ConditionGroup, and
+// always render with the parentheses needed to preserve their structure.
+type Condition interface {
+	// validate checks that the condition can be built.
+	validate() error
+
+	// build generates the SQL with placeholders numbered from argIndex,
+	// returning the arguments and the next arg index.
+	build(argIndex int) (string, []any, int)
+}
+
+// booleanOperatorRegex detects top-level boolean operators in raw expressions.
+var booleanOperatorRegex = regexp.MustCompile(`(?i)\b(AND|OR)\b`)
+
+// exprCondition is a raw SQL condition with ? placeholders.
+type exprCondition struct {
+	sql  string
+	args []any
+}
+
+// Expr creates a condition from raw SQL.
+// Use ? as placeholder for arguments, they will be converted to $1, $2, etc.
+func Expr(condition string, args ...any) Condition {
+	return exprCondition{sql: condition, args: args}
+}
+
+func (e exprCondition) validate() error {
+	if strings.TrimSpace(e.sql) == "" {
+		return fmt.Errorf("condition cannot be empty")
+	}
+	return nil
+}
+
+func (e exprCondition) build(argIndex int) (string, []any, int) {
+	sql, argIndex := replacePlaceholders(e.sql, argIndex)
+	return sql, e.args, argIndex
+}
+
+// logicalCondition combines conditions with AND or OR.
+type logicalCondition struct {
+	operator   string
+	conditions []Condition
+}
+
+// And combines conditions with AND.
+func And(conditions ...Condition) Condition {
+	return logicalCondition{operator: "AND", conditions: conditions}
+}
+
+// Or combines conditions with OR.
+func Or(conditions ...Condition) Condition {
+	return logicalCondition{operator: "OR", conditions: conditions}
+}
+
+func (l logicalCondition) validate() error {
+	if len(l.conditions) == 0 {
+		return fmt.Errorf("%s condition requires at least one condition", l.operator)
+	}
+	for _, c := range l.conditions {
+		if c == nil {
+			return fmt.Errorf("%s condition contains a nil condition", l.operator)
+		}
+		if err := c.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l logicalCondition) build(argIndex int) (string, []any, int) {
+	if len(l.conditions) == 1 {
+		return buildOperand(l.conditions[0], argIndex)
+	}
+	parts := make([]string, len(l.conditions))
+	var args []any
+	for i, c := range l.conditions {
+		sql, condArgs, newIndex := buildOperand(c, argIndex)
+		parts[i] = sql
+		args = append(args, condArgs...)
+		argIndex = newIndex
+	}
+	return "(" + strings.Join(parts, " "+l.operator+" ") + ")", args, argIndex
+}
+
+// notCondition negates a condition.
+type notCondition struct {
+	condition Condition
+}
+
+// Not negates a condition.
+func Not(condition Condition) Condition {
+	return notCondition{condition: condition}
+}
+
+func (n notCondition) validate() error {
+	if n.condition == nil {
+		return fmt.Errorf("NOT condition cannot be nil")
+	}
+	return n.condition.validate()
+}
+
+func (n notCondition) build(argIndex int) (string, []any, int) {
+	sql, args, argIndex := n.condition.build(argIndex)
+	if !isParenthesized(n.condition) {
+		sql = "(" + sql + ")"
+	}
+	return "NOT " + sql, args, argIndex
+}
+
+// isParenthesized reports whether a condition always renders wrapped in parentheses.
+func isParenthesized(c Condition) bool {
+	switch v := c.(type) {
+	case logicalCondition:
+		if len(v.conditions) == 1 {
+			return isParenthesized(v.conditions[0]) || isBooleanExpr(v.conditions[0])
+		}
+		return len(v.conditions) > 1
+	case *ConditionGroup:
+		return len(v.clauses) > 1
+	default:
+		return false
+	}
+}
+
+// isBooleanExpr reports whether a condition is a raw expression containing boolean operators.
+func isBooleanExpr(c Condition) bool {
+	e, ok := c.(exprCondition)
+	return ok && booleanOperatorRegex.MatchString(e.sql)
+}
+
+// buildOperand builds a condition used as an operand of AND or OR,
+// parenthesizing raw expressions that contain boolean operators.
+func buildOperand(c Condition, argIndex int) (string, []any, int) {
+	sql, args, argIndex := c.build(argIndex)
+	if isBooleanExpr(c) {
+		sql = "(" + sql + ")"
+	}
+	return sql, args, argIndex
+}
+
+// ConditionGroup collects conditions added with Where and OrWhere and renders
+// them as a single parenthesized condition. It is passed to WhereGroup callbacks.
+type ConditionGroup struct {
+	clauses []whereClause
+}
+
+// Where adds a condition with AND logic.
+func (g *ConditionGroup) Where(condition string, args ...any) *ConditionGroup {
+	g.clauses = append(g.clauses, whereClause{condition: condition, args: args, isOr: false})
+	return g
+}
+
+// OrWhere adds a condition with OR logic.
+func (g *ConditionGroup) OrWhere(condition string, args ...any) *ConditionGroup {
+	g.clauses = append(g.clauses, whereClause{condition: condition, args: args, isOr: true})
+	return g
+}
+
+// WhereIn adds a column IN (...) condition with AND logic.
+func (g *ConditionGroup) WhereIn(column string, values ...any) *ConditionGroup {
+	if len(values) == 0 {
+		return g
+	}
+	g.clauses = append(g.clauses, newInClause(column, values, false, nil))
+	return g
+}
+
+// WhereNull adds a column IS NULL condition with AND logic.
+func (g *ConditionGroup) WhereNull(column string) *ConditionGroup {
+	g.clauses = append(g.clauses, newColumnClause(column, "IS NULL"))
+	return g
+}
+
+// WhereNotNull adds a column IS NOT NULL condition with AND logic.
+func (g *ConditionGroup) WhereNotNull(column string) *ConditionGroup {
+	g.clauses = append(g.clauses, newColumnClause(column, "IS NOT NULL"))
+	return g
+}
+
+// WhereCondition adds a condition tree with AND logic.
+func (g *ConditionGroup) WhereCondition(condition Condition) *ConditionGroup {
+	g.clauses = append(g.clauses, whereClause{hasCond: true, cond: condition, isOr: false})
+	return g
+}
+
+// OrWhereCondition adds a condition tree with OR logic.
+func (g *ConditionGroup) OrWhereCondition(condition Condition) *ConditionGroup {
+	g.clauses = append(g.clauses, whereClause{hasCond: true, cond: condition, isOr: true})
+	return g
+}
+
+// WhereGroup adds a nested parenthesized group with AND logic.
+func (g *ConditionGroup) WhereGroup(fn func(g *ConditionGroup)) *ConditionGroup {
+	return g.WhereCondition(newConditionGroup(fn))
+}
+
+// OrWhereGroup adds a nested parenthesized group with OR logic.
+func (g *ConditionGroup) OrWhereGroup(fn func(g *ConditionGroup)) *ConditionGroup {
+	return g.OrWhereCondition(newConditionGroup(fn))
+}
+
+// newConditionGroup creates a ConditionGroup populated by fn.
+func newConditionGroup(fn func(g *ConditionGroup)) *ConditionGroup {
+	g := &ConditionGroup{}
+	if fn != nil {
+		fn(g)
+	}
+	return g
+}
+
+func (g *ConditionGroup) validate() error {
+	if len(g.clauses) == 0 {
+		return fmt.Errorf("condition group cannot be empty")
+	}
+	return validateConditionClauses(g.clauses)
+}
+
+func (g *ConditionGroup) build(argIndex int) (string, []any, int) {
+	sql, args, argIndex := buildConditionClauses(g.clauses, "", argIndex)
+	if len(g.clauses) > 1 || (!g.clauses[0].hasCond && booleanOperatorRegex.MatchString(sql)) {
+		sql = "(" + sql + ")"
+	}
+	return sql, args, argIndex
+}
+
+// WhereCondition adds a condition tree with AND logic.
+func (s *SelectBuilder) WhereCondition(condition Condition) *SelectBuilder {
+	s.where = append(s.where, whereClause{hasCond: true, cond: condition, isOr: false})
+	return s
+}
+
+// OrWhereCondition adds a condition tree with OR logic.
+func (s *SelectBuilder) OrWhereCondition(condition Condition) *SelectBuilder {
+	s.where = append(s.where, whereClause{hasCond: true, cond: condition, isOr: true})
+	return s
+}
+
+// WhereGroup adds a parenthesized group of conditions with AND logic.
+func (s *SelectBuilder) WhereGroup(fn func(g *ConditionGroup)) *SelectBuilder {
+	return s.WhereCondition(newConditionGroup(fn))
+}
+
+// OrWhereGroup adds a parenthesized group of conditions with OR logic.
+func (s *SelectBuilder) OrWhereGroup(fn func(g *ConditionGroup)) *SelectBuilder {
+	return s.OrWhereCondition(newConditionGroup(fn))
+}
+
+// HavingCondition adds a HAVING condition tree with AND logic.
+func (s *SelectBuilder) HavingCondition(condition Condition) *SelectBuilder {
+	s.having = append(s.having, whereClause{hasCond: true, cond: condition, isOr: false})
+	return s
+}
+
+// HavingGroup adds a parenthesized group of HAVING conditions with AND logic.
+func (s *SelectBuilder) HavingGroup(fn func(g *ConditionGroup)) *SelectBuilder {
+	return s.HavingCondition(newConditionGroup(fn))
+}
+
+// WhereCondition adds a condition tree with AND logic.
+func (u *UpdateBuilder) WhereCondition(condition Condition) *UpdateBuilder {
+	u.where = append(u.where, whereClause{hasCond: true, cond: condition, isOr: false})
+	return u
+}
+
+// OrWhereCondition adds a condition tree with OR logic.
+func (u *UpdateBuilder) OrWhereCondition(condition Condition) *UpdateBuilder {
+	u.where = append(u.where, whereClause{hasCond: true, cond: condition, isOr: true})
+	return u
+}
+
+// WhereGroup adds a parenthesized group of conditions with AND logic.
+func (u *UpdateBuilder) WhereGroup(fn func(g *ConditionGroup)) *UpdateBuilder {
+	return u.WhereCondition(newConditionGroup(fn))
+}
+
+// OrWhereGroup adds a parenthesized group of conditions with OR logic.
+func (u *UpdateBuilder) OrWhereGroup(fn func(g *ConditionGroup)) *UpdateBuilder {
+	return u.OrWhereCondition(newConditionGroup(fn))
+}
+
+// WhereCondition adds a condition tree with AND logic.
+func (d *DeleteBuilder) WhereCondition(condition Condition) *DeleteBuilder {
+	d.where = append(d.where, whereClause{hasCond: true, cond: condition, isOr: false})
+	return d
+}
+
+// OrWhereCondition adds a condition tree with OR logic.
+func (d *DeleteBuilder) OrWhereCondition(condition Condition) *DeleteBuilder {
+	d.where = append(d.where, whereClause{hasCond: true, cond: condition, isOr: true})
+	return d
+}
+
+// WhereGroup adds a parenthesized group of conditions with AND logic.
+func (d *DeleteBuilder) WhereGroup(fn func(g *ConditionGroup)) *DeleteBuilder {
+	return d.WhereCondition(newConditionGroup(fn))
+}
+
+// OrWhereGroup adds a parenthesized group of conditions with OR logic.
+func (d *DeleteBuilder) OrWhereGroup(fn func(g *ConditionGroup)) *DeleteBuilder {
+	return d.OrWhereCondition(newConditionGroup(fn))
+}
@@ -67,6 +67,32 @@ func (c *pgxConn) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
 	return row
 }
 
+// SendBatch sends the queued queries of a batch in one round trip.
+// Query errors are returned by the methods of the BatchResults.
+func (c *pgxConn) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	return c.conn.SendBatch(ctx, b)
+}
+
+// CopyFrom copies rows into the table using the COPY protocol.
+func (c *pgxConn) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	start := time.Now()
+	n, err := c.conn.CopyFrom(ctx, tableName, columnNames, rowSrc)
+	duration := time.Since(start)
+
+	copySQL := "COPY " + tableName.Sanitize()
+	c.logSlowQuery(ctx, copySQL, duration)
+
+	if err != nil {
+		c.logger.ErrorContext(ctx, "copy failed",
+			"sql", copySQL,
+			"duration_ms", duration.Milliseconds(),
+			"error", err.Error(),
+		)
+		return n, FromPgError(err)
+	}
+	return n, nil
+}
+
 // logSlowQuery logs a warning if the query duration exceeds the threshold.
 func (c *pgxConn) logSlowQuery(ctx context.Context, sql string, duration time.Duration) {
 	if c.slowQueryThreshold > 0 && duration >= c.slowQueryThreshold {
@@ -0,0 +1,54 @@
+package postgres
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCron_Next(t *testing.T) {
+	t.Parallel()
+
+	from := time.Date(2026, time.March, 14, 10, 7, 30, 0, time.UTC) // Saturday
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{spec: "* * * * *", want: time.Date(2026, time.March, 14, 10, 8, 0, 0, time.UTC)},
+		{spec: "*/15 * * * *", want: time.Date(2026, time.March, 14, 10, 15, 0, 0, time.UTC)},
+		{spec: "0 3 * * *", want: time.Date(2026, time.March, 15, 3, 0, 0, 0, time.UTC)},
+		{spec: "30 9 * * 1-5", want: time.Date(2026, time.March, 16, 9, 30, 0, 0, time.UTC)},
+		{spec: "0 0 * * 7", want: time.Date(2026, time.March, 15, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 12 1,15 * *", want: time.Date(2026, time.March, 15, 12, 0, 0, 0, time.UTC)},
+		{spec: "0 0 31 * *", want: time.Date(2026, time.March, 31, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 0 1 * 1", want: time.Date(2026, time.March, 16, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 0 */2 * 1", want: time.Date(2026, time.March, 23, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 0 1 * */2", want: time.Date(2026, time.August, 1, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 0 29 2 *", want: time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{spec: "@hourly", want: time.Date(2026, time.March, 14, 11, 0, 0, 0, time.UTC)},
+		{spec: "@monthly", want: time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 0 30 2 *", want: time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			t.Parallel()
+			schedule, err := ParseCron(tt.spec)
+			if err != nil {
+				t.Fatalf("ParseCron(%q) error = %v", tt.spec, err)
+			}
+			if got := schedule.Next(from); !got.Equal(tt.want) {
+				t.Errorf("Next() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCron_Invalid(t *testing.T) {
+	t.Parallel()
+
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "*/0 * * * *", "5-1 * * * *", "a * * * *", "@every 5m"} {
+		if _, err := ParseCron(spec); err == nil {
+			t.Errorf("ParseCron(%q) should fail", spec)
+		}
+	}
+}
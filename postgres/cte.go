@@ -0,0 +1,155 @@
+// Package postgres provides PostgreSQL database utilities.
+package postgres
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Builder is a query builder that can be embedded in another query,
+// such as a common table expression.
+// It is implemented by SelectBuilder, CompoundSelectBuilder, InsertBuilder,
+// UpdateBuilder and DeleteBuilder.
+type Builder interface {
+	// Build generates the SQL query and returns it with the arguments.
+	Build() (string, []any, error)
+
+	// validate checks that the builder can be built.
+	validate() error
+
+	// build generates the SQL with placeholders numbered from argIndex,
+	// returning the arguments and the next arg index.
+	build(argIndex int) (string, []any, int)
+}
+
+// cte represents a single common table expression.
+type cte struct {
+	name    string
+	columns []string
+	query   Builder
+}
+
+// withClause represents the WITH clause of a query.
+type withClause struct {
+	recursive bool
+	ctes      []cte
+}
+
+// add appends a common table expression to the WITH clause.
+func (w *withClause) add(name string, query Builder, columns []string, recursive bool) {
+	w.ctes = append(w.ctes, cte{name: name, columns: columns, query: query})
+	if recursive {
+		w.recursive = true
+	}
+}
+
+// validate checks the names and queries of the common table expressions.
+func (w *withClause) validate() error {
+	seen := make(map[string]struct{}, len(w.ctes))
+	for _, c := range w.ctes {
+		if err := validateTableName(c.name); err != nil {
+			return fmt.Errorf("invalid cte name: %w", err)
+		}
+		if _, ok := seen[c.name]; ok {
+			return fmt.Errorf("duplicate cte name: %s", c.name)
+		}
+		seen[c.name] = struct{}{}
+		for _, column := range c.columns {
+			if _, err := parseIdentifier(column, 1); err != nil {
+				return fmt.Errorf("invalid column name in cte %s: %s", c.name, column)
+			}
+		}
+		if c.query == nil {
+			return fmt.Errorf("cte %s has no query", c.name)
+		}
+		if err := c.query.validate(); err != nil {
+			return fmt.Errorf("invalid cte %s: %w", c.name, err)
+		}
+	}
+	return nil
+}
+
+// build generates the WITH portion, including a trailing space,
+// and returns args and next arg index.
+func (w *withClause) build(argIndex int) (string, []any, int) {
+	if len(w.ctes) == 0 {
+		return "", nil, argIndex
+	}
+	var sb strings.Builder
+	var args []any
+	sb.WriteString("WITH ")
+	if w.recursive {
+		sb.WriteString("RECURSIVE ")
+	}
+	for i, c := range w.ctes {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		cteSQL, cteArgs, newIndex := c.query.build(argIndex)
+		sb.WriteString(quoteIdent(c.name))
+		if len(c.columns) > 0 {
+			sb.WriteString("(")
+			sb.WriteString(quoteIdents(c.columns))
+			sb.WriteString(")")
+		}
+		sb.WriteString(" AS (")
+		sb.WriteString(cteSQL)
+		sb.WriteString(")")
+		args = append(args, cteArgs...)
+		argIndex = newIndex
+	}
+	sb.WriteString(" ")
+	return sb.String(), args, argIndex
+}
+
+// With adds a common table expression: WITH name AS (query), or
+// WITH name(columns) AS (query) if columns are given to name its output columns.
+// The query may be any builder, including an INSERT, UPDATE or DELETE with RETURNING.
+func (s *SelectBuilder) With(name string, query Builder, columns ...string) *SelectBuilder {
+	s.with.add(name, query, columns, false)
+	return s
+}
+
+// WithRecursive adds a common table expression and marks the WITH clause RECURSIVE,
+// allowing the query to reference itself. The query is typically a UnionAll of a
+// non-recursive term and a term joining name.
+func (s *SelectBuilder) WithRecursive(name string, query Builder, columns ...string) *SelectBuilder {
+	s.with.add(name, query, columns, true)
+	return s
+}
+
+// With adds a common table expression: WITH name AS (query).
+func (i *InsertBuilder) With(name string, query Builder, columns ...string) *InsertBuilder {
+	i.with.add(name, query, columns, false)
+	return i
+}
+
+// WithRecursive adds a common table expression and marks the WITH clause RECURSIVE.
+func (i *InsertBuilder) WithRecursive(name string, query Builder, columns ...string) *InsertBuilder {
+	i.with.add(name, query, columns, true)
+	return i
+}
+
+// With adds a common table expression: WITH name AS (query).
+func (u *UpdateBuilder) With(name string, query Builder, columns ...string) *UpdateBuilder {
+	u.with.add(name, query, columns, false)
+	return u
+}
+
+// WithRecursive adds a common table expression and marks the WITH clause RECURSIVE.
+func (u *UpdateBuilder) WithRecursive(name string, query Builder, columns ...string) *UpdateBuilder {
+	u.with.add(name, query, columns, true)
+	return u
+}
+
+// With adds a common table expression: WITH name AS (query).
+func (d *DeleteBuilder) With(name string, query Builder, columns ...string) *DeleteBuilder {
+	d.with.add(name, query, columns, false)
+	return d
+}
+
+// WithRecursive adds a common table expression and marks the WITH clause RECURSIVE.
+func (d *DeleteBuilder) WithRecursive(name string, query Builder, columns ...string) *DeleteBuilder {
+	d.with.add(name, query, columns, true)
+	return d
+}
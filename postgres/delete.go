@@ -9,9 +9,11 @@ import (
 // DeleteBuilder builds DELETE queries.
 type DeleteBuilder struct {
 	*QueryBuilder
+	with              withClause
 	table             string
+	using             []join
 	where             []whereClause
-	returning         []string
+	returning         []columnRef
 	allowUnrestricted bool
 }
 
@@ -21,7 +23,7 @@ func Delete(table string) *DeleteBuilder {
 		QueryBuilder: NewQueryBuilder(),
 		table:        table,
 		where:        []whereClause{},
-		returning:    []string{},
+		returning:    []columnRef{},
 	}
 }
 
@@ -31,7 +33,7 @@ func DeleteWithAllowlist(table string, allowedColumns ...string) *DeleteBuilder
 		QueryBuilder: NewQueryBuilder(allowedColumns...),
 		table:        table,
 		where:        []whereClause{},
-		returning:    []string{},
+		returning:    []columnRef{},
 	}
 }
 
@@ -52,18 +54,27 @@ func (d *DeleteBuilder) WhereIn(column string, values ...any) *DeleteBuilder {
 	if len(values) == 0 {
 		return d
 	}
-	placeholders := make([]string, len(values))
-	for i := range values {
-		placeholders[i] = "?"
-	}
-	condition := fmt.Sprintf("%s IN (%s)", column, strings.Join(placeholders, ", "))
-	d.where = append(d.where, whereClause{condition: condition, args: values, isOr: false})
+	d.where = append(d.where, newInClause(column, values, false, &d.arrayThreshold))
+	return d
+}
+
+// Using adds a table to the USING clause: DELETE FROM t USING table WHERE condition.
+// The join condition is ANDed with the WHERE conditions and may be empty.
+// Use ? as placeholder for arguments.
+func (d *DeleteBuilder) Using(table, condition string, args ...any) *DeleteBuilder {
+	d.using = append(d.using, join{table: table, condition: condition, args: args})
 	return d
 }
 
 // Returning specifies columns to return after delete.
 func (d *DeleteBuilder) Returning(columns ...string) *DeleteBuilder {
-	d.returning = append(d.returning, columns...)
+	d.returning = append(d.returning, columnRefs(columns)...)
+	return d
+}
+
+// ReturningRaw adds expressions to return after delete.
+func (d *DeleteBuilder) ReturningRaw(exprs ...Raw) *DeleteBuilder {
+	d.returning = append(d.returning, rawColumnRefs(exprs)...)
 	return d
 }
 
@@ -76,52 +87,78 @@ func (d *DeleteBuilder) AllowUnrestrictedDelete() *DeleteBuilder {
 	return d
 }
 
-// Build generates the SQL query and returns it with the arguments.
-func (d *DeleteBuilder) Build() (string, []any, error) {
+// validate implements Builder.
+func (d *DeleteBuilder) validate() error {
+	return d.validateDelete()
+}
+
+// validateDelete checks that the delete has a valid table and is restricted.
+func (d *DeleteBuilder) validateDelete() error {
+	if err := d.with.validate(); err != nil {
+		return err
+	}
+
 	// Validate table name
 	if err := validateTableName(d.table); err != nil {
-		return "", nil, err
+		return err
+	}
+
+	if err := validateJoinTables(d.using, "using"); err != nil {
+		return err
+	}
+
+	if err := validateConditionClauses(d.where); err != nil {
+		return err
+	}
+	if err := validateReturningColumns(d.QueryBuilder, d.returning); err != nil {
+		return err
 	}
 
 	// Safeguard against unrestricted deletes
-	if len(d.where) == 0 && !d.allowUnrestricted {
-		return "", nil, fmt.Errorf("DELETE without WHERE clause requires explicit opt-in via AllowUnrestrictedDelete()")
+	if len(d.where) == 0 && !hasJoinConditions(d.using) && !d.allowUnrestricted {
+		return fmt.Errorf("DELETE without WHERE clause requires explicit opt-in via AllowUnrestrictedDelete()")
 	}
+	return nil
+}
 
-	var args []any
-	argIndex := 1
+// Build generates the SQL query and returns it with the arguments.
+func (d *DeleteBuilder) Build() (string, []any, error) {
+	if err := d.validateDelete(); err != nil {
+		return "", nil, err
+	}
+	sql, args, _ := d.build(1)
+	return sql, args, nil
+}
 
+// build generates the SQL with placeholders numbered from argIndex,
+// returning the arguments and the next arg index.
+// The builder must have been validated.
+func (d *DeleteBuilder) build(argIndex int) (string, []any, int) {
 	var sb strings.Builder
 
+	// WITH clause
+	withClause, args, argIndex := d.with.build(argIndex)
+	sb.WriteString(withClause)
+
 	// DELETE FROM clause
 	sb.WriteString("DELETE FROM ")
-	sb.WriteString(d.table)
-
-	// WHERE clause
-	if len(d.where) > 0 {
-		sb.WriteString(" WHERE ")
-		for i, w := range d.where {
-			if i > 0 {
-				if w.isOr {
-					sb.WriteString(" OR ")
-				} else {
-					sb.WriteString(" AND ")
-				}
-			}
-			condition, newIndex := replacePlaceholders(w.condition, argIndex)
-			sb.WriteString(condition)
-			args = append(args, w.args...)
-			argIndex = newIndex
-		}
-	}
+	sb.WriteString(quoteIdent(d.table))
+
+	// USING clause
+	sb.WriteString(buildJoinTableList(d.using, "USING"))
+
+	// WHERE clause, including USING join conditions
+	whereClause, whereArgs, argIndex := buildJoinedWhere(d.using, d.where, argIndex)
+	sb.WriteString(whereClause)
+	args = append(args, whereArgs...)
 
 	// RETURNING clause
 	if len(d.returning) > 0 {
 		sb.WriteString(" RETURNING ")
-		sb.WriteString(strings.Join(d.returning, ", "))
+		sb.WriteString(joinColumnRefs(d.returning))
 	}
 
-	return sb.String(), args, nil
+	return sb.String(), args, argIndex
 }
 
 // MustBuild generates the SQL query and panics on error.
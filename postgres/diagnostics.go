@@ -0,0 +1,212 @@
+// Package postgres provides PostgreSQL database utilities for the Txova platform.
+package postgres
+
+import (
+	"context"
+	"time"
+)
+
+// LockDiagnosticsTrigger identifies why lock diagnostics were captured.
+type LockDiagnosticsTrigger string
+
+// Lock diagnostics triggers.
+const (
+	// LockDiagnosticsDeadlock indicates a transaction attempt failed with a deadlock.
+	LockDiagnosticsDeadlock LockDiagnosticsTrigger = "deadlock"
+	// LockDiagnosticsLockTimeout indicates a statement exceeded lock_timeout.
+	LockDiagnosticsLockTimeout LockDiagnosticsTrigger = "lock_timeout"
+	// LockDiagnosticsSlowTx indicates a transaction attempt exceeded the slow transaction threshold.
+	LockDiagnosticsSlowTx LockDiagnosticsTrigger = "slow_transaction"
+	// LockDiagnosticsManual indicates diagnostics were captured by CaptureLockDiagnostics.
+	LockDiagnosticsManual LockDiagnosticsTrigger = "manual"
+)
+
+const (
+	// lockDiagnosticsTimeout bounds the diagnostics query.
+	lockDiagnosticsTimeout = 2 * time.Second
+	// lockDiagnosticsMaxSessions limits the number of sessions captured.
+	lockDiagnosticsMaxSessions = 50
+)
+
+// lockDiagnosticsSQL selects sessions in the current database that are blocked
+// by, or are blocking, another session, with the lock each one is waiting for.
+const lockDiagnosticsSQL = `WITH sessions AS (
+	SELECT pid, pg_blocking_pids(pid) AS blocking_pids, state, wait_event_type, wait_event, query, xact_start
+	FROM pg_stat_activity
+	WHERE datname = current_database() AND pid <> pg_backend_pid()
+)
+SELECT s.pid, s.blocking_pids,
+	COALESCE(s.state, ''), COALESCE(s.wait_event_type, ''), COALESCE(s.wait_event, ''),
+	COALESCE(s.query, ''), s.xact_start,
+	COALESCE(l.locktype, ''), COALESCE(l.mode, ''), COALESCE(l.relation::regclass::text, '')
+FROM sessions s
+LEFT JOIN pg_locks l ON l.pid = s.pid AND NOT l.granted
+WHERE cardinality(s.blocking_pids) > 0
+	OR s.pid IN (SELECT unnest(blocking_pids) FROM sessions)
+ORDER BY s.pid
+LIMIT $1`
+
+// LockDiagnostics is a snapshot of lock contention in the database,
+// built from pg_locks joined with pg_stat_activity.
+type LockDiagnostics struct {
+	// Trigger is the reason the snapshot was captured.
+	Trigger LockDiagnosticsTrigger
+
+	// CapturedAt is when the snapshot was taken.
+	CapturedAt time.Time
+
+	// Sessions are the sessions that are blocked or blocking others.
+	Sessions []LockSession
+}
+
+// LockSession describes a backend involved in lock contention.
+type LockSession struct {
+	// PID is the backend process ID.
+	PID int32
+
+	// BlockingPIDs are the backends holding locks this session waits for.
+	BlockingPIDs []int32
+
+	// State is the backend state, e.g. "active" or "idle in transaction".
+	State string
+
+	// WaitEventType and WaitEvent describe what the backend is waiting on.
+	WaitEventType string
+	WaitEvent     string
+
+	// Query is the most recent query of the backend.
+	Query string
+
+	// XactStart is when the backend's current transaction started, if any.
+	XactStart *time.Time
+
+	// LockType, LockMode and Relation describe the lock being waited for.
+	// They are empty if the session is not waiting for a lock.
+	LockType string
+	LockMode string
+	Relation string
+}
+
+// Blocked reports whether the session is waiting on another session.
+func (s LockSession) Blocked() bool {
+	return len(s.BlockingPIDs) > 0
+}
+
+// CaptureLockDiagnostics captures a snapshot of blocked and blocking sessions.
+// It should be run on a connection other than the one being diagnosed, such as the pool.
+func CaptureLockDiagnostics(ctx context.Context, q Querier) (*LockDiagnostics, error) {
+	rows, err := q.Query(ctx, lockDiagnosticsSQL, lockDiagnosticsMaxSessions)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	diag := &LockDiagnostics{
+		Trigger:    LockDiagnosticsManual,
+		CapturedAt: time.Now(),
+	}
+	for rows.Next() {
+		var s LockSession
+		if err := rows.Scan(
+			&s.PID, &s.BlockingPIDs,
+			&s.State, &s.WaitEventType, &s.WaitEvent,
+			&s.Query, &s.XactStart,
+			&s.LockType, &s.LockMode, &s.Relation,
+		); err != nil {
+			return nil, FromPgError(err)
+		}
+		diag.Sessions = append(diag.Sessions, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, FromPgError(err)
+	}
+	return diag, nil
+}
+
+// lockDiagnosticsTrigger returns the trigger for a failed transaction attempt,
+// or false if the error does not warrant lock diagnostics.
+func lockDiagnosticsTrigger(err error) (LockDiagnosticsTrigger, bool) {
+	dbErr := AsError(err)
+	if dbErr == nil {
+		return "", false
+	}
+	switch {
+	case dbErr.Code() == CodeDeadlock:
+		return LockDiagnosticsDeadlock, true
+	case dbErr.SQLState() == sqlStateLockNotAvailable:
+		return LockDiagnosticsLockTimeout, true
+	}
+	return "", false
+}
+
+// attachLockDiagnostics attaches diagnostics to the database error in err, if any.
+func attachLockDiagnostics(err error, diag *LockDiagnostics) {
+	if dbErr := AsError(err); dbErr != nil && diag != nil {
+		dbErr.lockDiagnostics = diag
+	}
+}
+
+// captureLockDiagnostics captures and logs lock diagnostics on a pooled connection.
+// It runs even if ctx is cancelled, bounded by lockDiagnosticsTimeout.
+func (m *txManager) captureLockDiagnostics(ctx context.Context, trigger LockDiagnosticsTrigger) *LockDiagnostics {
+	captureCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), lockDiagnosticsTimeout)
+	defer cancel()
+
+	diag, err := CaptureLockDiagnostics(captureCtx, m.pool)
+	if err != nil {
+		m.config.Logger.WarnContext(ctx, "failed to capture lock diagnostics",
+			"trigger", string(trigger),
+			"error", err.Error(),
+		)
+		return nil
+	}
+	diag.Trigger = trigger
+
+	m.config.Logger.WarnContext(ctx, "lock diagnostics captured",
+		"trigger", string(trigger),
+		"sessions", len(diag.Sessions),
+	)
+	for _, s := range diag.Sessions {
+		m.config.Logger.WarnContext(ctx, "lock contention",
+			"trigger", string(trigger),
+			"pid", s.PID,
+			"blocking_pids", s.BlockingPIDs,
+			"state", s.State,
+			"wait_event", s.WaitEventType+":"+s.WaitEvent,
+			"lock", s.LockMode+" "+s.LockType+" "+s.Relation,
+			"query", truncateSQL(s.Query),
+		)
+	}
+	return diag
+}
+
+// slowTxWatch captures lock diagnostics once a transaction attempt exceeds
+// the slow transaction threshold, while the attempt is still running.
+type slowTxWatch struct {
+	timer *time.Timer
+	done  chan struct{}
+	diag  *LockDiagnostics
+}
+
+// watchSlowTx starts a slow transaction watch, or returns nil if disabled.
+func (m *txManager) watchSlowTx(ctx context.Context) *slowTxWatch {
+	if m.config.SlowTxThreshold <= 0 {
+		return nil
+	}
+	w := &slowTxWatch{done: make(chan struct{})}
+	w.timer = time.AfterFunc(m.config.SlowTxThreshold, func() {
+		defer close(w.done)
+		w.diag = m.captureLockDiagnostics(ctx, LockDiagnosticsSlowTx)
+	})
+	return w
+}
+
+// stop stops the watch and returns the diagnostics captured, if any.
+// If a capture is in progress, it waits for it to finish.
+func (w *slowTxWatch) stop() *LockDiagnostics {
+	if w == nil || w.timer.Stop() {
+		return nil
+	}
+	<-w.done
+	return w.diag
+}
@@ -0,0 +1,198 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+func lockDiagnosticsRows() *pgxmock.Rows {
+	xactStart := time.Now().Add(-time.Second)
+	return pgxmock.NewRows([]string{
+		"pid", "blocking_pids", "state", "wait_event_type", "wait_event",
+		"query", "xact_start", "locktype", "mode", "relation",
+	}).
+		AddRow(int32(101), []int32{102}, "active", "Lock", "transactionid",
+			"UPDATE accounts SET balance = balance - 10 WHERE id = 1", &xactStart,
+			"transactionid", "ShareLock", "").
+		AddRow(int32(102), []int32{}, "idle in transaction", "Client", "ClientRead",
+			"UPDATE accounts SET balance = balance + 10 WHERE id = 2", &xactStart,
+			"", "", "")
+}
+
+func TestLockDiagnosticsTrigger(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		err      error
+		expected LockDiagnosticsTrigger
+		ok       bool
+	}{
+		{
+			name:     "deadlock",
+			err:      FromPgError(&pgconn.PgError{Code: "40P01", Message: "deadlock detected"}),
+			expected: LockDiagnosticsDeadlock,
+			ok:       true,
+		},
+		{
+			name:     "lock timeout",
+			err:      FromPgError(&pgconn.PgError{Code: "55P03", Message: "canceling statement due to lock timeout"}),
+			expected: LockDiagnosticsLockTimeout,
+			ok:       true,
+		},
+		{
+			name: "serialization failure",
+			err:  FromPgError(&pgconn.PgError{Code: "40001", Message: "could not serialize access"}),
+		},
+		{
+			name: "non database error",
+			err:  errors.New("boom"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			trigger, ok := lockDiagnosticsTrigger(tt.err)
+			if trigger != tt.expected || ok != tt.ok {
+				t.Errorf("lockDiagnosticsTrigger() = %q, %v, want %q, %v", trigger, ok, tt.expected, tt.ok)
+			}
+		})
+	}
+}
+
+func TestError_LockDiagnostics_Wrapped(t *testing.T) {
+	t.Parallel()
+
+	cause := FromPgError(&pgconn.PgError{Code: "40P01", Message: "deadlock detected"})
+	diag := &LockDiagnostics{Trigger: LockDiagnosticsDeadlock}
+	attachLockDiagnostics(cause, diag)
+
+	wrapped := Wrap(CodeDeadlock, "transaction failed after max retries", cause)
+	if wrapped.LockDiagnostics() != diag {
+		t.Error("expected diagnostics to be found through wrapped errors")
+	}
+	if New(CodeDeadlock, "deadlock").LockDiagnostics() != nil {
+		t.Error("expected nil diagnostics when none were captured")
+	}
+}
+
+func TestTxManager_LockDiagnostics_Deadlock(t *testing.T) {
+	t.Parallel()
+
+	pool, mock := newMockPool(t)
+	defer mock.Close()
+
+	txMgr := NewTxManager(pool, WithMaxRetries(0), WithLockDiagnostics(true))
+	ctx := context.Background()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE").
+		WillReturnError(&pgconn.PgError{Code: "40P01", Message: "deadlock detected"})
+	mock.ExpectRollback()
+	mock.ExpectQuery("FROM pg_stat_activity").
+		WithArgs(lockDiagnosticsMaxSessions).
+		WillReturnRows(lockDiagnosticsRows())
+
+	err := txMgr.WithTx(ctx, func(tx Tx) error {
+		_, err := tx.Exec(ctx, "UPDATE accounts SET balance = 0")
+		return err
+	})
+	if !IsDeadlock(err) {
+		t.Fatalf("WithTx() error = %v, want deadlock", err)
+	}
+
+	diag := AsError(err).LockDiagnostics()
+	if diag == nil {
+		t.Fatal("expected lock diagnostics to be attached")
+	}
+	if diag.Trigger != LockDiagnosticsDeadlock {
+		t.Errorf("Trigger = %q, want %q", diag.Trigger, LockDiagnosticsDeadlock)
+	}
+	if len(diag.Sessions) != 2 {
+		t.Fatalf("len(Sessions) = %d, want 2", len(diag.Sessions))
+	}
+	if !diag.Sessions[0].Blocked() || diag.Sessions[0].BlockingPIDs[0] != 102 {
+		t.Errorf("Sessions[0] = %+v, want blocked by 102", diag.Sessions[0])
+	}
+	if diag.Sessions[1].Blocked() {
+		t.Errorf("Sessions[1] = %+v, want not blocked", diag.Sessions[1])
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestTxManager_LockDiagnostics_CaptureFailure(t *testing.T) {
+	t.Parallel()
+
+	pool, mock := newMockPool(t)
+	defer mock.Close()
+
+	txMgr := NewTxManager(pool, WithMaxRetries(0), WithLockDiagnostics(true))
+	ctx := context.Background()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE").
+		WillReturnError(&pgconn.PgError{Code: "55P03", Message: "canceling statement due to lock timeout"})
+	mock.ExpectRollback()
+	mock.ExpectQuery("FROM pg_stat_activity").
+		WithArgs(lockDiagnosticsMaxSessions).
+		WillReturnError(errors.New("too many connections"))
+
+	err := txMgr.WithTx(ctx, func(tx Tx) error {
+		_, err := tx.Exec(ctx, "UPDATE accounts SET balance = 0")
+		return err
+	})
+	dbErr := AsError(err)
+	if dbErr == nil || dbErr.SQLState() != "55P03" {
+		t.Fatalf("WithTx() error = %v, want lock timeout", err)
+	}
+	if dbErr.LockDiagnostics() != nil {
+		t.Error("expected no diagnostics when capture fails")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestTxManager_LockDiagnostics_SlowTx(t *testing.T) {
+	t.Parallel()
+
+	pool, mock := newMockPool(t)
+	defer mock.Close()
+
+	txMgr := NewTxManager(pool, WithMaxRetries(0), WithSlowTxThreshold(5*time.Millisecond))
+	ctx := context.Background()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("FROM pg_stat_activity").
+		WithArgs(lockDiagnosticsMaxSessions).
+		WillReturnRows(lockDiagnosticsRows())
+	mock.ExpectRollback()
+
+	errSlow := New(CodeInternal, "gave up")
+	err := txMgr.WithTx(ctx, func(tx Tx) error {
+		time.Sleep(100 * time.Millisecond)
+		return errSlow
+	})
+	if !errors.Is(err, errSlow) {
+		t.Fatalf("WithTx() error = %v, want %v", err, errSlow)
+	}
+
+	diag := errSlow.LockDiagnostics()
+	if diag == nil || diag.Trigger != LockDiagnosticsSlowTx {
+		t.Fatalf("LockDiagnostics() = %+v, want slow transaction snapshot", diag)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
@@ -51,6 +51,16 @@ const (
 	// CodeInvalidInput indicates invalid input data.
 	// Maps to core.CodeValidationError (HTTP 400).
 	CodeInvalidInput Code = "DB_INVALID_INPUT"
+	// CodeLockFailed indicates an advisory lock could not be acquired.
+	// Maps to core.CodeConflict (HTTP 409).
+	CodeLockFailed Code = "DB_LOCK_FAILED"
+	// CodeLockNotHeld indicates an advisory lock is not held by the caller.
+	// Maps to core.CodeConflict (HTTP 409).
+	CodeLockNotHeld Code = "DB_LOCK_NOT_HELD"
+	// CodeLockNotAvailable indicates a row or table lock could not be acquired
+	// immediately (NOWAIT) or within lock_timeout.
+	// Maps to core.CodeConflict (HTTP 409).
+	CodeLockNotAvailable Code = "DB_LOCK_NOT_AVAILABLE"
 	// CodeInternal indicates an unclassified internal database error.
 	// Maps to core.CodeInternalError (HTTP 500).
 	CodeInternal Code = "DB_INTERNAL"
@@ -64,16 +74,19 @@ func (c Code) String() string {
 // coreCodeMapping maps database error codes to core application error codes.
 // This enables unified error handling across the application.
 var coreCodeMapping = map[Code]coreerrors.Code{
-	CodeNotFound:       coreerrors.CodeNotFound,
-	CodeDuplicate:      coreerrors.CodeConflict,
-	CodeForeignKey:     coreerrors.CodeConflict,
-	CodeCheckViolation: coreerrors.CodeValidationError,
-	CodeConnection:     coreerrors.CodeServiceUnavailable,
-	CodeTimeout:        coreerrors.CodeServiceUnavailable,
-	CodeSerialization:  coreerrors.CodeConflict,
-	CodeDeadlock:       coreerrors.CodeConflict,
-	CodeInvalidInput:   coreerrors.CodeValidationError,
-	CodeInternal:       coreerrors.CodeInternalError,
+	CodeNotFound:         coreerrors.CodeNotFound,
+	CodeDuplicate:        coreerrors.CodeConflict,
+	CodeForeignKey:       coreerrors.CodeConflict,
+	CodeCheckViolation:   coreerrors.CodeValidationError,
+	CodeConnection:       coreerrors.CodeServiceUnavailable,
+	CodeTimeout:          coreerrors.CodeServiceUnavailable,
+	CodeSerialization:    coreerrors.CodeConflict,
+	CodeDeadlock:         coreerrors.CodeConflict,
+	CodeInvalidInput:     coreerrors.CodeValidationError,
+	CodeLockFailed:       coreerrors.CodeConflict,
+	CodeLockNotHeld:      coreerrors.CodeConflict,
+	CodeLockNotAvailable: coreerrors.CodeConflict,
+	CodeInternal:         coreerrors.CodeInternalError,
 }
 
 // CoreCode returns the corresponding core.Code for this database error code.
@@ -96,6 +109,7 @@ type Error struct {
 	tableName            string // Table name if available
 	column               string // Column name if available
 	constraint           string // Constraint name if available
+	lockDiagnostics      *LockDiagnostics
 }
 
 // New creates a new Error with the given code and message.
@@ -168,6 +182,19 @@ func (e *Error) Constraint() string {
 	return e.constraint
 }
 
+// LockDiagnostics returns the lock diagnostics captured when this error occurred,
+// searching wrapped database errors. Returns nil if none were captured.
+// Diagnostics are captured by TxManager when lock diagnostics are enabled.
+func (e *Error) LockDiagnostics() *LockDiagnostics {
+	if e.lockDiagnostics != nil {
+		return e.lockDiagnostics
+	}
+	if cause := AsError(e.Unwrap()); cause != nil {
+		return cause.LockDiagnostics()
+	}
+	return nil
+}
+
 // Unwrap returns the wrapped error, if any.
 // This enables errors.Unwrap() and errors.Is() to work correctly.
 func (e *Error) Unwrap() error {
@@ -225,6 +252,9 @@ const (
 	// Class 40 - Transaction Rollback.
 	sqlStateSerializationFailure = "40001"
 	sqlStateDeadlockDetected     = "40P01"
+	// Class 55 - Object Not In Prerequisite State.
+	// Raised when lock_timeout is exceeded or NOWAIT fails.
+	sqlStateLockNotAvailable = "55P03"
 	// Class 57 - Operator Intervention.
 	sqlStateQueryCanceled = "57014"
 )
@@ -272,6 +302,8 @@ func mapSQLState(sqlState string) Code {
 		return CodeSerialization
 	case sqlStateDeadlockDetected:
 		return CodeDeadlock
+	case sqlStateLockNotAvailable:
+		return CodeLockNotAvailable
 	case sqlStateQueryCanceled:
 		return CodeTimeout
 	}
@@ -362,6 +394,22 @@ func IsDeadlock(err error) bool {
 	return IsCode(err, CodeDeadlock)
 }
 
+// IsLockFailed checks if the error is an advisory lock acquisition failure.
+func IsLockFailed(err error) bool {
+	return IsCode(err, CodeLockFailed)
+}
+
+// IsLockNotHeld checks if the error indicates an advisory lock is not held.
+func IsLockNotHeld(err error) bool {
+	return IsCode(err, CodeLockNotHeld)
+}
+
+// IsLockNotAvailable checks if the error is a row or table lock that could not be
+// acquired, e.g. a FOR UPDATE NOWAIT on a locked row or an exceeded lock_timeout.
+func IsLockNotAvailable(err error) bool {
+	return IsCode(err, CodeLockNotAvailable)
+}
+
 // Convenience constructors.
 
 // NotFound creates a new not found error with the given message.
@@ -409,6 +457,16 @@ func TimeoutWrap(message string, cause error) *Error {
 	return Wrap(CodeTimeout, message, cause)
 }
 
+// LockFailed creates a new lock acquisition failure error.
+func LockFailed(message string) *Error {
+	return New(CodeLockFailed, message)
+}
+
+// LockNotHeld creates a new lock not held error.
+func LockNotHeld(message string) *Error {
+	return New(CodeLockNotHeld, message)
+}
+
 // Internal creates a new internal error.
 func Internal(message string) *Error {
 	return New(CodeInternal, message)
@@ -24,6 +24,9 @@ func TestCode_String(t *testing.T) {
 		{CodeSerialization, "DB_SERIALIZATION"},
 		{CodeDeadlock, "DB_DEADLOCK"},
 		{CodeInvalidInput, "DB_INVALID_INPUT"},
+		{CodeLockFailed, "DB_LOCK_FAILED"},
+		{CodeLockNotHeld, "DB_LOCK_NOT_HELD"},
+		{CodeLockNotAvailable, "DB_LOCK_NOT_AVAILABLE"},
 		{CodeInternal, "DB_INTERNAL"},
 	}
 
@@ -164,6 +167,7 @@ func TestMapSQLState(t *testing.T) {
 		{"not null violation", "23502", CodeInvalidInput},
 		{"serialization failure", "40001", CodeSerialization},
 		{"deadlock detected", "40P01", CodeDeadlock},
+		{"lock not available", "55P03", CodeLockNotAvailable},
 		{"query canceled", "57014", CodeTimeout},
 		{"connection exception", "08000", CodeConnection},
 		{"connection exception 08001", "08001", CodeConnection},
@@ -204,6 +208,18 @@ func TestFromPgError(t *testing.T) {
 		}
 	})
 
+	t.Run("pg lock not available", func(t *testing.T) {
+		t.Parallel()
+		got := FromPgError(&pgconn.PgError{Code: "55P03", Message: "could not obtain lock on row in relation \"jobs\""})
+
+		if !IsLockNotAvailable(got) {
+			t.Errorf("IsLockNotAvailable() = false for %v", got)
+		}
+		if IsLockFailed(got) || IsTimeout(got) {
+			t.Errorf("lock not available error matched another code: %v", got.Code())
+		}
+	})
+
 	t.Run("pg unique violation", func(t *testing.T) {
 		t.Parallel()
 		pgErr := &pgconn.PgError{
@@ -464,6 +480,8 @@ func TestConvenienceConstructors(t *testing.T) {
 		{"ForeignKey", ForeignKey("fk error"), CodeForeignKey, "fk error"},
 		{"Connection", Connection("conn error"), CodeConnection, "conn error"},
 		{"Timeout", Timeout("timeout"), CodeTimeout, "timeout"},
+		{"LockFailed", LockFailed("lock held"), CodeLockFailed, "lock held"},
+		{"LockNotHeld", LockNotHeld("not held"), CodeLockNotHeld, "not held"},
 		{"Internal", Internal("internal"), CodeInternal, "internal"},
 	}
 
@@ -620,6 +638,9 @@ func TestCoreCodeMapping(t *testing.T) {
 		{CodeSerialization, coreerrors.CodeConflict},
 		{CodeDeadlock, coreerrors.CodeConflict},
 		{CodeInvalidInput, coreerrors.CodeValidationError},
+		{CodeLockFailed, coreerrors.CodeConflict},
+		{CodeLockNotHeld, coreerrors.CodeConflict},
+		{CodeLockNotAvailable, coreerrors.CodeConflict},
 		{CodeInternal, coreerrors.CodeInternalError},
 	}
 
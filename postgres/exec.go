@@ -0,0 +1,165 @@
+// Package postgres provides PostgreSQL database utilities.
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ScanFunc scans the current row of a result, e.g. func(row Scanner) error { return row.Scan(&id) }.
+type ScanFunc func(row Scanner) error
+
+// buildQuery builds a query, reporting build errors as CodeInvalidInput.
+func buildQuery(builder Builder) (string, []any, error) {
+	sql, args, err := builder.Build()
+	if err != nil {
+		if AsError(err) != nil {
+			return "", nil, err
+		}
+		return "", nil, Wrap(CodeInvalidInput, "invalid query", err)
+	}
+	return sql, args, nil
+}
+
+// queryRows builds and runs a query, calls scan for each row, up to limit rows
+// if limit is positive, and returns the number of rows scanned. Rows are always closed.
+func queryRows(ctx context.Context, q Querier, builder Builder, scan ScanFunc, limit int) (int, error) {
+	sql, args, err := buildQuery(builder)
+	if err != nil {
+		return 0, err
+	}
+	rows, err := q.Query(ctx, sql, args...)
+	if err != nil {
+		return 0, asDBError(err)
+	}
+	defer rows.Close()
+
+	n := 0
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return n, asDBError(err)
+		}
+		n++
+		if n == limit {
+			break
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return n, asDBError(err)
+	}
+	return n, nil
+}
+
+// execQuery builds and executes a query and returns the number of rows affected.
+func execQuery(ctx context.Context, q Querier, builder Builder) (int64, error) {
+	sql, args, err := buildQuery(builder)
+	if err != nil {
+		return 0, err
+	}
+	tag, err := q.Exec(ctx, sql, args...)
+	if err != nil {
+		return 0, asDBError(err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// QueryAll runs the select and calls scan for each row.
+func (s *SelectBuilder) QueryAll(ctx context.Context, q Querier, scan ScanFunc) error {
+	_, err := queryRows(ctx, q, s, scan, 0)
+	return err
+}
+
+// QueryOne runs the select and scans its first row.
+// Returns a CodeNotFound error wrapping pgx.ErrNoRows if there are no rows.
+func (s *SelectBuilder) QueryOne(ctx context.Context, q Querier, scan ScanFunc) error {
+	n, err := queryRows(ctx, q, s, scan, 1)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return Wrap(CodeNotFound, "no rows in result set", pgx.ErrNoRows)
+	}
+	return nil
+}
+
+// Exists reports whether the select returns any row, using SELECT EXISTS (query).
+func (s *SelectBuilder) Exists(ctx context.Context, q Querier) (bool, error) {
+	sql, args, err := buildQuery(s)
+	if err != nil {
+		return false, err
+	}
+	var exists bool
+	if err := q.QueryRow(ctx, "SELECT EXISTS ("+sql+")", args...).Scan(&exists); err != nil {
+		return false, asDBError(err)
+	}
+	return exists, nil
+}
+
+// Exec executes the insert and returns the number of rows inserted.
+func (i *InsertBuilder) Exec(ctx context.Context, q Querier) (int64, error) {
+	return execQuery(ctx, q, i)
+}
+
+// ExecReturning executes the insert and calls scan for each row of its RETURNING
+// clause, returning the number of rows inserted. Returning must be set.
+// Returns a CodeDuplicate error if no row was inserted because of ON CONFLICT.
+func (i *InsertBuilder) ExecReturning(ctx context.Context, q Querier, scan ScanFunc) (int64, error) {
+	if len(i.returning) == 0 {
+		return 0, New(CodeInvalidInput, "ExecReturning requires a RETURNING clause")
+	}
+	n, err := queryRows(ctx, q, i, scan, 0)
+	if err != nil {
+		return int64(n), err
+	}
+	if n == 0 {
+		return 0, New(CodeDuplicate, fmt.Sprintf("no row inserted into %s", i.table))
+	}
+	return int64(n), nil
+}
+
+// Exec executes the update and returns the number of rows updated.
+func (u *UpdateBuilder) Exec(ctx context.Context, q Querier) (int64, error) {
+	return execQuery(ctx, q, u)
+}
+
+// ExecReturning executes the update and calls scan for each row of its RETURNING
+// clause, returning the number of rows updated. Returning must be set.
+// Returns a CodeNotFound error wrapping pgx.ErrNoRows if no row was updated.
+func (u *UpdateBuilder) ExecReturning(ctx context.Context, q Querier, scan ScanFunc) (int64, error) {
+	if len(u.returning) == 0 {
+		return 0, New(CodeInvalidInput, "ExecReturning requires a RETURNING clause")
+	}
+	n, err := queryRows(ctx, q, u, scan, 0)
+	if err != nil {
+		return int64(n), err
+	}
+	if n == 0 {
+		return 0, Wrap(CodeNotFound, fmt.Sprintf("%s not found", u.table), pgx.ErrNoRows)
+	}
+	return int64(n), nil
+}
+
+// Exec executes the delete and returns the number of rows deleted.
+func (d *DeleteBuilder) Exec(ctx context.Context, q Querier) (int64, error) {
+	return execQuery(ctx, q, d)
+}
+
+// ExecReturning executes the delete and calls scan for each row of its RETURNING
+// clause, returning the number of rows deleted. Returning must be set.
+// Returns a CodeNotFound error wrapping pgx.ErrNoRows if no row was deleted.
+func (d *DeleteBuilder) ExecReturning(ctx context.Context, q Querier, scan ScanFunc) (int64, error) {
+	if len(d.returning) == 0 {
+		return 0, New(CodeInvalidInput, "ExecReturning requires a RETURNING clause")
+	}
+	n, err := queryRows(ctx, q, d, scan, 0)
+	if err != nil {
+		return int64(n), err
+	}
+	if n == 0 {
+		return 0, Wrap(CodeNotFound, fmt.Sprintf("%s not found", d.table), pgx.ErrNoRows)
+	}
+	return int64(n), nil
+}
@@ -0,0 +1,284 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+func TestSelectBuilder_QueryAll(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	query := regexp.QuoteMeta("SELECT id, name FROM users WHERE active = $1")
+
+	t.Run("scans all rows", func(t *testing.T) {
+		t.Parallel()
+		pool, mock := newMockPool(t)
+		defer mock.Close()
+
+		rows := pgxmock.NewRows([]string{"id", "name"}).AddRow(int64(1), "Ana").AddRow(int64(2), "Rui")
+		mock.ExpectQuery(query).WithArgs(true).WillReturnRows(rows).RowsWillBeClosed()
+
+		var names []string
+		err := Select("users").Columns("id", "name").Where("active = ?", true).
+			QueryAll(ctx, pool, func(row Scanner) error {
+				var id int64
+				var name string
+				if err := row.Scan(&id, &name); err != nil {
+					return err
+				}
+				names = append(names, name)
+				return nil
+			})
+		if err != nil {
+			t.Fatalf("QueryAll() error = %v", err)
+		}
+		if len(names) != 2 || names[1] != "Rui" {
+			t.Errorf("names = %v", names)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("scan error closes rows", func(t *testing.T) {
+		t.Parallel()
+		pool, mock := newMockPool(t)
+		defer mock.Close()
+
+		rows := pgxmock.NewRows([]string{"id", "name"}).AddRow(int64(1), "Ana").AddRow(int64(2), "Rui")
+		mock.ExpectQuery(query).WithArgs(true).WillReturnRows(rows).RowsWillBeClosed()
+
+		scanErr := errors.New("boom")
+		err := Select("users").Columns("id", "name").Where("active = ?", true).
+			QueryAll(ctx, pool, func(Scanner) error { return scanErr })
+		if !errors.Is(err, scanErr) || AsError(err) == nil {
+			t.Errorf("QueryAll() error = %v, want mapped scan error", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("query error is mapped", func(t *testing.T) {
+		t.Parallel()
+		pool, mock := newMockPool(t)
+		defer mock.Close()
+
+		mock.ExpectQuery(query).WithArgs(true).WillReturnError(&pgconn.PgError{Code: "57014"})
+
+		err := Select("users").Columns("id", "name").Where("active = ?", true).
+			QueryAll(ctx, pool, func(Scanner) error { return nil })
+		if !IsTimeout(err) {
+			t.Errorf("QueryAll() error = %v, want timeout", err)
+		}
+	})
+
+	t.Run("build error", func(t *testing.T) {
+		t.Parallel()
+		pool, mock := newMockPool(t)
+		defer mock.Close()
+
+		err := Select("users").Columns("lower(name)").QueryAll(ctx, pool, func(Scanner) error { return nil })
+		if !IsCode(err, CodeInvalidInput) {
+			t.Errorf("QueryAll() error = %v, want CodeInvalidInput", err)
+		}
+	})
+}
+
+func TestSelectBuilder_QueryOne(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	query := regexp.QuoteMeta("SELECT name FROM users WHERE id = $1")
+
+	t.Run("scans first row", func(t *testing.T) {
+		t.Parallel()
+		pool, mock := newMockPool(t)
+		defer mock.Close()
+
+		rows := pgxmock.NewRows([]string{"name"}).AddRow("Ana").AddRow("Rui")
+		mock.ExpectQuery(query).WithArgs(1).WillReturnRows(rows).RowsWillBeClosed()
+
+		var name string
+		err := Select("users").Columns("name").Where("id = ?", 1).
+			QueryOne(ctx, pool, func(row Scanner) error { return row.Scan(&name) })
+		if err != nil || name != "Ana" {
+			t.Errorf("QueryOne() = %q, %v", name, err)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		t.Parallel()
+		pool, mock := newMockPool(t)
+		defer mock.Close()
+
+		mock.ExpectQuery(query).WithArgs(1).WillReturnRows(pgxmock.NewRows([]string{"name"}))
+
+		err := Select("users").Columns("name").Where("id = ?", 1).
+			QueryOne(ctx, pool, func(Scanner) error { return nil })
+		if !IsNotFound(err) || !errors.Is(err, pgx.ErrNoRows) {
+			t.Errorf("QueryOne() error = %v, want not found", err)
+		}
+	})
+}
+
+func TestSelectBuilder_Exists(t *testing.T) {
+	t.Parallel()
+	pool, mock := newMockPool(t)
+	defer mock.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT EXISTS (SELECT 1 FROM trips WHERE driver_id = $1)")).
+		WithArgs(7).
+		WillReturnRows(pgxmock.NewRows([]string{"exists"}).AddRow(true))
+
+	exists, err := Select("trips").ColumnsRaw("1").Where("driver_id = ?", 7).Exists(context.Background(), pool)
+	if err != nil || !exists {
+		t.Errorf("Exists() = %v, %v", exists, err)
+	}
+}
+
+func TestBuilders_Exec(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		exec    func(ctx context.Context, q Querier) (int64, error)
+		wantSQL string
+		args    []any
+		rows    int64
+	}{
+		{
+			name:    "insert",
+			exec:    Insert("users").Columns("name").Values("Ana").Values("Rui").Exec,
+			wantSQL: "INSERT INTO users (name) VALUES ($1), ($2)",
+			args:    []any{"Ana", "Rui"},
+			rows:    2,
+		},
+		{
+			name:    "update",
+			exec:    Update("users").Set("active", false).Where("id = ?", 1).Exec,
+			wantSQL: "UPDATE users SET active = $1 WHERE id = $2",
+			args:    []any{false, 1},
+			rows:    1,
+		},
+		{
+			name:    "delete",
+			exec:    Delete("users").Where("id = ?", 1).Exec,
+			wantSQL: "DELETE FROM users WHERE id = $1",
+			args:    []any{1},
+			rows:    0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			pool, mock := newMockPool(t)
+			defer mock.Close()
+
+			mock.ExpectExec(regexp.QuoteMeta(tt.wantSQL)).WithArgs(tt.args...).WillReturnResult(pgxmock.NewResult("OK", tt.rows))
+
+			n, err := tt.exec(ctx, pool)
+			if err != nil || n != tt.rows {
+				t.Errorf("Exec() = %d, %v, want %d", n, err, tt.rows)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestBuilders_ExecReturning(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	scanID := func(id *int64) ScanFunc {
+		return func(row Scanner) error { return row.Scan(id) }
+	}
+
+	t.Run("update returns rows", func(t *testing.T) {
+		t.Parallel()
+		pool, mock := newMockPool(t)
+		defer mock.Close()
+
+		mock.ExpectQuery(regexp.QuoteMeta("UPDATE trips SET status = $1 WHERE id = $2 RETURNING id")).
+			WithArgs("done", 5).
+			WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int64(5))).
+			RowsWillBeClosed()
+
+		var id int64
+		n, err := Update("trips").Set("status", "done").Where("id = ?", 5).Returning("id").
+			ExecReturning(ctx, pool, scanID(&id))
+		if err != nil || n != 1 || id != 5 {
+			t.Errorf("ExecReturning() = %d, %v, id %d", n, err, id)
+		}
+	})
+
+	t.Run("update not found", func(t *testing.T) {
+		t.Parallel()
+		pool, mock := newMockPool(t)
+		defer mock.Close()
+
+		mock.ExpectQuery(regexp.QuoteMeta("UPDATE trips SET status = $1 WHERE id = $2 RETURNING id")).
+			WithArgs("done", 5).
+			WillReturnRows(pgxmock.NewRows([]string{"id"}))
+
+		var id int64
+		_, err := Update("trips").Set("status", "done").Where("id = ?", 5).Returning("id").
+			ExecReturning(ctx, pool, scanID(&id))
+		if !IsNotFound(err) {
+			t.Errorf("ExecReturning() error = %v, want not found", err)
+		}
+	})
+
+	t.Run("delete not found", func(t *testing.T) {
+		t.Parallel()
+		pool, mock := newMockPool(t)
+		defer mock.Close()
+
+		mock.ExpectQuery(regexp.QuoteMeta("DELETE FROM trips WHERE id = $1 RETURNING id")).
+			WithArgs(5).
+			WillReturnRows(pgxmock.NewRows([]string{"id"}))
+
+		var id int64
+		_, err := Delete("trips").Where("id = ?", 5).Returning("id").ExecReturning(ctx, pool, scanID(&id))
+		if !IsNotFound(err) {
+			t.Errorf("ExecReturning() error = %v, want not found", err)
+		}
+	})
+
+	t.Run("insert skipped by conflict", func(t *testing.T) {
+		t.Parallel()
+		pool, mock := newMockPool(t)
+		defer mock.Close()
+
+		mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO users (email) VALUES ($1) ON CONFLICT (email) DO NOTHING RETURNING id")).
+			WithArgs("a@x.co").
+			WillReturnRows(pgxmock.NewRows([]string{"id"}))
+
+		var id int64
+		_, err := Insert("users").Columns("email").Values("a@x.co").OnConflictDoNothing("email").Returning("id").
+			ExecReturning(ctx, pool, scanID(&id))
+		if !IsDuplicate(err) {
+			t.Errorf("ExecReturning() error = %v, want duplicate", err)
+		}
+	})
+
+	t.Run("requires returning", func(t *testing.T) {
+		t.Parallel()
+		pool, mock := newMockPool(t)
+		defer mock.Close()
+
+		var id int64
+		_, err := Delete("trips").Where("id = ?", 5).ExecReturning(ctx, pool, scanID(&id))
+		if !IsCode(err, CodeInvalidInput) {
+			t.Errorf("ExecReturning() error = %v, want CodeInvalidInput", err)
+		}
+	})
+}
@@ -0,0 +1,604 @@
+// Package postgres provides PostgreSQL database utilities.
+package postgres
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Dorico-Dynamics/txova-go-types/pagination"
+)
+
+// DefaultFilterSortParam is the query parameter that holds the sort order.
+const DefaultFilterSortParam = "sort"
+
+// DefaultFilterIgnoredParams are query parameters that are not filters and are
+// skipped by ParseQuery, such as pagination parameters.
+var DefaultFilterIgnoredParams = []string{"limit", "offset", "page", "page_size", "cursor"}
+
+// FilterType is the type of a filter field's values.
+type FilterType int
+
+// Filter field types.
+const (
+	// FilterString accepts any string.
+	FilterString FilterType = iota
+	// FilterInt accepts 64-bit integers.
+	FilterInt
+	// FilterFloat accepts floating point numbers.
+	FilterFloat
+	// FilterBool accepts true and false (and 1, 0, t, f).
+	FilterBool
+	// FilterTime accepts RFC 3339 timestamps and dates (2006-01-02).
+	FilterTime
+)
+
+// String returns the name of the filter type.
+func (t FilterType) String() string {
+	switch t {
+	case FilterString:
+		return "string"
+	case FilterInt:
+		return "integer"
+	case FilterFloat:
+		return "number"
+	case FilterBool:
+		return "boolean"
+	case FilterTime:
+		return "time"
+	default:
+		return "unknown"
+	}
+}
+
+// FilterOperator is a comparison accepted by a filter field.
+// Operators appear in query parameters as field[op]=value and in JSON
+// filters as {"field": {"op": value}}.
+type FilterOperator string
+
+// Filter operators.
+const (
+	// FilterEq matches equal values. It is used when no operator is given.
+	FilterEq FilterOperator = "eq"
+	// FilterNe matches different values.
+	FilterNe FilterOperator = "ne"
+	// FilterGt matches greater values.
+	FilterGt FilterOperator = "gt"
+	// FilterGte matches greater or equal values.
+	FilterGte FilterOperator = "gte"
+	// FilterLt matches smaller values.
+	FilterLt FilterOperator = "lt"
+	// FilterLte matches smaller or equal values.
+	FilterLte FilterOperator = "lte"
+	// FilterIn matches any of a list of values, comma-separated in query parameters.
+	// The list is bound as a single array parameter: column = ANY($1).
+	FilterIn FilterOperator = "in"
+	// FilterContains matches strings containing the value, case-insensitively.
+	FilterContains FilterOperator = "contains"
+	// FilterNull matches NULL values if the value is true and non-NULL values if false.
+	FilterNull FilterOperator = "null"
+)
+
+// filterComparisons maps comparison operators to their SQL operators.
+var filterComparisons = map[FilterOperator]string{
+	FilterEq:  "=",
+	FilterNe:  "<>",
+	FilterGt:  ">",
+	FilterGte: ">=",
+	FilterLt:  "<",
+	FilterLte: "<=",
+}
+
+// FilterField declares a field that clients may filter or sort by.
+type FilterField struct {
+	// Column is the column the field maps to. Defaults to the field name.
+	Column string
+	// Type is the type of the field's values.
+	Type FilterType
+	// Operators are the operators clients may use. Defaults to FilterEq.
+	Operators []FilterOperator
+	// Sortable allows clients to sort by the field.
+	Sortable bool
+}
+
+// FieldError is a validation error for a single filter or sort field.
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+// Error implements the error interface.
+func (e FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// FilterError collects the field errors of a rejected filter.
+// Parse errors wrap it with CodeInvalidInput; use errors.As to get the fields.
+type FilterError struct {
+	Fields []FieldError
+}
+
+// Error implements the error interface.
+func (e *FilterError) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = f.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// add records an error for a field.
+func (e *FilterError) add(field, format string, args ...any) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// err returns the collected errors as an Error, or nil if there are none.
+func (e *FilterError) err() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	sort.SliceStable(e.Fields, func(i, j int) bool { return e.Fields[i].Field < e.Fields[j].Field })
+	return Wrap(CodeInvalidInput, "invalid filter", e)
+}
+
+// FilterSpecConfig holds configuration for a FilterSpec.
+type FilterSpecConfig struct {
+	// SortParam is the query parameter that holds the sort order.
+	SortParam string
+	// IgnoredParams are query parameters skipped by ParseQuery.
+	// Other parameters that are not declared fields are rejected.
+	IgnoredParams []string
+	// DefaultSort is the sort order used when the client gives none, e.g. "-created_at".
+	DefaultSort string
+}
+
+// DefaultFilterSpecConfig returns the default filter spec configuration.
+func DefaultFilterSpecConfig() FilterSpecConfig {
+	return FilterSpecConfig{
+		SortParam:     DefaultFilterSortParam,
+		IgnoredParams: slices.Clone(DefaultFilterIgnoredParams),
+	}
+}
+
+// FilterSpecOption is a functional option for configuring a FilterSpec.
+type FilterSpecOption func(*FilterSpecConfig)
+
+// WithFilterSortParam sets the query parameter that holds the sort order.
+func WithFilterSortParam(name string) FilterSpecOption {
+	return func(c *FilterSpecConfig) {
+		c.SortParam = name
+	}
+}
+
+// WithFilterIgnoredParams sets the query parameters skipped by ParseQuery.
+func WithFilterIgnoredParams(names ...string) FilterSpecOption {
+	return func(c *FilterSpecConfig) {
+		c.IgnoredParams = names
+	}
+}
+
+// WithFilterDefaultSort sets the sort order used when the client gives none.
+func WithFilterDefaultSort(sort string) FilterSpecOption {
+	return func(c *FilterSpecConfig) {
+		c.DefaultSort = sort
+	}
+}
+
+// FilterSpec declares the fields an API endpoint accepts for filtering and
+// sorting, and turns client input into validated conditions.
+// Only declared fields and operators are accepted, and values are bound as
+// parameters, so filters built from request input are safe to apply.
+type FilterSpec struct {
+	fields      map[string]FilterField
+	config      FilterSpecConfig
+	defaultSort []SortField
+}
+
+// NewFilterSpec creates a filter spec from fields keyed by their API names.
+func NewFilterSpec(fields map[string]FilterField, opts ...FilterSpecOption) (*FilterSpec, error) {
+	config := DefaultFilterSpecConfig()
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	spec := &FilterSpec{fields: make(map[string]FilterField, len(fields)), config: config}
+	for name, field := range fields {
+		if name == "" {
+			return nil, New(CodeInvalidInput, "filter field name is required")
+		}
+		if field.Column == "" {
+			field.Column = name
+		}
+		if err := validateColumnIdentifier(field.Column); err != nil {
+			return nil, Wrap(CodeInvalidInput, fmt.Sprintf("invalid filter field %s", name), err)
+		}
+		if field.Type < FilterString || field.Type > FilterTime {
+			return nil, New(CodeInvalidInput, fmt.Sprintf("filter field %s has an unknown type", name))
+		}
+		if len(field.Operators) == 0 {
+			field.Operators = []FilterOperator{FilterEq}
+		}
+		for _, op := range field.Operators {
+			if _, ok := filterComparisons[op]; ok || op == FilterIn || op == FilterNull {
+				continue
+			}
+			if op == FilterContains && field.Type == FilterString {
+				continue
+			}
+			return nil, New(CodeInvalidInput,
+				fmt.Sprintf("filter field %s: operator %q is not supported for %s values", name, op, field.Type))
+		}
+		spec.fields[name] = field
+	}
+
+	if config.DefaultSort != "" {
+		defaultSort, err := spec.ParseSort(config.DefaultSort)
+		if err != nil {
+			return nil, err
+		}
+		spec.defaultSort = defaultSort
+	}
+	return spec, nil
+}
+
+// SortField is a column of a sort order.
+type SortField struct {
+	Column    string
+	Direction pagination.SortDirection
+}
+
+// ParsedFilter is the result of parsing client input with a FilterSpec.
+type ParsedFilter struct {
+	// Conditions are the filter conditions, combined with AND.
+	Conditions []Condition
+	// Sort is the requested sort order, or the spec's default.
+	Sort []SortField
+}
+
+// Condition returns the filter conditions combined with AND, or nil if there are none.
+func (f ParsedFilter) Condition() Condition {
+	if len(f.Conditions) == 0 {
+		return nil
+	}
+	return And(f.Conditions...)
+}
+
+// Apply adds the filter conditions and sort order to a select builder.
+func (f ParsedFilter) Apply(s *SelectBuilder) *SelectBuilder {
+	for _, c := range f.Conditions {
+		s.WhereCondition(c)
+	}
+	for _, o := range f.Sort {
+		s.OrderBy(o.Column, o.Direction)
+	}
+	return s
+}
+
+// ParseQuery parses filters and the sort order from URL query parameters:
+//
+//	?status=active&fare[gte]=100&city[in]=maputo,beira&sort=-created_at,id
+//
+// A parameter without an operator uses eq. Parameters listed in IgnoredParams
+// are skipped; any other unknown parameter is rejected. All problems are
+// reported together as a FilterError wrapped with CodeInvalidInput.
+func (s *FilterSpec) ParseQuery(values url.Values) (ParsedFilter, error) {
+	var result ParsedFilter
+	errs := &FilterError{}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if key == s.config.SortParam || slices.Contains(s.config.IgnoredParams, key) {
+			continue
+		}
+		name, op := key, FilterEq
+		if open := strings.IndexByte(key, '['); open > 0 && strings.HasSuffix(key, "]") {
+			name, op = key[:open], FilterOperator(key[open+1:len(key)-1])
+		}
+		field, ok := s.lookup(errs, name, op)
+		if !ok {
+			continue
+		}
+		for _, raw := range values[key] {
+			var value any = raw
+			if op == FilterIn {
+				value = splitFilterList(raw)
+			}
+			if c, ok := filterCondition(errs, name, field, op, value); ok {
+				result.Conditions = append(result.Conditions, c)
+			}
+		}
+	}
+
+	result.Sort = s.defaultSort
+	if sortValues, ok := values[s.config.SortParam]; ok {
+		if sortFields, ok := s.parseSort(errs, strings.Join(sortValues, ",")); ok {
+			result.Sort = sortFields
+		}
+	}
+	if err := errs.err(); err != nil {
+		return ParsedFilter{}, err
+	}
+	return result, nil
+}
+
+// ParseJSON parses filters from a JSON object that maps field names to a
+// value (eq) or to an object of operators:
+//
+//	{"status": "active", "fare": {"gte": 100, "lt": 500}, "city": {"in": ["maputo", "beira"]}}
+//
+// The sort order is the spec's default; use ParseSort for a client sort.
+func (s *FilterSpec) ParseJSON(data []byte) (ParsedFilter, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return ParsedFilter{}, Wrap(CodeInvalidInput, "invalid filter JSON", err)
+	}
+
+	var result ParsedFilter
+	errs := &FilterError{}
+
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		ops := map[FilterOperator]json.RawMessage{}
+		raw := bytes.TrimSpace(fields[name])
+		if len(raw) > 0 && raw[0] == '{' {
+			if err := json.Unmarshal(raw, &ops); err != nil {
+				errs.add(name, "invalid operators")
+				continue
+			}
+		} else {
+			ops[FilterEq] = raw
+		}
+
+		opNames := make([]string, 0, len(ops))
+		for op := range ops {
+			opNames = append(opNames, string(op))
+		}
+		sort.Strings(opNames)
+
+		for _, opName := range opNames {
+			op := FilterOperator(opName)
+			field, ok := s.lookup(errs, name, op)
+			if !ok {
+				continue
+			}
+			value, err := decodeFilterJSON(ops[op])
+			if err != nil {
+				errs.add(name, "invalid value")
+				continue
+			}
+			if c, ok := filterCondition(errs, name, field, op, value); ok {
+				result.Conditions = append(result.Conditions, c)
+			}
+		}
+	}
+
+	result.Sort = s.defaultSort
+	if err := errs.err(); err != nil {
+		return ParsedFilter{}, err
+	}
+	return result, nil
+}
+
+// ParseSort parses a sort order such as "-created_at,name": fields separated
+// by commas, each optionally prefixed with - for descending or + for ascending.
+// Only sortable fields are accepted.
+func (s *FilterSpec) ParseSort(value string) ([]SortField, error) {
+	errs := &FilterError{}
+	fields, _ := s.parseSort(errs, value)
+	if err := errs.err(); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// parseSort parses a sort order, recording problems in errs.
+func (s *FilterSpec) parseSort(errs *FilterError, value string) ([]SortField, bool) {
+	var fields []SortField
+	failed := false
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		direction := pagination.SortAsc
+		switch part[0] {
+		case '-':
+			direction, part = pagination.SortDesc, part[1:]
+		case '+':
+			part = part[1:]
+		}
+		column, ok := s.sortColumn(errs, part)
+		if !ok {
+			failed = true
+			continue
+		}
+		fields = append(fields, SortField{Column: column, Direction: direction})
+	}
+	return fields, !failed
+}
+
+// SortPage maps the SortField of a page request from an API field name to
+// its column, so the request can be passed to SelectBuilder.Page.
+// An empty SortField is left empty; a field that is not sortable is rejected.
+func (s *FilterSpec) SortPage(req pagination.PageRequest) (pagination.PageRequest, error) {
+	if req.SortField == "" {
+		return req, nil
+	}
+	errs := &FilterError{}
+	column, ok := s.sortColumn(errs, req.SortField)
+	if !ok {
+		return req, errs.err()
+	}
+	req.SortField = column
+	return req, nil
+}
+
+// sortColumn returns the column of a sortable field.
+func (s *FilterSpec) sortColumn(errs *FilterError, name string) (string, bool) {
+	field, ok := s.fields[name]
+	if !ok || !field.Sortable {
+		errs.add(name, "sorting is not allowed")
+		return "", false
+	}
+	return field.Column, true
+}
+
+// lookup returns a declared field and checks that it accepts the operator.
+func (s *FilterSpec) lookup(errs *FilterError, name string, op FilterOperator) (FilterField, bool) {
+	field, ok := s.fields[name]
+	if !ok {
+		errs.add(name, "unknown filter field")
+		return FilterField{}, false
+	}
+	if !slices.Contains(field.Operators, op) {
+		errs.add(name, "operator %q is not allowed", op)
+		return FilterField{}, false
+	}
+	return field, true
+}
+
+// filterCondition builds the condition of a field and operator from a raw value:
+// a string, a json.Number or bool from JSON, or a slice of these for FilterIn.
+func filterCondition(errs *FilterError, name string, field FilterField, op FilterOperator, raw any) (Condition, bool) {
+	column := quoteIdent(field.Column)
+
+	switch op {
+	case FilterNull:
+		isNull, err := convertFilterValue(FilterBool, raw)
+		if err != nil {
+			errs.add(name, "null expects a boolean")
+			return nil, false
+		}
+		if isNull.(bool) {
+			return Expr(column + " IS NULL"), true
+		}
+		return Expr(column + " IS NOT NULL"), true
+
+	case FilterIn:
+		items, ok := raw.([]any)
+		if !ok || len(items) == 0 {
+			errs.add(name, "in expects a non-empty list")
+			return nil, false
+		}
+		values := make([]any, len(items))
+		for i, item := range items {
+			v, err := convertFilterValue(field.Type, item)
+			if err != nil {
+				errs.add(name, "%v", err)
+				return nil, false
+			}
+			values[i] = v
+		}
+		// One array parameter keeps long lists under the parameter limit.
+		return Expr(column+" = ANY(?)", typedSlice(values)), true
+
+	case FilterContains:
+		text, ok := raw.(string)
+		if !ok {
+			errs.add(name, "contains expects a string")
+			return nil, false
+		}
+		return Expr(column+` ILIKE ? ESCAPE '\'`, "%"+escapeLikePattern(text)+"%"), true
+
+	default:
+		v, err := convertFilterValue(field.Type, raw)
+		if err != nil {
+			errs.add(name, "%v", err)
+			return nil, false
+		}
+		return Expr(column+" "+filterComparisons[op]+" ?", v), true
+	}
+}
+
+// convertFilterValue converts a raw value to the Go type of a filter type.
+func convertFilterValue(typ FilterType, raw any) (any, error) {
+	invalid := fmt.Errorf("invalid %s value", typ)
+	switch v := raw.(type) {
+	case bool:
+		if typ != FilterBool {
+			return nil, invalid
+		}
+		return v, nil
+	case json.Number:
+		if typ != FilterInt && typ != FilterFloat {
+			return nil, invalid
+		}
+		return convertFilterValue(typ, v.String())
+	case string:
+		switch typ {
+		case FilterString:
+			return v, nil
+		case FilterInt:
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return nil, invalid
+			}
+			return n, nil
+		case FilterFloat:
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, invalid
+			}
+			return f, nil
+		case FilterBool:
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil, invalid
+			}
+			return b, nil
+		case FilterTime:
+			if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+				return t, nil
+			}
+			t, err := time.Parse(time.DateOnly, v)
+			if err != nil {
+				return nil, invalid
+			}
+			return t, nil
+		}
+	}
+	return nil, invalid
+}
+
+// decodeFilterJSON decodes a JSON filter value, keeping numbers as json.Number.
+func decodeFilterJSON(raw json.RawMessage) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// splitFilterList splits a comma-separated query parameter value.
+func splitFilterList(value string) []any {
+	var items []any
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// escapeLikePattern escapes the LIKE wildcards % and _ and the escape character.
+func escapeLikePattern(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
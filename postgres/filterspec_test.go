@@ -0,0 +1,211 @@
+package postgres
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Dorico-Dynamics/txova-go-types/pagination"
+)
+
+func newTestFilterSpec(t *testing.T, opts ...FilterSpecOption) *FilterSpec {
+	t.Helper()
+	spec, err := NewFilterSpec(map[string]FilterField{
+		"status":     {Type: FilterString, Operators: []FilterOperator{FilterEq, FilterIn}},
+		"fare":       {Column: "fare_cents", Type: FilterInt, Operators: []FilterOperator{FilterEq, FilterGte, FilterLte}, Sortable: true},
+		"rating":     {Type: FilterFloat, Operators: []FilterOperator{FilterGt}},
+		"name":       {Type: FilterString, Operators: []FilterOperator{FilterContains}, Sortable: true},
+		"paid":       {Type: FilterBool},
+		"cancelled":  {Column: "cancelled_at", Type: FilterTime, Operators: []FilterOperator{FilterNull}},
+		"created_at": {Type: FilterTime, Operators: []FilterOperator{FilterGte, FilterLt}, Sortable: true},
+		"order":      {Column: "order", Type: FilterInt, Sortable: true},
+	}, opts...)
+	if err != nil {
+		t.Fatalf("NewFilterSpec() error = %v", err)
+	}
+	return spec
+}
+
+func TestNewFilterSpec_Invalid(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		fields map[string]FilterField
+		opts   []FilterSpecOption
+	}{
+		{name: "expression column", fields: map[string]FilterField{"total": {Column: "price * qty"}}},
+		{name: "unknown type", fields: map[string]FilterField{"total": {Type: FilterType(42)}}},
+		{name: "unknown operator", fields: map[string]FilterField{"total": {Operators: []FilterOperator{"regex"}}}},
+		{name: "contains on number", fields: map[string]FilterField{"total": {Type: FilterInt, Operators: []FilterOperator{FilterContains}}}},
+		{name: "default sort not sortable", fields: map[string]FilterField{"total": {}}, opts: []FilterSpecOption{WithFilterDefaultSort("-total")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := NewFilterSpec(tt.fields, tt.opts...)
+			if !IsCode(err, CodeInvalidInput) {
+				t.Errorf("NewFilterSpec() error = %v, want CodeInvalidInput", err)
+			}
+		})
+	}
+}
+
+func TestFilterSpec_ParseQuery(t *testing.T) {
+	t.Parallel()
+
+	spec := newTestFilterSpec(t, WithFilterDefaultSort("-created_at"))
+	day := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		query    string
+		wantSQL  string
+		wantArgs []any
+	}{
+		{
+			name:    "no filters uses default sort",
+			query:   "limit=20&offset=40",
+			wantSQL: "SELECT * FROM trips ORDER BY created_at DESC",
+		},
+		{
+			name:     "eq and range",
+			query:    "status=active&fare[gte]=100&fare[lte]=500&sort=-fare,name",
+			wantSQL:  "SELECT * FROM trips WHERE fare_cents >= $1 AND fare_cents <= $2 AND status = $3 ORDER BY fare_cents DESC, name ASC",
+			wantArgs: []any{int64(100), int64(500), "active"},
+		},
+		{
+			name:     "in list",
+			query:    "status[in]=active,completed",
+			wantSQL:  "SELECT * FROM trips WHERE status = ANY($1) ORDER BY created_at DESC",
+			wantArgs: []any{[]string{"active", "completed"}},
+		},
+		{
+			name:     "contains escapes wildcards",
+			query:    "name[contains]=50%25_off",
+			wantSQL:  `SELECT * FROM trips WHERE name ILIKE $1 ESCAPE '\' ORDER BY created_at DESC`,
+			wantArgs: []any{`%50\%\_off%`},
+		},
+		{
+			name:    "null",
+			query:   "cancelled[null]=false",
+			wantSQL: "SELECT * FROM trips WHERE cancelled_at IS NOT NULL ORDER BY created_at DESC",
+		},
+		{
+			name:     "typed values",
+			query:    "paid=true&rating[gt]=4.5&created_at[gte]=2026-05-01&order=3&sort=%2Border",
+			wantSQL:  `SELECT * FROM trips WHERE created_at >= $1 AND "order" = $2 AND paid = $3 AND rating > $4 ORDER BY "order" ASC`,
+			wantArgs: []any{day, int64(3), true, 4.5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			values, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			filter, err := spec.ParseQuery(values)
+			if err != nil {
+				t.Fatalf("ParseQuery() error = %v", err)
+			}
+			assertBuild(t, filter.Apply(Select("trips")), tt.wantSQL, tt.wantArgs, "")
+		})
+	}
+}
+
+func TestFilterSpec_ParseQuery_Errors(t *testing.T) {
+	t.Parallel()
+
+	spec := newTestFilterSpec(t)
+	values, err := url.ParseQuery("fare[gte]=cheap&status[ne]=x&unknown=1&paid=maybe&sort=-rating&page=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = spec.ParseQuery(values)
+	if !IsCode(err, CodeInvalidInput) {
+		t.Fatalf("ParseQuery() error = %v, want CodeInvalidInput", err)
+	}
+	var filterErr *FilterError
+	if !errors.As(err, &filterErr) {
+		t.Fatalf("error %v does not wrap a FilterError", err)
+	}
+	want := []FieldError{
+		{Field: "fare", Message: "invalid integer value"},
+		{Field: "paid", Message: "invalid boolean value"},
+		{Field: "rating", Message: "sorting is not allowed"},
+		{Field: "status", Message: `operator "ne" is not allowed`},
+		{Field: "unknown", Message: "unknown filter field"},
+	}
+	if !reflect.DeepEqual(filterErr.Fields, want) {
+		t.Errorf("Fields = %#v, want %#v", filterErr.Fields, want)
+	}
+}
+
+func TestFilterSpec_ParseJSON(t *testing.T) {
+	t.Parallel()
+
+	spec := newTestFilterSpec(t)
+
+	t.Run("operators", func(t *testing.T) {
+		t.Parallel()
+
+		filter, err := spec.ParseJSON([]byte(`{"status": {"in": ["active", "completed"]}, "fare": {"gte": 100, "lte": "500"}, "paid": false}`))
+		if err != nil {
+			t.Fatalf("ParseJSON() error = %v", err)
+		}
+		assertBuild(t, Select("trips").WhereCondition(filter.Condition()),
+			"SELECT * FROM trips WHERE (fare_cents >= $1 AND fare_cents <= $2 AND paid = $3 AND status = ANY($4))",
+			[]any{int64(100), int64(500), false, []string{"active", "completed"}}, "")
+	})
+
+	t.Run("invalid values", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := spec.ParseJSON([]byte(`{"fare": {"gte": 1.5}, "status": {"in": []}, "paid": "yes"}`))
+		var filterErr *FilterError
+		if !errors.As(err, &filterErr) || len(filterErr.Fields) != 3 {
+			t.Fatalf("ParseJSON() error = %v, want 3 field errors", err)
+		}
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		t.Parallel()
+
+		if _, err := spec.ParseJSON([]byte(`[1, 2]`)); !IsCode(err, CodeInvalidInput) {
+			t.Errorf("ParseJSON() error = %v, want CodeInvalidInput", err)
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		t.Parallel()
+
+		filter, err := spec.ParseJSON([]byte(`{}`))
+		if err != nil || filter.Condition() != nil {
+			t.Errorf("ParseJSON() = %v, %v, want no conditions", filter, err)
+		}
+	})
+}
+
+func TestFilterSpec_SortPage(t *testing.T) {
+	t.Parallel()
+
+	spec := newTestFilterSpec(t)
+
+	req, err := spec.SortPage(pagination.PageRequest{Limit: 10, SortField: "fare", SortDir: pagination.SortDesc})
+	if err != nil {
+		t.Fatalf("SortPage() error = %v", err)
+	}
+	assertBuild(t, Select("trips").Page(req),
+		"SELECT * FROM trips ORDER BY fare_cents DESC LIMIT 10 OFFSET 0", nil, "")
+
+	if _, err := spec.SortPage(pagination.PageRequest{SortField: "status"}); !IsCode(err, CodeInvalidInput) {
+		t.Errorf("SortPage() error = %v, want CodeInvalidInput", err)
+	}
+}
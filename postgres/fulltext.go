@@ -0,0 +1,206 @@
+// Package postgres provides PostgreSQL database utilities.
+package postgres
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Dorico-Dynamics/txova-go-types/pagination"
+)
+
+// identifierRegex validates unqualified identifiers such as generated column names.
+var identifierRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// textSearch represents a full-text search applied with WhereTextSearch.
+type textSearch struct {
+	column string
+	query  string
+	config string
+}
+
+// tsquery returns the websearch_to_tsquery expression with ? placeholders and its args.
+func (t *textSearch) tsquery() (string, []any) {
+	if t.config == "" {
+		return "websearch_to_tsquery(?)", []any{t.query}
+	}
+	return "websearch_to_tsquery(?::regconfig, ?)", []any{t.config, t.query}
+}
+
+// textHeadline represents a ts_headline column.
+type textHeadline struct {
+	source  string
+	alias   string
+	options string
+}
+
+// WhereTextSearch adds a full-text search condition on a tsvector column:
+// column @@ websearch_to_tsquery(config, query). The query uses web search syntax,
+// e.g. `"exact phrase" -excluded or other`, and never fails to parse.
+// If config is empty, the server's default_text_search_config is used.
+func (s *SelectBuilder) WhereTextSearch(column, query, config string) *SelectBuilder {
+	s.textSearch = &textSearch{column: column, query: query, config: config}
+	tsquery, args := s.textSearch.tsquery()
+	s.where = append(s.where, whereClause{condition: quoteIdent(column) + " @@ " + tsquery, args: args})
+	return s
+}
+
+// SelectTextRank adds the ts_rank_cd rank of the WhereTextSearch match as a column named alias.
+func (s *SelectBuilder) SelectTextRank(alias string) *SelectBuilder {
+	s.textRank = alias
+	return s
+}
+
+// OrderByRank orders by the SelectTextRank column, best matches first.
+func (s *SelectBuilder) OrderByRank() *SelectBuilder {
+	s.orderBy = append(s.orderBy, orderByClause{direction: pagination.SortDesc, rank: true})
+	return s
+}
+
+// SelectTextHeadline adds a ts_headline snippet of the source text column, highlighting
+// the WhereTextSearch match, as a column named alias. Options are passed to ts_headline,
+// e.g. "MaxWords=35, MinWords=15, StartSel=<b>, StopSel=</b>", and may be empty.
+func (s *SelectBuilder) SelectTextHeadline(source, alias, options string) *SelectBuilder {
+	s.textHeadlines = append(s.textHeadlines, textHeadline{source: source, alias: alias, options: options})
+	return s
+}
+
+// validateTextSearch checks the text search configuration and output columns.
+func (s *SelectBuilder) validateTextSearch() error {
+	if s.textSearch != nil {
+		if err := s.validateColumnName(s.textSearch.column); err != nil {
+			return fmt.Errorf("invalid text search column: %w", err)
+		}
+		if s.textSearch.config != "" && !columnNameRegex.MatchString(s.textSearch.config) {
+			return fmt.Errorf("invalid text search config: %q", s.textSearch.config)
+		}
+	}
+	hasRankOrder := false
+	for _, o := range s.orderBy {
+		hasRankOrder = hasRankOrder || o.rank
+	}
+	if s.textSearch == nil && (s.textRank != "" || hasRankOrder || len(s.textHeadlines) > 0) {
+		return fmt.Errorf("text rank and headline require WhereTextSearch")
+	}
+	if s.textRank != "" && !identifierRegex.MatchString(s.textRank) {
+		return fmt.Errorf("invalid text rank alias: %q", s.textRank)
+	}
+	if hasRankOrder && s.textRank == "" {
+		return fmt.Errorf("OrderByRank requires SelectTextRank")
+	}
+	if hasRankOrder && s.keyset != nil {
+		return fmt.Errorf("OrderByRank cannot be used with keyset pagination")
+	}
+	for _, h := range s.textHeadlines {
+		if err := s.validateColumnName(h.source); err != nil {
+			return fmt.Errorf("invalid text headline source: %w", err)
+		}
+		if !identifierRegex.MatchString(h.alias) {
+			return fmt.Errorf("invalid text headline alias: %q", h.alias)
+		}
+	}
+	return nil
+}
+
+// textSearchExprs returns the SELECT list expressions for the text rank and headlines.
+func (s *SelectBuilder) textSearchExprs() []selectExpr {
+	if s.textSearch == nil {
+		return nil
+	}
+	tsquery, tsqueryArgs := s.textSearch.tsquery()
+	var exprs []selectExpr
+	if s.textRank != "" {
+		exprs = append(exprs, selectExpr{
+			expr: fmt.Sprintf("ts_rank_cd(%s, %s) AS %s", quoteIdent(s.textSearch.column), tsquery, s.textRank),
+			args: tsqueryArgs,
+		})
+	}
+	for _, h := range s.textHeadlines {
+		var sb strings.Builder
+		var args []any
+		sb.WriteString("ts_headline(")
+		if s.textSearch.config != "" {
+			sb.WriteString("?::regconfig, ")
+			args = append(args, s.textSearch.config)
+		}
+		sb.WriteString(quoteIdent(h.source) + ", " + tsquery)
+		args = append(args, tsqueryArgs...)
+		if h.options != "" {
+			sb.WriteString(", ?")
+			args = append(args, h.options)
+		}
+		sb.WriteString(") AS " + h.alias)
+		exprs = append(exprs, selectExpr{expr: sb.String(), args: args})
+	}
+	return exprs
+}
+
+// TextSearchSource is a text column indexed by a generated tsvector column.
+type TextSearchSource struct {
+	// Column is the text column.
+	Column string
+
+	// Weight is the rank weight, "A" (highest) to "D". Empty means unweighted.
+	Weight string
+}
+
+// TextSearchColumn describes a generated tsvector column for full-text search.
+type TextSearchColumn struct {
+	// Table is the table to add the column to.
+	Table string
+
+	// Column is the tsvector column name, e.g. "search".
+	Column string
+
+	// Config is the text search configuration, e.g. "english" or "simple".
+	Config string
+
+	// Sources are the text columns to index.
+	Sources []TextSearchSource
+}
+
+// TextSearchMigration returns migration SQL adding a stored generated tsvector column
+// over the source columns and a GIN index on it, for use with WhereTextSearch.
+// The index is named <table>_<column>_idx.
+func TextSearchMigration(c TextSearchColumn) (up, down string, err error) {
+	if err := validateTableName(c.Table); err != nil {
+		return "", "", Wrap(CodeInvalidInput, "invalid text search table", err)
+	}
+	if !identifierRegex.MatchString(c.Column) {
+		return "", "", New(CodeInvalidInput, fmt.Sprintf("invalid text search column: %q", c.Column))
+	}
+	if !columnNameRegex.MatchString(c.Config) {
+		return "", "", New(CodeInvalidInput, fmt.Sprintf("invalid text search config: %q", c.Config))
+	}
+	if len(c.Sources) == 0 {
+		return "", "", New(CodeInvalidInput, "text search column requires at least one source")
+	}
+
+	parts := make([]string, len(c.Sources))
+	for i, src := range c.Sources {
+		if !identifierRegex.MatchString(src.Column) {
+			return "", "", New(CodeInvalidInput, fmt.Sprintf("invalid text search source: %q", src.Column))
+		}
+		vector := fmt.Sprintf("to_tsvector('%s'::regconfig, coalesce(%s, ''))", c.Config, quoteIdent(src.Column))
+		switch src.Weight {
+		case "":
+		case "A", "B", "C", "D":
+			vector = fmt.Sprintf("setweight(%s, '%s')", vector, src.Weight)
+		default:
+			return "", "", New(CodeInvalidInput, fmt.Sprintf("invalid text search weight: %q", src.Weight))
+		}
+		parts[i] = vector
+	}
+
+	table, column := quoteIdent(c.Table), quoteIdent(c.Column)
+	index := formatIdentifier([]string{identifierPrefix(c.Table) + "_" + c.Column + "_idx"})
+	up = fmt.Sprintf(
+		"ALTER TABLE %s ADD COLUMN %s tsvector GENERATED ALWAYS AS (%s) STORED;\n"+
+			"CREATE INDEX %s ON %s USING GIN (%s);\n",
+		table, column, strings.Join(parts, " || "), index, table, column)
+	down = fmt.Sprintf(
+		"DROP INDEX IF EXISTS %s;\n"+
+			"ALTER TABLE %s DROP COLUMN IF EXISTS %s;\n",
+		index, table, column)
+	return up, down, nil
+}
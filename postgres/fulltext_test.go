@@ -0,0 +1,182 @@
+package postgres
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTextSearch(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		builder     Builder
+		wantSQL     string
+		wantArgs    []any
+		errContains string
+	}{
+		{
+			name: "search with config",
+			builder: Select("drivers").
+				Columns("id", "name").
+				WhereTextSearch("search", `"joao silva" -maputo`, "portuguese").
+				Where("active = ?", true),
+			wantSQL:  "SELECT id, name FROM drivers WHERE search @@ websearch_to_tsquery($1::regconfig, $2) AND active = $3",
+			wantArgs: []any{"portuguese", `"joao silva" -maputo`, true},
+		},
+		{
+			name:     "search with default config",
+			builder:  Select("riders").WhereTextSearch("search", "ana", ""),
+			wantSQL:  "SELECT * FROM riders WHERE search @@ websearch_to_tsquery($1)",
+			wantArgs: []any{"ana"},
+		},
+		{
+			name: "rank and order by rank",
+			builder: Select("drivers").
+				Columns("id").
+				WhereTextSearch("search", "ana", "simple").
+				SelectTextRank("rank").
+				OrderByRank().
+				OrderByAsc("id").
+				Limit(10),
+			wantSQL: "SELECT id, ts_rank_cd(search, websearch_to_tsquery($1::regconfig, $2)) AS rank FROM drivers " +
+				"WHERE search @@ websearch_to_tsquery($3::regconfig, $4) ORDER BY rank DESC, id ASC LIMIT 10",
+			wantArgs: []any{"simple", "ana", "simple", "ana"},
+		},
+		{
+			name: "headline",
+			builder: Select("drivers").
+				Columns("id").
+				WhereTextSearch("search", "ana", "simple").
+				SelectTextHeadline("bio", "snippet", "MaxWords=20, StartSel=<b>, StopSel=</b>"),
+			wantSQL: "SELECT id, ts_headline($1::regconfig, bio, websearch_to_tsquery($2::regconfig, $3), $4) AS snippet FROM drivers " +
+				"WHERE search @@ websearch_to_tsquery($5::regconfig, $6)",
+			wantArgs: []any{"simple", "simple", "ana", "MaxWords=20, StartSel=<b>, StopSel=</b>", "simple", "ana"},
+		},
+		{
+			name: "headline without config or options",
+			builder: Select("drivers").
+				WhereTextSearch("search", "ana", "").
+				SelectTextHeadline("bio", "snippet", ""),
+			wantSQL:  "SELECT *, ts_headline(bio, websearch_to_tsquery($1)) AS snippet FROM drivers WHERE search @@ websearch_to_tsquery($2)",
+			wantArgs: []any{"ana", "ana"},
+		},
+		{
+			name: "rank allowed with allowlist",
+			builder: SelectWithAllowlist("drivers", "id", "search").
+				Columns("id").
+				WhereTextSearch("search", "ana", "simple").
+				SelectTextRank("rank").
+				OrderByRank(),
+			wantSQL: "SELECT id, ts_rank_cd(search, websearch_to_tsquery($1::regconfig, $2)) AS rank FROM drivers " +
+				"WHERE search @@ websearch_to_tsquery($3::regconfig, $4) ORDER BY rank DESC",
+			wantArgs: []any{"simple", "ana", "simple", "ana"},
+		},
+		{
+			name:        "search column not in allowlist",
+			builder:     SelectWithAllowlist("drivers", "id").WhereTextSearch("search", "ana", "simple"),
+			errContains: "invalid text search column",
+		},
+		{
+			name:        "invalid config",
+			builder:     Select("drivers").WhereTextSearch("search", "ana", "simple'); DROP"),
+			errContains: "invalid text search config",
+		},
+		{
+			name:        "rank without search",
+			builder:     Select("drivers").SelectTextRank("rank"),
+			errContains: "require WhereTextSearch",
+		},
+		{
+			name:        "order by rank without rank",
+			builder:     Select("drivers").WhereTextSearch("search", "ana", "").OrderByRank(),
+			errContains: "OrderByRank requires SelectTextRank",
+		},
+		{
+			name:        "invalid rank alias",
+			builder:     Select("drivers").WhereTextSearch("search", "ana", "").SelectTextRank("r; DROP"),
+			errContains: "invalid text rank alias",
+		},
+		{
+			name:        "invalid headline alias",
+			builder:     Select("drivers").WhereTextSearch("search", "ana", "").SelectTextHeadline("bio", "", ""),
+			errContains: "invalid text headline alias",
+		},
+		{
+			name: "order by rank with keyset",
+			builder: Select("drivers").
+				WhereTextSearch("search", "ana", "").
+				SelectTextRank("rank").
+				OrderByRank().
+				PageAfter(nil, 10),
+			errContains: "cannot be used with keyset pagination",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assertBuild(t, tt.builder, tt.wantSQL, tt.wantArgs, tt.errContains)
+		})
+	}
+}
+
+func TestTextSearch_UnionColumnCount(t *testing.T) {
+	t.Parallel()
+
+	drivers := Select("drivers").Columns("id").WhereTextSearch("search", "ana", "").SelectTextRank("rank")
+	riders := Select("riders").Columns("id").ColumnsRaw("0")
+	if _, _, err := Union(drivers, riders).Build(); err != nil {
+		t.Errorf("Union() error = %v", err)
+	}
+}
+
+func TestTextSearchMigration(t *testing.T) {
+	t.Parallel()
+
+	up, down, err := TextSearchMigration(TextSearchColumn{
+		Table:  "drivers",
+		Column: "search",
+		Config: "simple",
+		Sources: []TextSearchSource{
+			{Column: "name", Weight: "A"},
+			{Column: "bio"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("TextSearchMigration() error = %v", err)
+	}
+	wantUp := "ALTER TABLE drivers ADD COLUMN search tsvector GENERATED ALWAYS AS (" +
+		"setweight(to_tsvector('simple'::regconfig, coalesce(name, '')), 'A') || " +
+		"to_tsvector('simple'::regconfig, coalesce(bio, ''))) STORED;\n" +
+		"CREATE INDEX drivers_search_idx ON drivers USING GIN (search);\n"
+	if up != wantUp {
+		t.Errorf("up =\n%s\nwant\n%s", up, wantUp)
+	}
+	wantDown := "DROP INDEX IF EXISTS drivers_search_idx;\nALTER TABLE drivers DROP COLUMN IF EXISTS search;\n"
+	if down != wantDown {
+		t.Errorf("down =\n%s\nwant\n%s", down, wantDown)
+	}
+
+	invalid := []struct {
+		name        string
+		column      TextSearchColumn
+		errContains string
+	}{
+		{"table", TextSearchColumn{Table: "a b", Column: "search", Config: "simple", Sources: []TextSearchSource{{Column: "name"}}}, "table"},
+		{"column", TextSearchColumn{Table: "drivers", Column: "x.search", Config: "simple", Sources: []TextSearchSource{{Column: "name"}}}, "column"},
+		{"config", TextSearchColumn{Table: "drivers", Column: "search", Config: "'x'", Sources: []TextSearchSource{{Column: "name"}}}, "config"},
+		{"no sources", TextSearchColumn{Table: "drivers", Column: "search", Config: "simple"}, "at least one source"},
+		{"source", TextSearchColumn{Table: "drivers", Column: "search", Config: "simple", Sources: []TextSearchSource{{Column: "name)"}}}, "source"},
+		{"weight", TextSearchColumn{Table: "drivers", Column: "search", Config: "simple", Sources: []TextSearchSource{{Column: "name", Weight: "E"}}}, "weight"},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, _, err := TextSearchMigration(tt.column)
+			if !IsCode(err, CodeInvalidInput) || !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("TextSearchMigration() error = %v, want invalid input containing %q", err, tt.errContains)
+			}
+		})
+	}
+}
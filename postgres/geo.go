@@ -0,0 +1,239 @@
+// Package postgres provides PostgreSQL database utilities.
+package postgres
+
+import (
+	"database/sql/driver"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/Dorico-Dynamics/txova-go-types/pagination"
+)
+
+// SRIDWGS84 is the spatial reference id of WGS 84 longitude/latitude coordinates used by GPS.
+const SRIDWGS84 = 4326
+
+// EWKB geometry type codes and flags.
+const (
+	wkbPoint   = 1
+	wkbSRIDBit = 0x20000000
+	wkbZMBits  = 0xC0000000
+)
+
+// Point is a WGS 84 location. It is encoded as a PostGIS geography or geometry
+// parameter and decodes from a geography(Point, 4326) or geometry(Point, 4326) column.
+type Point struct {
+	Lat float64
+	Lng float64
+}
+
+// validate checks that the point has valid coordinates.
+func (p Point) validate() error {
+	if math.IsNaN(p.Lat) || p.Lat < -90 || p.Lat > 90 {
+		return fmt.Errorf("invalid latitude: %v", p.Lat)
+	}
+	if math.IsNaN(p.Lng) || p.Lng < -180 || p.Lng > 180 {
+		return fmt.Errorf("invalid longitude: %v", p.Lng)
+	}
+	return nil
+}
+
+// coordinates returns the point as WKT "lng lat".
+func (p Point) coordinates() string {
+	return strconv.FormatFloat(p.Lng, 'f', -1, 64) + " " + strconv.FormatFloat(p.Lat, 'f', -1, 64)
+}
+
+// String returns the point as EWKT, e.g. SRID=4326;POINT(32.5732 -25.9692).
+func (p Point) String() string {
+	return fmt.Sprintf("SRID=%d;POINT(%s)", SRIDWGS84, p.coordinates())
+}
+
+// Value implements driver.Valuer, encoding the point as EWKT.
+func (p Point) Value() (driver.Value, error) {
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+	return p.String(), nil
+}
+
+// Scan implements sql.Scanner, decoding a point from EWKB in binary or hex form,
+// which is how PostGIS returns geography and geometry values.
+func (p *Point) Scan(src any) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		return fmt.Errorf("cannot scan NULL into Point")
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return fmt.Errorf("cannot scan %T into Point", src)
+	}
+	if len(data) > 0 && data[0] != 0 && data[0] != 1 {
+		decoded, err := hex.DecodeString(string(data))
+		if err != nil {
+			return fmt.Errorf("invalid point: %w", err)
+		}
+		data = decoded
+	}
+	return p.decodeEWKB(data)
+}
+
+// decodeEWKB decodes a 2D point from (E)WKB.
+func (p *Point) decodeEWKB(data []byte) error {
+	if len(data) < 5 {
+		return fmt.Errorf("invalid point: %d bytes", len(data))
+	}
+	var order binary.ByteOrder = binary.BigEndian
+	if data[0] == 1 {
+		order = binary.LittleEndian
+	}
+	geomType := order.Uint32(data[1:5])
+	data = data[5:]
+	if geomType&wkbZMBits != 0 || geomType&^wkbSRIDBit != wkbPoint {
+		return fmt.Errorf("invalid point: unsupported geometry type %#x", geomType)
+	}
+	if geomType&wkbSRIDBit != 0 {
+		if len(data) < 4 {
+			return fmt.Errorf("invalid point: missing srid")
+		}
+		if srid := order.Uint32(data[:4]); srid != SRIDWGS84 {
+			return fmt.Errorf("invalid point: srid %d, want %d", srid, SRIDWGS84)
+		}
+		data = data[4:]
+	}
+	if len(data) != 16 {
+		return fmt.Errorf("invalid point: %d coordinate bytes", len(data))
+	}
+	p.Lng = math.Float64frombits(order.Uint64(data[:8]))
+	p.Lat = math.Float64frombits(order.Uint64(data[8:]))
+	return nil
+}
+
+// distanceColumn represents an ST_Distance column added with SelectDistance.
+type distanceColumn struct {
+	column string
+	from   Point
+	alias  string
+}
+
+// polygonEWKT returns the polygon as EWKT, closing the ring if needed.
+func polygonEWKT(polygon []Point) (string, error) {
+	if len(polygon) < 3 {
+		return "", fmt.Errorf("polygon requires at least 3 points, got %d", len(polygon))
+	}
+	ring := make([]string, 0, len(polygon)+1)
+	for _, p := range polygon {
+		if err := p.validate(); err != nil {
+			return "", fmt.Errorf("invalid polygon: %w", err)
+		}
+		ring = append(ring, p.coordinates())
+	}
+	if polygon[0] != polygon[len(polygon)-1] {
+		ring = append(ring, ring[0])
+	} else if len(polygon) < 4 {
+		return "", fmt.Errorf("polygon requires at least 3 distinct points")
+	}
+	return fmt.Sprintf("SRID=%d;POLYGON((%s))", SRIDWGS84, strings.Join(ring, ", ")), nil
+}
+
+// WhereWithinDistance adds a WHERE ST_DWithin(column, point, meters) condition matching
+// locations within meters of lat/lng. The column should be a geography(Point, 4326)
+// with a GiST index, which this condition uses.
+func (s *SelectBuilder) WhereWithinDistance(column string, lat, lng, meters float64) *SelectBuilder {
+	point := Point{Lat: lat, Lng: lng}
+	err := point.validate()
+	if err == nil && (math.IsNaN(meters) || math.IsInf(meters, 0) || meters < 0) {
+		err = fmt.Errorf("invalid distance: %v", meters)
+	}
+	if err == nil {
+		err = validateColumnIdentifier(column)
+	}
+	s.where = append(s.where, whereClause{
+		condition: fmt.Sprintf("ST_DWithin(%s, ?::geography, ?)", quoteIdent(column)),
+		args:      []any{point, meters},
+		err:       err,
+	})
+	return s
+}
+
+// WhereInsidePolygon adds a WHERE ST_Covers(polygon, column) condition matching locations
+// inside the polygon or on its boundary. The ring is closed automatically.
+func (s *SelectBuilder) WhereInsidePolygon(column string, polygon []Point) *SelectBuilder {
+	ewkt, err := polygonEWKT(polygon)
+	if err == nil {
+		err = validateColumnIdentifier(column)
+	}
+	s.where = append(s.where, whereClause{
+		condition: fmt.Sprintf("ST_Covers(?::geography, %s)", quoteIdent(column)),
+		args:      []any{ewkt},
+		err:       err,
+	})
+	return s
+}
+
+// SelectDistance adds the distance in meters between column and lat/lng as a column named alias.
+func (s *SelectBuilder) SelectDistance(column string, lat, lng float64, alias string) *SelectBuilder {
+	s.distances = append(s.distances, distanceColumn{column: column, from: Point{Lat: lat, Lng: lng}, alias: alias})
+	return s
+}
+
+// OrderByDistance orders by distance from lat/lng, nearest first, using the
+// KNN operator column <-> point so a GiST index on column can be used.
+func (s *SelectBuilder) OrderByDistance(column string, lat, lng float64) *SelectBuilder {
+	s.orderBy = append(s.orderBy, orderByClause{
+		column:       column,
+		direction:    pagination.SortAsc,
+		distanceFrom: &Point{Lat: lat, Lng: lng},
+	})
+	return s
+}
+
+// validateGeo checks the distance columns and distance ordering.
+func (s *SelectBuilder) validateGeo() error {
+	for _, d := range s.distances {
+		if err := s.validateColumnName(d.column); err != nil {
+			return fmt.Errorf("invalid distance column: %w", err)
+		}
+		if err := d.from.validate(); err != nil {
+			return err
+		}
+		if !identifierRegex.MatchString(d.alias) {
+			return fmt.Errorf("invalid distance alias: %q", d.alias)
+		}
+	}
+	for _, o := range s.orderBy {
+		if o.distanceFrom == nil {
+			continue
+		}
+		if err := o.distanceFrom.validate(); err != nil {
+			return err
+		}
+		if s.keyset != nil {
+			return fmt.Errorf("OrderByDistance cannot be used with keyset pagination")
+		}
+	}
+	return nil
+}
+
+// distanceExprs returns the SELECT list expressions for the distance columns.
+func (s *SelectBuilder) distanceExprs() []selectExpr {
+	exprs := make([]selectExpr, len(s.distances))
+	for i, d := range s.distances {
+		exprs[i] = selectExpr{
+			expr: fmt.Sprintf("ST_Distance(%s, ?::geography) AS %s", quoteIdent(d.column), d.alias),
+			args: []any{d.from},
+		}
+	}
+	return exprs
+}
+
+// distanceOrderExpr returns the KNN ORDER BY expression for column and point.
+// The point is inlined as a constant, which the planner requires to use the index.
+func distanceOrderExpr(column string, p Point) string {
+	return fmt.Sprintf("%s <-> '%s'::geography", quoteIdent(column), p)
+}
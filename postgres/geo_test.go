@@ -0,0 +1,164 @@
+package postgres
+
+import (
+	"encoding/hex"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestGeoPredicates(t *testing.T) {
+	t.Parallel()
+
+	maputo := Point{Lat: -25.9692, Lng: 32.5732}
+	zone := []Point{{Lat: -25.9, Lng: 32.5}, {Lat: -25.9, Lng: 32.6}, {Lat: -26.0, Lng: 32.6}}
+
+	tests := []struct {
+		name        string
+		builder     Builder
+		wantSQL     string
+		wantArgs    []any
+		errContains string
+	}{
+		{
+			name: "within distance",
+			builder: Select("drivers").
+				Columns("id").
+				Where("available = ?", true).
+				WhereWithinDistance("location", -25.9692, 32.5732, 2000),
+			wantSQL:  "SELECT id FROM drivers WHERE available = $1 AND ST_DWithin(location, $2::geography, $3)",
+			wantArgs: []any{true, maputo, float64(2000)},
+		},
+		{
+			name: "nearest drivers with distance",
+			builder: Select("drivers").
+				Columns("id").
+				SelectDistance("location", -25.9692, 32.5732, "distance").
+				WhereWithinDistance("location", -25.9692, 32.5732, 5000).
+				OrderByDistance("location", -25.9692, 32.5732).
+				Limit(5),
+			wantSQL: "SELECT id, ST_Distance(location, $1::geography) AS distance FROM drivers " +
+				"WHERE ST_DWithin(location, $2::geography, $3) " +
+				"ORDER BY location <-> 'SRID=4326;POINT(32.5732 -25.9692)'::geography ASC LIMIT 5",
+			wantArgs: []any{maputo, maputo, float64(5000)},
+		},
+		{
+			name:     "inside polygon closes ring",
+			builder:  Select("trips").Columns("id").WhereInsidePolygon("pickup", zone),
+			wantSQL:  "SELECT id FROM trips WHERE ST_Covers($1::geography, pickup)",
+			wantArgs: []any{"SRID=4326;POLYGON((32.5 -25.9, 32.6 -25.9, 32.6 -26, 32.5 -25.9))"},
+		},
+		{
+			name:     "inside closed polygon",
+			builder:  Select("trips").Columns("id").WhereInsidePolygon("pickup", append(zone, zone[0])),
+			wantSQL:  "SELECT id FROM trips WHERE ST_Covers($1::geography, pickup)",
+			wantArgs: []any{"SRID=4326;POLYGON((32.5 -25.9, 32.6 -25.9, 32.6 -26, 32.5 -25.9))"},
+		},
+		{
+			name:        "invalid latitude",
+			builder:     Select("drivers").WhereWithinDistance("location", 91, 32.5, 100),
+			errContains: "invalid latitude",
+		},
+		{
+			name:        "negative distance",
+			builder:     Select("drivers").WhereWithinDistance("location", -25.9, 32.5, -1),
+			errContains: "invalid distance",
+		},
+		{
+			name:        "too few polygon points",
+			builder:     Select("trips").WhereInsidePolygon("pickup", zone[:2]),
+			errContains: "at least 3 points",
+		},
+		{
+			name:        "degenerate closed polygon",
+			builder:     Select("trips").WhereInsidePolygon("pickup", []Point{zone[0], zone[1], zone[0]}),
+			errContains: "3 distinct points",
+		},
+		{
+			name:        "invalid order by longitude",
+			builder:     Select("drivers").OrderByDistance("location", 0, 181),
+			errContains: "invalid longitude",
+		},
+		{
+			name:        "order by column not in allowlist",
+			builder:     SelectWithAllowlist("drivers", "id").OrderByDistance("location", 0, 0),
+			errContains: "invalid order by column",
+		},
+		{
+			name:        "invalid distance alias",
+			builder:     Select("drivers").SelectDistance("location", 0, 0, "d; DROP"),
+			errContains: "invalid distance alias",
+		},
+		{
+			name:        "order by distance with keyset",
+			builder:     Select("drivers").OrderByDistance("location", 0, 0).PageAfter(nil, 10),
+			errContains: "cannot be used with keyset pagination",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assertBuild(t, tt.builder, tt.wantSQL, tt.wantArgs, tt.errContains)
+		})
+	}
+}
+
+func TestPoint_Value(t *testing.T) {
+	t.Parallel()
+
+	v, err := Point{Lat: -25.9692, Lng: 32.5732}.Value()
+	if err != nil {
+		t.Fatalf("Value() error = %v", err)
+	}
+	if v != "SRID=4326;POINT(32.5732 -25.9692)" {
+		t.Errorf("Value() = %v", v)
+	}
+	if _, err := (Point{Lat: math.NaN()}).Value(); err == nil {
+		t.Error("Value() with NaN latitude should fail")
+	}
+}
+
+func TestPoint_Scan(t *testing.T) {
+	t.Parallel()
+
+	littleEndianEWKB := "0101000020E61000009A081B9E5E4940408048BF7D1DF839C0"
+	bigEndianWKB := "00000000014040495e9e1b089ac039f81d7dbf4880"
+	binaryWKB, _ := hex.DecodeString(bigEndianWKB)
+
+	tests := []struct {
+		name        string
+		src         any
+		errContains string
+	}{
+		{name: "hex ewkb string", src: littleEndianEWKB},
+		{name: "hex ewkb bytes", src: []byte(littleEndianEWKB)},
+		{name: "binary wkb", src: binaryWKB},
+		{name: "null", src: nil, errContains: "NULL"},
+		{name: "unsupported type", src: 42, errContains: "cannot scan int"},
+		{name: "not hex", src: "POINT(1 2)", errContains: "invalid point"},
+		{name: "linestring", src: "010200000000000000", errContains: "unsupported geometry type"},
+		{name: "other srid", src: "0101000020110F00009A081B9E5E4940408048BF7D1DF839C0", errContains: "srid 3857"},
+		{name: "truncated", src: "0101000020E61000009A081B9E", errContains: "coordinate bytes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var p Point
+			err := p.Scan(tt.src)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Errorf("Scan() error = %v, want containing %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Scan() error = %v", err)
+			}
+			if p != (Point{Lat: -25.9692, Lng: 32.5732}) {
+				t.Errorf("Scan() = %+v", p)
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+// Package postgres provides PostgreSQL database utilities.
+package postgres
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Raw is a SQL expression that builders insert verbatim where they otherwise
+// expect a column name, such as COUNT(*) AS trips or lower(email).
+// Raw expressions bypass identifier validation, quoting and column allowlists,
+// so they must never contain user input.
+type Raw string
+
+// Maximum number of dot-separated parts of table names (schema.table)
+// and column names (schema.table.column).
+const (
+	maxTableParts  = 2
+	maxColumnParts = 3
+)
+
+// bareIdentifierRegex matches an unquoted identifier part.
+var bareIdentifierRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_$]*$`)
+
+// plainIdentifierRegex matches an identifier part that can be written without quotes.
+var plainIdentifierRegex = regexp.MustCompile(`^[a-z_][a-z0-9_$]*$`)
+
+// reservedKeywords are the PostgreSQL keywords that cannot be used as bare
+// table or column names, such as order and user.
+var reservedKeywords = map[string]struct{}{
+	"all": {}, "analyse": {}, "analyze": {}, "and": {}, "any": {}, "array": {}, "as": {}, "asc": {},
+	"asymmetric": {}, "authorization": {}, "binary": {}, "both": {}, "case": {}, "cast": {},
+	"check": {}, "collate": {}, "collation": {}, "column": {}, "concurrently": {}, "constraint": {},
+	"create": {}, "cross": {}, "current_catalog": {}, "current_date": {}, "current_role": {},
+	"current_schema": {}, "current_time": {}, "current_timestamp": {}, "current_user": {},
+	"default": {}, "deferrable": {}, "desc": {}, "distinct": {}, "do": {}, "else": {}, "end": {},
+	"except": {}, "false": {}, "fetch": {}, "for": {}, "foreign": {}, "freeze": {}, "from": {},
+	"full": {}, "grant": {}, "group": {}, "having": {}, "ilike": {}, "in": {}, "initially": {},
+	"inner": {}, "intersect": {}, "into": {}, "is": {}, "isnull": {}, "join": {}, "lateral": {},
+	"leading": {}, "left": {}, "like": {}, "limit": {}, "localtime": {}, "localtimestamp": {},
+	"natural": {}, "not": {}, "notnull": {}, "null": {}, "offset": {}, "on": {}, "only": {},
+	"or": {}, "order": {}, "outer": {}, "overlaps": {}, "placing": {}, "primary": {},
+	"references": {}, "returning": {}, "right": {}, "select": {}, "session_user": {},
+	"similar": {}, "some": {}, "symmetric": {}, "system_user": {}, "table": {}, "tablesample": {},
+	"then": {}, "to": {}, "trailing": {}, "true": {}, "union": {}, "unique": {}, "user": {},
+	"using": {}, "variadic": {}, "verbose": {}, "when": {}, "where": {}, "window": {}, "with": {},
+}
+
+// QuoteIdentifier quotes a single identifier, escaping embedded double quotes.
+// It always quotes, so the name is used exactly, including its case:
+// QuoteIdentifier("createdAt") returns "createdAt" in double quotes.
+// Use it to reference columns inside conditions and Raw expressions.
+func QuoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// parseIdentifier splits a possibly qualified name such as public.users or
+// "Trips"."order" into its parts, without quotes. Unquoted parts are folded
+// to lower case, as PostgreSQL does.
+func parseIdentifier(name string, maxParts int) ([]string, error) {
+	var parts []string
+	rest := name
+	for {
+		var part string
+		if strings.HasPrefix(rest, `"`) {
+			var sb strings.Builder
+			end := 1
+			for {
+				i := strings.IndexByte(rest[end:], '"')
+				if i < 0 {
+					return nil, fmt.Errorf("unterminated quoted identifier: %s", name)
+				}
+				sb.WriteString(rest[end : end+i])
+				end += i + 1
+				if !strings.HasPrefix(rest[end:], `"`) {
+					break
+				}
+				sb.WriteByte('"')
+				end++
+			}
+			part = sb.String()
+			if part == "" || strings.ContainsRune(part, 0) {
+				return nil, fmt.Errorf("invalid quoted identifier: %s", name)
+			}
+			rest = rest[end:]
+		} else {
+			end := strings.IndexByte(rest, '.')
+			if end < 0 {
+				end = len(rest)
+			}
+			if !bareIdentifierRegex.MatchString(rest[:end]) {
+				return nil, fmt.Errorf("invalid identifier: %s", name)
+			}
+			part = strings.ToLower(rest[:end])
+			rest = rest[end:]
+		}
+		parts = append(parts, part)
+		if rest == "" {
+			break
+		}
+		if rest[0] != '.' {
+			return nil, fmt.Errorf("invalid identifier: %s", name)
+		}
+		rest = rest[1:]
+	}
+	if len(parts) > maxParts {
+		return nil, fmt.Errorf("identifier has more than %d parts: %s", maxParts, name)
+	}
+	return parts, nil
+}
+
+// formatIdentifier joins identifier parts with dots, quoting the parts that
+// are reserved keywords or are not plain lower-case names.
+func formatIdentifier(parts []string) string {
+	quoted := make([]string, len(parts))
+	for i, part := range parts {
+		if _, reserved := reservedKeywords[part]; reserved || !plainIdentifierRegex.MatchString(part) {
+			quoted[i] = QuoteIdentifier(part)
+		} else {
+			quoted[i] = part
+		}
+	}
+	return strings.Join(quoted, ".")
+}
+
+// quoteIdent formats a table or column name for SQL, quoting the parts that need it.
+// A trailing * is kept, as in trips.*. Names that do not parse are returned
+// unchanged; builders validate names before building.
+func quoteIdent(name string) string {
+	if name == "*" {
+		return name
+	}
+	if prefix, ok := strings.CutSuffix(name, ".*"); ok {
+		return quoteIdent(prefix) + ".*"
+	}
+	parts, err := parseIdentifier(name, maxColumnParts)
+	if err != nil {
+		return name
+	}
+	return formatIdentifier(parts)
+}
+
+// identifierPrefix joins the parts of a validated name with underscores,
+// for deriving object names such as the index public_trips_search_idx.
+func identifierPrefix(name string) string {
+	parts, _ := parseIdentifier(name, maxColumnParts)
+	return strings.Join(parts, "_")
+}
+
+// validateColumnIdentifier checks that a name used in a condition helper is a column name.
+func validateColumnIdentifier(column string) error {
+	if _, err := parseIdentifier(column, maxColumnParts); err != nil {
+		return fmt.Errorf("invalid column name: %s", column)
+	}
+	return nil
+}
+
+// columnRef is an entry of a column list: a column name, or a Raw expression if raw is set.
+type columnRef struct {
+	name string
+	raw  bool
+}
+
+// columnRefs converts column names to column list entries.
+func columnRefs(names []string) []columnRef {
+	refs := make([]columnRef, len(names))
+	for i, name := range names {
+		refs[i] = columnRef{name: name}
+	}
+	return refs
+}
+
+// rawColumnRefs converts Raw expressions to column list entries.
+func rawColumnRefs(exprs []Raw) []columnRef {
+	refs := make([]columnRef, len(exprs))
+	for i, expr := range exprs {
+		refs[i] = columnRef{name: string(expr), raw: true}
+	}
+	return refs
+}
+
+// sql returns the entry as SQL: the quoted column name or the raw expression.
+func (c columnRef) sql() string {
+	if c.raw {
+		return c.name
+	}
+	return quoteIdent(c.name)
+}
+
+// joinColumnRefs renders a column list separated by commas.
+func joinColumnRefs(refs []columnRef) string {
+	parts := make([]string, len(refs))
+	for i, ref := range refs {
+		parts[i] = ref.sql()
+	}
+	return strings.Join(parts, ", ")
+}
+
+// quoteIdents quotes each name of a column list and joins them with commas.
+func quoteIdents(names []string) string {
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = quoteIdent(name)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,149 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/Dorico-Dynamics/txova-go-types/pagination"
+)
+
+func TestQuoteIdent(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "id", want: "id"},
+		{name: "users.id", want: "users.id"},
+		{name: "order", want: `"order"`},
+		{name: "ORDER", want: `"order"`},
+		{name: "trips.user", want: `trips."user"`},
+		{name: "CreatedAt", want: "createdat"},
+		{name: `"createdAt"`, want: `"createdAt"`},
+		{name: `"plain"`, want: "plain"},
+		{name: `public."Trips"`, want: `public."Trips"`},
+		{name: `"a""b"`, want: `"a""b"`},
+		{name: `"with space"`, want: `"with space"`},
+		{name: "*", want: "*"},
+		{name: "user.*", want: `"user".*`},
+		{name: "COUNT(*)", want: "COUNT(*)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := quoteIdent(tt.name); got != tt.want {
+				t.Errorf("quoteIdent(%q) = %s, want %s", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuoteIdentifier(t *testing.T) {
+	t.Parallel()
+
+	if got := QuoteIdentifier(`my"col`); got != `"my""col"` {
+		t.Errorf("QuoteIdentifier() = %s", got)
+	}
+	if got := QuoteIdentifier("createdAt"); got != `"createdAt"` {
+		t.Errorf("QuoteIdentifier() = %s", got)
+	}
+}
+
+func TestValidateTableName_Identifiers(t *testing.T) {
+	t.Parallel()
+
+	for _, name := range []string{"users", "public.users", `"Users"`, `public."user"`, "user"} {
+		if err := validateTableName(name); err != nil {
+			t.Errorf("validateTableName(%q) = %v, want nil", name, err)
+		}
+	}
+	for _, name := range []string{"", "a.b.c", "users u", `"users`, "users;"} {
+		if err := validateTableName(name); err == nil {
+			t.Errorf("validateTableName(%q) = nil, want error", name)
+		}
+	}
+}
+
+func TestBuilders_IdentifierQuoting(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		builder     Builder
+		wantSQL     string
+		wantArgs    []any
+		errContains string
+	}{
+		{
+			name: "select reserved and mixed-case names",
+			builder: Select("user").Columns("id", "order", `"displayName"`, "user.*").
+				Join(`public."Trips"`, `"Trips".rider_id = "user".id`).
+				WhereNull("order").WhereIn("group", 1, 2).
+				GroupBy("order").OrderByDesc(`"displayName"`),
+			wantSQL: `SELECT id, "order", "displayName", "user".* FROM "user" INNER JOIN public."Trips" ON "Trips".rider_id = "user".id ` +
+				`WHERE "order" IS NULL AND "group" IN ($1, $2) GROUP BY "order" ORDER BY "displayName" DESC`,
+			wantArgs: []any{1, 2},
+		},
+		{
+			name: "raw expressions",
+			builder: Select("trips").Columns("driver_id").ColumnsRaw("COUNT(*) AS trips").
+				GroupByRaw("date_trunc('day', created_at)").GroupBy("driver_id").
+				OrderByRaw("lower(city)", pagination.SortAsc),
+			wantSQL: "SELECT driver_id, COUNT(*) AS trips FROM trips GROUP BY date_trunc('day', created_at), driver_id ORDER BY lower(city) ASC",
+		},
+		{
+			name:        "expression in columns",
+			builder:     Select("trips").Columns("COUNT(*)"),
+			errContains: "invalid column name: COUNT(*)",
+		},
+		{
+			name:        "expression in where helper",
+			builder:     Select("users").WhereLike("lower(email)", "%@x.com"),
+			errContains: "invalid column name: lower(email)",
+		},
+		{
+			name:        "expression in order by",
+			builder:     Select("users").OrderByAsc("random()"),
+			errContains: "invalid order by column",
+		},
+		{
+			name: "upsert with reserved columns",
+			builder: Insert("settings").Columns("user", "default").Values(1, "x").
+				OnConflictDoUpdate("user").SetExcluded("default").
+				Returning("user").ReturningRaw("xmax = 0 AS inserted"),
+			wantSQL: `INSERT INTO settings ("user", "default") VALUES ($1, $2) ON CONFLICT ("user") DO UPDATE SET "default" = EXCLUDED."default" ` +
+				`RETURNING "user", xmax = 0 AS inserted`,
+			wantArgs: []any{1, "x"},
+		},
+		{
+			name:     "update reserved columns",
+			builder:  Update(`"Orders"`).Set("limit", 5).Where("id = ?", 1).Returning("limit"),
+			wantSQL:  `UPDATE "Orders" SET "limit" = $1 WHERE id = $2 RETURNING "limit"`,
+			wantArgs: []any{5, 1},
+		},
+		{
+			name:        "update expression column",
+			builder:     Update("orders").Set("total + 1", 5).Where("id = ?", 1),
+			errContains: "invalid column name",
+		},
+		{
+			name:     "delete returning raw",
+			builder:  Delete("order").WhereInArray("user", []int{1}).ReturningRaw("count(*) OVER ()"),
+			wantSQL:  `DELETE FROM "order" WHERE "user" = ANY($1) RETURNING count(*) OVER ()`,
+			wantArgs: []any{[]int{1}},
+		},
+		{
+			name:        "delete returning expression",
+			builder:     Delete("orders").Where("id = ?", 1).Returning("id + 1"),
+			errContains: "invalid returning column",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assertBuild(t, tt.builder, tt.wantSQL, tt.wantArgs, tt.errContains)
+		})
+	}
+}
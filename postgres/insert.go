@@ -9,24 +9,46 @@ import (
 // InsertBuilder builds INSERT queries.
 type InsertBuilder struct {
 	*QueryBuilder
+	with       withClause
 	table      string
 	columns    []string
 	values     [][]any
-	returning  []string
+	returning  []columnRef
 	onConflict *conflictClause
+	structErr  error
+
+	chunkSize     int
+	copyThreshold int
 }
 
 // conflictClause represents ON CONFLICT handling.
 type conflictClause struct {
-	columns    []string
-	doNothing  bool
-	constraint string
+	columns     []string
+	targetWhere []whereClause
+	doNothing   bool
+	doUpdate    bool
+	constraint  string
+	sets        []conflictSet
+	where       []whereClause
+}
+
+// conflictSet represents an assignment in ON CONFLICT DO UPDATE.
+// If excluded is set, the column is assigned from the EXCLUDED row.
+type conflictSet struct {
+	column   string
+	value    any
+	excluded bool
 }
 
 // setClause represents a column = value pair.
+// If expr is set, the column is assigned the expression with ? placeholders for args.
+// If err is set, the assignment could not be created and the query fails validation.
 type setClause struct {
 	column string
 	value  any
+	expr   string
+	args   []any
+	err    error
 }
 
 // Insert creates a new InsertBuilder for the specified table.
@@ -36,7 +58,7 @@ func Insert(table string) *InsertBuilder {
 		table:        table,
 		columns:      []string{},
 		values:       [][]any{},
-		returning:    []string{},
+		returning:    []columnRef{},
 	}
 }
 
@@ -47,7 +69,7 @@ func InsertWithAllowlist(table string, allowedColumns ...string) *InsertBuilder
 		table:        table,
 		columns:      []string{},
 		values:       [][]any{},
-		returning:    []string{},
+		returning:    []columnRef{},
 	}
 }
 
@@ -65,7 +87,13 @@ func (i *InsertBuilder) Values(values ...any) *InsertBuilder {
 
 // Returning specifies columns to return after insert.
 func (i *InsertBuilder) Returning(columns ...string) *InsertBuilder {
-	i.returning = append(i.returning, columns...)
+	i.returning = append(i.returning, columnRefs(columns)...)
+	return i
+}
+
+// ReturningRaw adds expressions to return after insert, e.g. Raw("xmax = 0 AS inserted").
+func (i *InsertBuilder) ReturningRaw(exprs ...Raw) *InsertBuilder {
+	i.returning = append(i.returning, rawColumnRefs(exprs)...)
 	return i
 }
 
@@ -87,8 +115,81 @@ func (i *InsertBuilder) OnConflictConstraintDoNothing(constraint string) *Insert
 	return i
 }
 
+// validate implements Builder.
+func (i *InsertBuilder) validate() error {
+	return i.validateInsert()
+}
+
+// OnConflictDoUpdate adds ON CONFLICT (columns) DO UPDATE, turning the insert into an upsert.
+// Use Set and SetExcluded to specify the assignments.
+func (i *InsertBuilder) OnConflictDoUpdate(targetColumns ...string) *InsertBuilder {
+	c := i.conflict()
+	c.columns = targetColumns
+	c.constraint = ""
+	c.doNothing = false
+	c.doUpdate = true
+	return i
+}
+
+// OnConflictConstraintDoUpdate adds ON CONFLICT ON CONSTRAINT ... DO UPDATE.
+func (i *InsertBuilder) OnConflictConstraintDoUpdate(constraint string) *InsertBuilder {
+	c := i.conflict()
+	c.columns = nil
+	c.constraint = constraint
+	c.doNothing = false
+	c.doUpdate = true
+	return i
+}
+
+// Set adds a column = value assignment to the ON CONFLICT DO UPDATE clause.
+func (i *InsertBuilder) Set(column string, value any) *InsertBuilder {
+	c := i.conflict()
+	c.sets = append(c.sets, conflictSet{column: column, value: value})
+	return i
+}
+
+// SetExcluded adds column = EXCLUDED.column assignments to the ON CONFLICT DO UPDATE
+// clause, updating the columns with the values proposed for insertion.
+func (i *InsertBuilder) SetExcluded(columns ...string) *InsertBuilder {
+	c := i.conflict()
+	for _, col := range columns {
+		c.sets = append(c.sets, conflictSet{column: col, excluded: true})
+	}
+	return i
+}
+
+// ConflictWhere adds a condition to the ON CONFLICT DO UPDATE clause.
+// Rows not matching the condition are not updated.
+func (i *InsertBuilder) ConflictWhere(condition string, args ...any) *InsertBuilder {
+	c := i.conflict()
+	c.where = append(c.where, whereClause{condition: condition, args: args})
+	return i
+}
+
+// ConflictTargetWhere adds an index predicate to the conflict target,
+// allowing a partial unique index to be inferred as the arbiter.
+func (i *InsertBuilder) ConflictTargetWhere(condition string, args ...any) *InsertBuilder {
+	c := i.conflict()
+	c.targetWhere = append(c.targetWhere, whereClause{condition: condition, args: args})
+	return i
+}
+
+// conflict returns the ON CONFLICT clause, creating it if needed.
+func (i *InsertBuilder) conflict() *conflictClause {
+	if i.onConflict == nil {
+		i.onConflict = &conflictClause{}
+	}
+	return i.onConflict
+}
+
 // validateInsert checks that the insert has valid table, columns, and values.
 func (i *InsertBuilder) validateInsert() error {
+	if i.structErr != nil {
+		return i.structErr
+	}
+	if err := i.with.validate(); err != nil {
+		return err
+	}
 	if err := validateTableName(i.table); err != nil {
 		return err
 	}
@@ -135,12 +236,17 @@ func (i *InsertBuilder) validateInsertValues() error {
 
 // validateReturningColumns validates the RETURNING columns.
 func (i *InsertBuilder) validateReturningColumns() error {
-	for _, col := range i.returning {
-		if col == "*" {
+	return validateReturningColumns(i.QueryBuilder, i.returning)
+}
+
+// validateReturningColumns validates the RETURNING columns of an insert, update or delete.
+func validateReturningColumns(qb *QueryBuilder, returning []columnRef) error {
+	for _, col := range returning {
+		if col.raw || col.name == "*" {
 			continue
 		}
-		if err := i.validateColumnName(col); err != nil {
-			return fmt.Errorf("invalid returning column: %q", col)
+		if err := qb.validateColumnName(col.name); err != nil {
+			return fmt.Errorf("invalid returning column: %q", col.name)
 		}
 	}
 	return nil
@@ -161,17 +267,49 @@ func (i *InsertBuilder) validateOnConflict() error {
 			return fmt.Errorf("invalid on conflict column: %q", col)
 		}
 	}
+	return i.validateConflictUpdate()
+}
+
+// validateConflictUpdate validates the ON CONFLICT DO UPDATE clause.
+func (i *InsertBuilder) validateConflictUpdate() error {
+	c := i.onConflict
+	if !c.doUpdate {
+		if len(c.sets) > 0 || len(c.where) > 0 {
+			return fmt.Errorf("on conflict assignments require OnConflictDoUpdate")
+		}
+		if len(c.targetWhere) > 0 && len(c.columns) == 0 {
+			return fmt.Errorf("on conflict target predicate requires target columns")
+		}
+		return nil
+	}
+	if len(c.columns) == 0 && c.constraint == "" {
+		return fmt.Errorf("on conflict do update requires target columns or a constraint")
+	}
+	if len(c.targetWhere) > 0 && c.constraint != "" {
+		return fmt.Errorf("on conflict target predicate cannot be used with a constraint")
+	}
+	if len(c.sets) == 0 {
+		return fmt.Errorf("no columns specified for on conflict do update")
+	}
+	for _, set := range c.sets {
+		// SET targets are columns of the inserted table and cannot be qualified.
+		if err := i.validateColumnName(set.column); err != nil {
+			return fmt.Errorf("invalid on conflict update column: %q", set.column)
+		}
+		if _, err := parseIdentifier(set.column, 1); err != nil {
+			return fmt.Errorf("invalid on conflict update column: %q", set.column)
+		}
+	}
 	return nil
 }
 
-// buildValuesClause generates the VALUES portion and collects arguments.
-func (i *InsertBuilder) buildValuesClause() (string, []any) {
+// buildValuesClause generates the VALUES portion and returns args and next arg index.
+func (i *InsertBuilder) buildValuesClause(argIndex int) (string, []any, int) {
 	totalArgs := 0
 	for _, row := range i.values {
 		totalArgs += len(row)
 	}
 	args := make([]any, 0, totalArgs)
-	argIndex := 1
 	valueParts := make([]string, len(i.values))
 
 	for rowIdx, row := range i.values {
@@ -184,28 +322,52 @@ func (i *InsertBuilder) buildValuesClause() (string, []any) {
 		args = append(args, row...)
 	}
 
-	return strings.Join(valueParts, ", "), args
+	return strings.Join(valueParts, ", "), args, argIndex
 }
 
-// buildOnConflictClause generates the ON CONFLICT portion.
-func (i *InsertBuilder) buildOnConflictClause() string {
+// buildOnConflictClause generates the ON CONFLICT portion and returns args and next arg index.
+func (i *InsertBuilder) buildOnConflictClause(argIndex int) (string, []any, int) {
 	if i.onConflict == nil {
-		return ""
+		return "", nil, argIndex
 	}
 	var sb strings.Builder
+	var args []any
 	sb.WriteString(" ON CONFLICT")
 	if i.onConflict.constraint != "" {
 		sb.WriteString(" ON CONSTRAINT ")
-		sb.WriteString(i.onConflict.constraint)
+		sb.WriteString(quoteIdent(i.onConflict.constraint))
 	} else if len(i.onConflict.columns) > 0 {
 		sb.WriteString(" (")
-		sb.WriteString(strings.Join(i.onConflict.columns, ", "))
+		sb.WriteString(quoteIdents(i.onConflict.columns))
 		sb.WriteString(")")
+		targetWhere, targetArgs, newIndex := buildConditionClauses(i.onConflict.targetWhere, " WHERE ", argIndex)
+		sb.WriteString(targetWhere)
+		args = append(args, targetArgs...)
+		argIndex = newIndex
 	}
 	if i.onConflict.doNothing {
 		sb.WriteString(" DO NOTHING")
 	}
-	return sb.String()
+	if i.onConflict.doUpdate {
+		sb.WriteString(" DO UPDATE SET ")
+		setParts := make([]string, len(i.onConflict.sets))
+		for idx, set := range i.onConflict.sets {
+			column := quoteIdent(set.column)
+			if set.excluded {
+				setParts[idx] = fmt.Sprintf("%s = EXCLUDED.%s", column, column)
+				continue
+			}
+			setParts[idx] = fmt.Sprintf("%s = $%d", column, argIndex)
+			args = append(args, set.value)
+			argIndex++
+		}
+		sb.WriteString(strings.Join(setParts, ", "))
+		where, whereArgs, newIndex := buildConditionClauses(i.onConflict.where, " WHERE ", argIndex)
+		sb.WriteString(where)
+		args = append(args, whereArgs...)
+		argIndex = newIndex
+	}
+	return sb.String(), args, argIndex
 }
 
 // Build generates the SQL query and returns it with the arguments.
@@ -213,24 +375,41 @@ func (i *InsertBuilder) Build() (string, []any, error) {
 	if err := i.validateInsert(); err != nil {
 		return "", nil, err
 	}
+	sql, args, _ := i.build(1)
+	if len(args) > MaxQueryParameters {
+		return "", nil, fmt.Errorf("insert has %d parameters, more than the limit of %d; use BuildChunks or ExecChunked",
+			len(args), MaxQueryParameters)
+	}
+	return sql, args, nil
+}
 
-	valuesClause, args := i.buildValuesClause()
+// build generates the SQL with placeholders numbered from argIndex,
+// returning the arguments and the next arg index.
+// The builder must have been validated.
+func (i *InsertBuilder) build(argIndex int) (string, []any, int) {
+	withClause, args, argIndex := i.with.build(argIndex)
+	valuesClause, valuesArgs, argIndex := i.buildValuesClause(argIndex)
+	args = append(args, valuesArgs...)
 
 	var sb strings.Builder
+	sb.WriteString(withClause)
 	sb.WriteString("INSERT INTO ")
-	sb.WriteString(i.table)
+	sb.WriteString(quoteIdent(i.table))
 	sb.WriteString(" (")
-	sb.WriteString(strings.Join(i.columns, ", "))
+	sb.WriteString(quoteIdents(i.columns))
 	sb.WriteString(") VALUES ")
 	sb.WriteString(valuesClause)
-	sb.WriteString(i.buildOnConflictClause())
+
+	conflictClause, conflictArgs, argIndex := i.buildOnConflictClause(argIndex)
+	sb.WriteString(conflictClause)
+	args = append(args, conflictArgs...)
 
 	if len(i.returning) > 0 {
 		sb.WriteString(" RETURNING ")
-		sb.WriteString(strings.Join(i.returning, ", "))
+		sb.WriteString(joinColumnRefs(i.returning))
 	}
 
-	return sb.String(), args, nil
+	return sb.String(), args, argIndex
 }
 
 // MustBuild generates the SQL query and panics on error.
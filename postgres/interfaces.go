@@ -22,6 +22,22 @@ type Querier interface {
 	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
 }
 
+// CopyFromer is implemented by queriers that support the COPY protocol for bulk loading.
+// The Pool, Conn and Tx implementations of this package implement it.
+type CopyFromer interface {
+	// CopyFrom copies rows into the table using COPY FROM STDIN
+	// and returns the number of rows copied.
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+}
+
+// Batcher is implemented by queriers that can send several queries in one round trip.
+// The Pool, Conn and Tx implementations of this package implement it.
+type Batcher interface {
+	// SendBatch sends all queued queries of the batch at once.
+	// The returned results must be closed.
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+}
+
 // Pool represents a PostgreSQL connection pool.
 // It is the primary interface for database operations in the application.
 type Pool interface {
@@ -137,6 +153,30 @@ type TxManager interface {
 	WithTxOptions(ctx context.Context, opts pgx.TxOptions, fn func(tx Tx) error) error
 }
 
+// TxSettingsManager is implemented by transaction managers that support
+// per-transaction settings. The manager returned by NewTxManager implements it:
+//
+//	if m, ok := txManager.(postgres.TxSettingsManager); ok { ... }
+type TxSettingsManager interface {
+	TxManager
+
+	// WithTxSettings executes fn within a transaction with the specified settings,
+	// including server-side timeouts and a maximum wall-clock duration.
+	WithTxSettings(ctx context.Context, settings TxSettings, fn func(tx Tx) error) error
+}
+
+// Preparer is implemented by transactions that support two-phase commit.
+// Transactions started by this package implement it:
+//
+//	if p, ok := tx.(postgres.Preparer); ok { ... }
+type Preparer interface {
+	// Prepare prepares the transaction for two-phase commit under the given
+	// global identifier (PREPARE TRANSACTION). The transaction is then detached
+	// from the connection and must be finished with COMMIT PREPARED or
+	// ROLLBACK PREPARED. Only top-level transactions can be prepared.
+	Prepare(ctx context.Context, gid string) error
+}
+
 // Scanner is implemented by types that can scan database rows.
 // This is compatible with pgx.Row and pgx.Rows.
 type Scanner interface {
@@ -0,0 +1,652 @@
+// Package postgres provides PostgreSQL database utilities.
+package postgres
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Dorico-Dynamics/txova-go-core/logging"
+)
+
+const (
+	// DefaultJobQueueTable is the default jobs table.
+	DefaultJobQueueTable = "jobs"
+
+	// DefaultJobMaxAttempts is the default number of attempts before a job is dead-lettered.
+	DefaultJobMaxAttempts = 25
+
+	// DefaultJobPollInterval is the default delay between polls when the queue is empty.
+	DefaultJobPollInterval = time.Second
+
+	// DefaultJobTimeout is the default maximum duration of a job.
+	DefaultJobTimeout = 5 * time.Minute
+)
+
+// jobAttemptRecordTimeout bounds recording the attempt of a job whose transaction was lost.
+const jobAttemptRecordTimeout = 5 * time.Second
+
+// Job statuses stored in the jobs table. Completed jobs are deleted.
+const (
+	jobStatusPending = "pending"
+	jobStatusDead    = "dead"
+)
+
+// JobQueueConfig holds configuration for a JobQueue.
+type JobQueueConfig struct {
+	// Table is the jobs table, created by the JobQueueMigration SQL.
+	// Default: "jobs".
+	Table string
+
+	// Concurrency is the number of jobs Run processes in parallel.
+	// Default: 1.
+	Concurrency int
+
+	// PollInterval is the delay between polls when no job is ready.
+	// Default: 1s.
+	PollInterval time.Duration
+
+	// JobTimeout bounds the duration of a job, including its transaction.
+	// Default: 5m.
+	JobTimeout time.Duration
+
+	// MaxAttempts is the number of attempts for jobs enqueued without
+	// WithJobMaxAttempts, after which a failing job is dead-lettered.
+	// Default: 25.
+	MaxAttempts int
+
+	// Backoff returns the delay before retrying a job after its given failed attempt.
+	// Default: exponential from 10s up to 1h.
+	Backoff BackoffStrategy
+
+	// Logger for job events.
+	Logger *logging.Logger
+}
+
+// DefaultJobQueueConfig returns a JobQueueConfig with sensible defaults.
+func DefaultJobQueueConfig() JobQueueConfig {
+	return JobQueueConfig{
+		Table:        DefaultJobQueueTable,
+		Concurrency:  1,
+		PollInterval: DefaultJobPollInterval,
+		JobTimeout:   DefaultJobTimeout,
+		MaxAttempts:  DefaultJobMaxAttempts,
+		Backoff:      ExponentialBackoff(10*time.Second, time.Hour),
+		Logger:       logging.Default(),
+	}
+}
+
+// JobQueueOption is a functional option for configuring a JobQueue.
+type JobQueueOption func(*JobQueueConfig)
+
+// WithJobQueueTable sets the jobs table.
+func WithJobQueueTable(table string) JobQueueOption {
+	return func(c *JobQueueConfig) {
+		c.Table = table
+	}
+}
+
+// WithJobQueueConcurrency sets the number of jobs Run processes in parallel.
+func WithJobQueueConcurrency(n int) JobQueueOption {
+	return func(c *JobQueueConfig) {
+		c.Concurrency = n
+	}
+}
+
+// WithJobQueuePollInterval sets the delay between polls when no job is ready.
+func WithJobQueuePollInterval(d time.Duration) JobQueueOption {
+	return func(c *JobQueueConfig) {
+		c.PollInterval = d
+	}
+}
+
+// WithJobQueueTimeout sets the maximum duration of a job.
+func WithJobQueueTimeout(d time.Duration) JobQueueOption {
+	return func(c *JobQueueConfig) {
+		c.JobTimeout = d
+	}
+}
+
+// WithJobQueueMaxAttempts sets the default number of attempts before a job is dead-lettered.
+func WithJobQueueMaxAttempts(n int) JobQueueOption {
+	return func(c *JobQueueConfig) {
+		c.MaxAttempts = n
+	}
+}
+
+// WithJobQueueBackoff sets the delay before retrying a failed job.
+func WithJobQueueBackoff(backoff BackoffStrategy) JobQueueOption {
+	return func(c *JobQueueConfig) {
+		c.Backoff = backoff
+	}
+}
+
+// WithJobQueueLogger sets the logger for job events.
+func WithJobQueueLogger(logger *logging.Logger) JobQueueOption {
+	return func(c *JobQueueConfig) {
+		c.Logger = logger
+	}
+}
+
+// Job is a job claimed from the queue.
+type Job struct {
+	// ID is the job id.
+	ID int64
+
+	// Kind selects the handler registered with JobQueue.Register.
+	Kind string
+
+	// Payload is the JSON payload passed to Enqueue.
+	Payload json.RawMessage
+
+	// Attempt is the 1-based number of the current attempt.
+	Attempt int
+
+	// MaxAttempts is the number of attempts before the job is dead-lettered.
+	MaxAttempts int
+
+	// RunAt is the time the job became ready to run.
+	RunAt time.Time
+
+	// UniqueKey is the key passed with WithJobUniqueKey, if any.
+	UniqueKey string
+
+	// LastError is the error of the previous attempt, if any.
+	LastError string
+
+	// CreatedAt is the time the job was enqueued.
+	CreatedAt time.Time
+}
+
+// Decode unmarshals the job payload into v.
+func (j Job) Decode(v any) error {
+	if err := json.Unmarshal(j.Payload, v); err != nil {
+		return Wrap(CodeInvalidInput, fmt.Sprintf("failed to decode payload of job %d", j.ID), err)
+	}
+	return nil
+}
+
+// JobHandler processes a job. The context carries the job's transaction, so
+// Repository and QuerierFromContext calls commit atomically with completing the job.
+// Returning an error retries the job with backoff until it runs out of attempts.
+type JobHandler func(ctx context.Context, job Job) error
+
+// enqueueOptions holds the options of a single Enqueue call.
+type enqueueOptions struct {
+	uniqueKey   string
+	maxAttempts int
+}
+
+// EnqueueOption is a functional option for Enqueue.
+type EnqueueOption func(*enqueueOptions)
+
+// WithJobUniqueKey prevents enqueueing the job while another pending job has the same key.
+func WithJobUniqueKey(key string) EnqueueOption {
+	return func(o *enqueueOptions) {
+		o.uniqueKey = key
+	}
+}
+
+// WithJobMaxAttempts sets the number of attempts before the job is dead-lettered.
+func WithJobMaxAttempts(n int) EnqueueOption {
+	return func(o *enqueueOptions) {
+		o.maxAttempts = n
+	}
+}
+
+// cronJob is a job enqueued on a cron schedule.
+// key prefixes the unique keys of its activations.
+type cronJob struct {
+	spec     string
+	schedule CronSchedule
+	kind     string
+	payload  []byte
+	key      string
+}
+
+// cronJobKey returns the unique key prefix of a scheduled job. It includes a
+// digest of the spec and payload, so that schedules of the same kind whose
+// activations coincide are enqueued separately.
+func cronJobKey(spec, kind string, payload []byte) string {
+	h := sha256.New()
+	h.Write([]byte(spec))
+	h.Write([]byte{0})
+	h.Write(payload)
+	return "cron:" + kind + ":" + hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+// JobQueue is a durable job queue stored in a PostgreSQL table.
+// Jobs are enqueued in the caller's transaction, so they are only visible once it commits.
+// Workers claim ready jobs with FOR UPDATE SKIP LOCKED and run each job in the
+// transaction that claimed it: the job is deleted on success, retried with backoff
+// on failure, and dead-lettered (status "dead") after its last attempt.
+type JobQueue struct {
+	txManager TxManager
+	config    JobQueueConfig
+	table     string
+
+	mu        sync.RWMutex
+	handlers  map[string]JobHandler
+	schedules []cronJob
+}
+
+// NewJobQueue creates a JobQueue whose workers run jobs using txManager.
+func NewJobQueue(txManager TxManager, opts ...JobQueueOption) (*JobQueue, error) {
+	if txManager == nil {
+		return nil, New(CodeInvalidInput, "transaction manager is required")
+	}
+	config := DefaultJobQueueConfig()
+	for _, opt := range opts {
+		opt(&config)
+	}
+	if err := validateTableName(config.Table); err != nil {
+		return nil, Wrap(CodeInvalidInput, "invalid job queue table", err)
+	}
+	if config.Concurrency < 1 {
+		return nil, New(CodeInvalidInput, "job queue concurrency must be at least 1")
+	}
+	if config.PollInterval <= 0 || config.JobTimeout <= 0 {
+		return nil, New(CodeInvalidInput, "job queue poll interval and timeout must be positive")
+	}
+	if config.MaxAttempts < 1 {
+		return nil, New(CodeInvalidInput, "job max attempts must be at least 1")
+	}
+	if config.Backoff == nil {
+		return nil, New(CodeInvalidInput, "job backoff is required")
+	}
+	if config.Logger == nil {
+		config.Logger = logging.Default()
+	}
+	return &JobQueue{
+		txManager: txManager,
+		config:    config,
+		table:     quoteIdent(config.Table),
+		handlers:  make(map[string]JobHandler),
+	}, nil
+}
+
+// Register sets the handler for jobs of the kind. Workers only claim jobs of registered kinds.
+func (q *JobQueue) Register(kind string, handler JobHandler) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.handlers[kind] = handler
+}
+
+// Schedule enqueues a job of the kind with the payload at every activation of
+// the cron expression (see ParseCron), evaluated in UTC while Run is running.
+// Each activation is enqueued once even if several processes run the scheduler,
+// as long as the previous one is still pending or their clocks agree.
+func (q *JobQueue) Schedule(spec, kind string, payload any) error {
+	schedule, err := ParseCron(spec)
+	if err != nil {
+		return Wrap(CodeInvalidInput, "invalid job schedule", err)
+	}
+	data, err := marshalJSON(payload)
+	if err != nil {
+		return Wrap(CodeInvalidInput, "invalid job payload", err)
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.schedules = append(q.schedules, cronJob{
+		spec:     spec,
+		schedule: schedule,
+		kind:     kind,
+		payload:  data,
+		key:      cronJobKey(spec, kind, data),
+	})
+	return nil
+}
+
+// Enqueue adds a job of the kind to the queue, to run at runAt or immediately if runAt is zero.
+// The payload is encoded as JSON. The job is inserted with querier, or the transaction
+// in the context, so it commits or rolls back with the caller's other writes.
+// If a pending job has the same WithJobUniqueKey, no job is added and a
+// CodeDuplicate error is returned.
+func (q *JobQueue) Enqueue(ctx context.Context, querier Querier, kind string, payload any, runAt time.Time, opts ...EnqueueOption) (int64, error) {
+	querier = QuerierFromContext(ctx, querier)
+	if querier == nil {
+		return 0, New(CodeInvalidInput, "querier is required")
+	}
+	if kind == "" {
+		return 0, New(CodeInvalidInput, "job kind is required")
+	}
+	options := enqueueOptions{maxAttempts: q.config.MaxAttempts}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.maxAttempts < 1 {
+		return 0, New(CodeInvalidInput, "job max attempts must be at least 1")
+	}
+	data, err := marshalJSON(payload)
+	if err != nil {
+		return 0, Wrap(CodeInvalidInput, "invalid job payload", err)
+	}
+
+	var runAtArg, uniqueKeyArg any
+	if !runAt.IsZero() {
+		runAtArg = runAt
+	}
+	if options.uniqueKey != "" {
+		uniqueKeyArg = options.uniqueKey
+	}
+	var id int64
+	err = querier.QueryRow(ctx, "INSERT INTO "+q.table+
+		" (kind, payload, run_at, max_attempts, unique_key)"+
+		" VALUES ($1, $2::jsonb, COALESCE($3::timestamptz, now()), $4, $5)"+
+		" ON CONFLICT DO NOTHING RETURNING id",
+		kind, data, runAtArg, options.maxAttempts, uniqueKeyArg).Scan(&id)
+	if IsNotFound(err) {
+		return 0, New(CodeDuplicate, fmt.Sprintf("job with unique key %s is already pending", options.uniqueKey))
+	}
+	if err != nil {
+		return 0, asDBError(err)
+	}
+	return id, nil
+}
+
+// Run processes jobs with Concurrency workers and enqueues scheduled jobs until ctx is done.
+// On shutdown it stops claiming jobs and waits for running jobs to finish, then returns nil.
+// Running jobs are not canceled with ctx; they are bounded by JobTimeout.
+func (q *JobQueue) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	for range q.config.Concurrency {
+		wg.Go(func() { q.workLoop(ctx) })
+	}
+	q.mu.RLock()
+	hasSchedules := len(q.schedules) > 0
+	q.mu.RUnlock()
+	if hasSchedules {
+		wg.Go(func() { q.scheduleLoop(ctx) })
+	}
+	wg.Wait()
+	return nil
+}
+
+// workLoop claims and runs jobs until ctx is done, waiting PollInterval when none is ready.
+func (q *JobQueue) workLoop(ctx context.Context) {
+	for ctx.Err() == nil {
+		processed, err := q.Work(context.WithoutCancel(ctx))
+		if err != nil {
+			q.config.Logger.ErrorContext(ctx, "job queue poll failed", "table", q.config.Table, "error", err.Error())
+		}
+		if processed && err == nil {
+			continue
+		}
+		timer := time.NewTimer(q.config.PollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// Work claims one ready job and runs it in a transaction.
+// It reports whether a job was run; a failing job is retried or dead-lettered
+// and does not cause an error. If the job's transaction fails, for example
+// because the job exceeded JobTimeout, the attempt is recorded as a failure in
+// a new transaction. Errors are returned for database failures.
+func (q *JobQueue) Work(ctx context.Context) (bool, error) {
+	q.mu.RLock()
+	kinds := make([]string, 0, len(q.handlers))
+	for kind := range q.handlers {
+		kinds = append(kinds, kind)
+	}
+	q.mu.RUnlock()
+	if len(kinds) == 0 {
+		return false, nil
+	}
+	slices.Sort(kinds)
+
+	ctx, cancel := context.WithTimeout(ctx, q.config.JobTimeout)
+	defer cancel()
+	var job Job
+	processed := false
+	err := q.txManager.WithTx(ctx, func(tx Tx) error {
+		claimed, found, err := q.claim(ctx, tx, kinds)
+		if err != nil || !found {
+			return err
+		}
+		job, processed = claimed, true
+		return q.process(ctx, tx, job)
+	})
+	if processed && err != nil {
+		// The rollback also discarded the attempt; without recording it, a job
+		// that always times out would be retried forever.
+		if recordErr := q.recordLostAttempt(ctx, job, err); recordErr != nil {
+			return true, errors.Join(err, recordErr)
+		}
+		return true, nil
+	}
+	return processed, err
+}
+
+// recordLostAttempt records a failed attempt of a job whose transaction was rolled back.
+// It runs in a new transaction that is not bound by the job's context, and skips the job
+// if another worker claimed it in the meantime.
+func (q *JobQueue) recordLostAttempt(ctx context.Context, job Job, jobErr error) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), jobAttemptRecordTimeout)
+	defer cancel()
+	return q.txManager.WithTx(ctx, func(tx Tx) error {
+		var attempts int
+		err := tx.QueryRow(ctx, "SELECT attempts FROM "+q.table+
+			" WHERE id = $1 AND status = $2 FOR UPDATE SKIP LOCKED", job.ID, jobStatusPending).Scan(&attempts)
+		if IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return asDBError(err)
+		}
+		if attempts != job.Attempt-1 {
+			return nil
+		}
+		return q.recordFailure(ctx, tx, job, jobErr)
+	})
+}
+
+// claim locks the next ready job of the kinds, skipping jobs locked by other workers.
+func (q *JobQueue) claim(ctx context.Context, tx Tx, kinds []string) (Job, bool, error) {
+	sql, args, err := Select(q.config.Table).
+		Columns("id", "kind", "payload", "attempts", "max_attempts", "run_at").
+		ColumnsRaw("COALESCE(unique_key, '')", "COALESCE(last_error, '')").
+		Columns("created_at").
+		Where("status = ?", jobStatusPending).
+		Where("run_at <= now()").
+		WhereInArray("kind", kinds).
+		OrderByAsc("run_at").
+		OrderByAsc("id").
+		Limit(1).
+		ForUpdate().SkipLocked().
+		Build()
+	if err != nil {
+		return Job{}, false, Wrap(CodeInvalidInput, "invalid job claim query", err)
+	}
+	var job Job
+	var attempts int
+	err = tx.QueryRow(ctx, sql, args...).Scan(&job.ID, &job.Kind, &job.Payload, &attempts, &job.MaxAttempts,
+		&job.RunAt, &job.UniqueKey, &job.LastError, &job.CreatedAt)
+	if IsNotFound(err) {
+		return Job{}, false, nil
+	}
+	if err != nil {
+		return Job{}, false, asDBError(err)
+	}
+	job.Attempt = attempts + 1
+	return job, true, nil
+}
+
+// process runs the job's handler in a savepoint, then deletes the job on success or
+// records the failure, so a failed handler's writes are rolled back but the attempt is kept.
+func (q *JobQueue) process(ctx context.Context, tx Tx, job Job) error {
+	start := time.Now()
+	jobErr := q.runHandler(ctx, tx, job)
+	if jobErr == nil {
+		if _, err := tx.Exec(ctx, "DELETE FROM "+q.table+" WHERE id = $1", job.ID); err != nil {
+			return asDBError(err)
+		}
+		q.config.Logger.DebugContext(ctx, "job completed",
+			"job_id", job.ID, "kind", job.Kind, "attempt", job.Attempt,
+			"duration_ms", time.Since(start).Milliseconds())
+		return nil
+	}
+	return q.recordFailure(ctx, tx, job, jobErr)
+}
+
+// recordFailure schedules a retry of a failed job, or dead-letters it after its last attempt.
+func (q *JobQueue) recordFailure(ctx context.Context, tx Tx, job Job, jobErr error) error {
+	if job.Attempt >= job.MaxAttempts {
+		q.config.Logger.ErrorContext(ctx, "job dead-lettered",
+			"job_id", job.ID, "kind", job.Kind, "attempt", job.Attempt, "error", jobErr.Error())
+		_, err := tx.Exec(ctx, "UPDATE "+q.table+
+			" SET status = $2, attempts = $3, last_error = $4, updated_at = now() WHERE id = $1",
+			job.ID, jobStatusDead, job.Attempt, jobErr.Error())
+		if err != nil {
+			return asDBError(err)
+		}
+		return nil
+	}
+
+	retryAt := time.Now().Add(q.config.Backoff(job.Attempt))
+	q.config.Logger.WarnContext(ctx, "job failed, retrying",
+		"job_id", job.ID, "kind", job.Kind, "attempt", job.Attempt, "retry_at", retryAt, "error", jobErr.Error())
+	_, err := tx.Exec(ctx, "UPDATE "+q.table+
+		" SET attempts = $2, last_error = $3, run_at = $4, updated_at = now() WHERE id = $1",
+		job.ID, job.Attempt, jobErr.Error(), retryAt)
+	if err != nil {
+		return asDBError(err)
+	}
+	return nil
+}
+
+// runHandler runs the job's handler in a savepoint, recovering from panics.
+func (q *JobQueue) runHandler(ctx context.Context, tx Tx, job Job) (err error) {
+	q.mu.RLock()
+	handler := q.handlers[job.Kind]
+	q.mu.RUnlock()
+	if handler == nil {
+		return fmt.Errorf("no handler registered for job kind %s", job.Kind)
+	}
+
+	savepoint, err := tx.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+		if err != nil {
+			if rbErr := savepoint.Rollback(ctx); rbErr != nil {
+				err = errors.Join(err, rbErr)
+			}
+			return
+		}
+		err = savepoint.Commit(ctx)
+	}()
+	return handler(ContextWithTx(ctx, savepoint), job)
+}
+
+// scheduleLoop enqueues scheduled jobs at their activation times until ctx is done.
+func (q *JobQueue) scheduleLoop(ctx context.Context) {
+	q.mu.RLock()
+	schedules := slices.Clone(q.schedules)
+	q.mu.RUnlock()
+
+	next := make([]time.Time, len(schedules))
+	now := time.Now().UTC()
+	for i, s := range schedules {
+		next[i] = s.schedule.Next(now)
+	}
+	for {
+		i := slices.IndexFunc(next, func(t time.Time) bool { return !t.IsZero() })
+		if i < 0 {
+			return
+		}
+		for j, t := range next {
+			if !t.IsZero() && t.Before(next[i]) {
+				i = j
+			}
+		}
+
+		timer := time.NewTimer(time.Until(next[i]))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if err := q.enqueueScheduled(ctx, schedules[i], next[i]); err != nil {
+			q.config.Logger.ErrorContext(ctx, "failed to enqueue scheduled job",
+				"kind", schedules[i].kind, "schedule", schedules[i].spec, "error", err.Error())
+		}
+		next[i] = schedules[i].schedule.Next(next[i])
+	}
+}
+
+// enqueueScheduled enqueues the activation of a scheduled job at the time,
+// keyed by schedule and time so concurrent schedulers enqueue it once.
+func (q *JobQueue) enqueueScheduled(ctx context.Context, s cronJob, at time.Time) error {
+	key := fmt.Sprintf("%s:%d", s.key, at.Unix())
+	return q.txManager.WithTx(ctx, func(tx Tx) error {
+		_, err := q.Enqueue(ctx, tx, s.kind, json.RawMessage(s.payload), at, WithJobUniqueKey(key))
+		if IsDuplicate(err) {
+			return nil
+		}
+		return err
+	})
+}
+
+// RetryDead moves a dead-lettered job back to the queue to run immediately with
+// a fresh set of attempts. Returns a CodeNotFound error if there is no such dead job.
+func (q *JobQueue) RetryDead(ctx context.Context, querier Querier, id int64) error {
+	querier = QuerierFromContext(ctx, querier)
+	if querier == nil {
+		return New(CodeInvalidInput, "querier is required")
+	}
+	tag, err := querier.Exec(ctx, "UPDATE "+q.table+
+		" SET status = $2, attempts = 0, run_at = now(), updated_at = now() WHERE id = $1 AND status = $3",
+		id, jobStatusPending, jobStatusDead)
+	if err != nil {
+		return asDBError(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return New(CodeNotFound, fmt.Sprintf("dead job %d not found", id))
+	}
+	return nil
+}
+
+// JobQueueMigration returns migration SQL creating the jobs table for a JobQueue
+// and its indexes: one for claiming ready jobs and a partial unique index on the
+// unique key of pending jobs. Dead-lettered jobs stay in the table with status "dead".
+func JobQueueMigration(table string) (up, down string, err error) {
+	if err := validateTableName(table); err != nil {
+		return "", "", Wrap(CodeInvalidInput, "invalid job queue table", err)
+	}
+	prefix := identifierPrefix(table)
+	up = fmt.Sprintf(`CREATE TABLE %[1]s (
+    id           BIGSERIAL PRIMARY KEY,
+    kind         TEXT NOT NULL,
+    payload      JSONB NOT NULL DEFAULT '{}',
+    status       TEXT NOT NULL DEFAULT '%[3]s' CHECK (status IN ('%[3]s', '%[4]s')),
+    attempts     INTEGER NOT NULL DEFAULT 0,
+    max_attempts INTEGER NOT NULL DEFAULT %[5]d CHECK (max_attempts > 0),
+    run_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    unique_key   TEXT,
+    last_error   TEXT,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX %[6]s ON %[1]s (run_at, id) WHERE status = '%[3]s';
+CREATE UNIQUE INDEX %[7]s ON %[1]s (unique_key) WHERE status = '%[3]s';
+`, quoteIdent(table), prefix, jobStatusPending, jobStatusDead, DefaultJobMaxAttempts,
+		formatIdentifier([]string{prefix + "_ready_idx"}), formatIdentifier([]string{prefix + "_unique_key_idx"}))
+	down = fmt.Sprintf("DROP TABLE IF EXISTS %s;\n", quoteIdent(table))
+	return up, down, nil
+}
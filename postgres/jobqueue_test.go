@@ -0,0 +1,414 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+const jobClaimSQL = "SELECT id, kind, payload, attempts, max_attempts, run_at, COALESCE(unique_key, ''), COALESCE(last_error, ''), created_at " +
+	"FROM jobs WHERE status = $1 AND run_at <= now() AND kind = ANY($2) ORDER BY run_at ASC, id ASC LIMIT 1 FOR UPDATE SKIP LOCKED"
+
+func jobRows(id int64, kind string, attempts, maxAttempts int) *pgxmock.Rows {
+	now := time.Now()
+	return pgxmock.NewRows([]string{"id", "kind", "payload", "attempts", "max_attempts", "run_at", "unique_key", "last_error", "created_at"}).
+		AddRow(id, kind, []byte(`{"to":"a@example.com"}`), attempts, maxAttempts, now, "", "", now)
+}
+
+func newTestJobQueue(t *testing.T, opts ...JobQueueOption) (*JobQueue, pgxmock.PgxPoolIface) {
+	t.Helper()
+	pool, mock := newMockPool(t)
+	q, err := NewJobQueue(NewTxManager(pool), opts...)
+	if err != nil {
+		t.Fatalf("NewJobQueue() error = %v", err)
+	}
+	return q, mock
+}
+
+func TestNewJobQueue_Invalid(t *testing.T) {
+	t.Parallel()
+
+	pool, mock := newMockPool(t)
+	defer mock.Close()
+	tm := NewTxManager(pool)
+	tests := []struct {
+		name string
+		tm   TxManager
+		opts []JobQueueOption
+	}{
+		{name: "nil tx manager"},
+		{name: "invalid table", tm: tm, opts: []JobQueueOption{WithJobQueueTable("jobs; DROP TABLE x")}},
+		{name: "zero concurrency", tm: tm, opts: []JobQueueOption{WithJobQueueConcurrency(0)}},
+		{name: "zero poll interval", tm: tm, opts: []JobQueueOption{WithJobQueuePollInterval(0)}},
+		{name: "zero max attempts", tm: tm, opts: []JobQueueOption{WithJobQueueMaxAttempts(0)}},
+		{name: "nil backoff", tm: tm, opts: []JobQueueOption{WithJobQueueBackoff(nil)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if _, err := NewJobQueue(tt.tm, tt.opts...); !IsCode(err, CodeInvalidInput) {
+				t.Errorf("NewJobQueue() error = %v, want invalid input", err)
+			}
+		})
+	}
+}
+
+func TestJobQueue_Enqueue(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	insertSQL := regexp.QuoteMeta("INSERT INTO jobs (kind, payload, run_at, max_attempts, unique_key) " +
+		"VALUES ($1, $2::jsonb, COALESCE($3::timestamptz, now()), $4, $5) ON CONFLICT DO NOTHING RETURNING id")
+
+	t.Run("in transaction", func(t *testing.T) {
+		t.Parallel()
+		q, mock := newTestJobQueue(t, WithJobQueueMaxAttempts(5))
+		defer mock.Close()
+		runAt := time.Date(2026, time.March, 14, 10, 0, 0, 0, time.UTC)
+		mock.ExpectBegin()
+		mock.ExpectQuery(insertSQL).
+			WithArgs("email", []byte(`{"to":"a@example.com"}`), runAt, 5, nil).
+			WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int64(7)))
+		mock.ExpectCommit()
+
+		var id int64
+		err := q.txManager.WithTx(ctx, func(tx Tx) error {
+			var err error
+			id, err = q.Enqueue(ctx, tx, "email", map[string]string{"to": "a@example.com"}, runAt)
+			return err
+		})
+		if err != nil || id != 7 {
+			t.Fatalf("Enqueue() = %d, %v, want 7", id, err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("duplicate unique key", func(t *testing.T) {
+		t.Parallel()
+		q, mock := newTestJobQueue(t)
+		defer mock.Close()
+		mock.ExpectQuery(insertSQL).
+			WithArgs("report", []byte(`null`), nil, DefaultJobMaxAttempts, "report:2026-03").
+			WillReturnRows(pgxmock.NewRows([]string{"id"}))
+
+		pool := q.txManager.(*txManager).pool
+		_, err := q.Enqueue(ctx, pool, "report", nil, time.Time{}, WithJobUniqueKey("report:2026-03"))
+		if !IsDuplicate(err) {
+			t.Errorf("Enqueue() error = %v, want duplicate", err)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+		q, mock := newTestJobQueue(t)
+		defer mock.Close()
+		pool := q.txManager.(*txManager).pool
+		if _, err := q.Enqueue(ctx, nil, "email", nil, time.Time{}); !IsCode(err, CodeInvalidInput) {
+			t.Errorf("Enqueue() without querier error = %v, want invalid input", err)
+		}
+		if _, err := q.Enqueue(ctx, pool, "", nil, time.Time{}); !IsCode(err, CodeInvalidInput) {
+			t.Errorf("Enqueue() without kind error = %v, want invalid input", err)
+		}
+		if _, err := q.Enqueue(ctx, pool, "email", nil, time.Time{}, WithJobMaxAttempts(0)); !IsCode(err, CodeInvalidInput) {
+			t.Errorf("Enqueue() with zero attempts error = %v, want invalid input", err)
+		}
+		if _, err := q.Enqueue(ctx, pool, "email", make(chan int), time.Time{}); !IsCode(err, CodeInvalidInput) {
+			t.Errorf("Enqueue() with unencodable payload error = %v, want invalid input", err)
+		}
+	})
+}
+
+func TestJobQueue_Work(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	t.Run("success deletes job", func(t *testing.T) {
+		t.Parallel()
+		q, mock := newTestJobQueue(t)
+		defer mock.Close()
+		var got struct{ To string }
+		q.Register("email", func(ctx context.Context, job Job) error {
+			if job.Attempt != 1 {
+				t.Errorf("Attempt = %d, want 1", job.Attempt)
+			}
+			if err := job.Decode(&got); err != nil {
+				return err
+			}
+			_, err := QuerierFromContext(ctx, nil).Exec(ctx, "UPDATE users SET notified = true")
+			return err
+		})
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(jobClaimSQL)).
+			WithArgs(jobStatusPending, []string{"email"}).
+			WillReturnRows(jobRows(1, "email", 0, 3))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE users").WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mock.ExpectCommit()
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM jobs WHERE id = $1")).WithArgs(int64(1)).
+			WillReturnResult(pgxmock.NewResult("DELETE", 1))
+		mock.ExpectCommit()
+
+		processed, err := q.Work(ctx)
+		if !processed || err != nil {
+			t.Fatalf("Work() = %v, %v, want true, nil", processed, err)
+		}
+		if got.To != "a@example.com" {
+			t.Errorf("payload To = %q", got.To)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("failure schedules retry", func(t *testing.T) {
+		t.Parallel()
+		q, mock := newTestJobQueue(t, WithJobQueueBackoff(func(int) time.Duration { return time.Hour }))
+		defer mock.Close()
+		q.Register("email", func(context.Context, Job) error { return errors.New("smtp unavailable") })
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(jobClaimSQL)).WithArgs(jobStatusPending, []string{"email"}).
+			WillReturnRows(jobRows(2, "email", 1, 3))
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE jobs SET attempts = $2, last_error = $3, run_at = $4")).
+			WithArgs(int64(2), 2, "smtp unavailable", pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mock.ExpectCommit()
+
+		if processed, err := q.Work(ctx); !processed || err != nil {
+			t.Fatalf("Work() = %v, %v, want true, nil", processed, err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("last attempt dead-letters", func(t *testing.T) {
+		t.Parallel()
+		q, mock := newTestJobQueue(t)
+		defer mock.Close()
+		q.Register("email", func(context.Context, Job) error { panic("bad payload") })
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(jobClaimSQL)).WithArgs(jobStatusPending, []string{"email"}).
+			WillReturnRows(jobRows(3, "email", 2, 3))
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE jobs SET status = $2, attempts = $3, last_error = $4")).
+			WithArgs(int64(3), jobStatusDead, 3, "job panicked: bad payload").
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mock.ExpectCommit()
+
+		if processed, err := q.Work(ctx); !processed || err != nil {
+			t.Fatalf("Work() = %v, %v, want true, nil", processed, err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("timeout records attempt", func(t *testing.T) {
+		t.Parallel()
+		q, mock := newTestJobQueue(t,
+			WithJobQueueTimeout(20*time.Millisecond),
+			WithJobQueueBackoff(func(int) time.Duration { return time.Hour }))
+		defer mock.Close()
+		q.Register("email", func(ctx context.Context, _ Job) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(jobClaimSQL)).WithArgs(jobStatusPending, []string{"email"}).
+			WillReturnRows(jobRows(4, "email", 1, 3))
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE jobs SET attempts = $2")).
+			WithArgs(int64(4), 2, pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WillReturnError(context.DeadlineExceeded)
+		mock.ExpectRollback()
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT attempts FROM jobs WHERE id = $1 AND status = $2 FOR UPDATE SKIP LOCKED")).
+			WithArgs(int64(4), jobStatusPending).
+			WillReturnRows(pgxmock.NewRows([]string{"attempts"}).AddRow(1))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE jobs SET attempts = $2, last_error = $3, run_at = $4")).
+			WithArgs(int64(4), 2, pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mock.ExpectCommit()
+
+		if processed, err := q.Work(ctx); !processed || err != nil {
+			t.Fatalf("Work() = %v, %v, want true, nil", processed, err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("empty queue", func(t *testing.T) {
+		t.Parallel()
+		q, mock := newTestJobQueue(t)
+		defer mock.Close()
+		q.Register("email", func(context.Context, Job) error { return nil })
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(jobClaimSQL)).WithArgs(jobStatusPending, []string{"email"}).
+			WillReturnRows(pgxmock.NewRows([]string{"id"}))
+		mock.ExpectCommit()
+
+		if processed, err := q.Work(ctx); processed || err != nil {
+			t.Errorf("Work() = %v, %v, want false, nil", processed, err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("no handlers", func(t *testing.T) {
+		t.Parallel()
+		q, mock := newTestJobQueue(t)
+		defer mock.Close()
+		if processed, err := q.Work(ctx); processed || err != nil {
+			t.Errorf("Work() = %v, %v, want false, nil", processed, err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unexpected queries: %v", err)
+		}
+	})
+}
+
+func TestJobQueue_Schedule(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	q, mock := newTestJobQueue(t)
+	defer mock.Close()
+	if err := q.Schedule("not a schedule", "report", nil); !IsCode(err, CodeInvalidInput) {
+		t.Errorf("Schedule() error = %v, want invalid input", err)
+	}
+	if err := q.Schedule("0 * * * *", "report", map[string]int{"hours": 1}); err != nil {
+		t.Fatalf("Schedule() error = %v", err)
+	}
+
+	at := time.Date(2026, time.March, 14, 11, 0, 0, 0, time.UTC)
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO jobs").
+		WithArgs("report", []byte(`{"hours":1}`), at, DefaultJobMaxAttempts, "cron:report:e1cd6679ef63d2a4:1773486000").
+		WillReturnRows(pgxmock.NewRows([]string{"id"}))
+	mock.ExpectCommit()
+
+	if err := q.enqueueScheduled(ctx, q.schedules[0], at); err != nil {
+		t.Errorf("enqueueScheduled() of an already enqueued activation error = %v, want nil", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestJobQueue_ScheduleKeys(t *testing.T) {
+	t.Parallel()
+
+	q, mock := newTestJobQueue(t)
+	defer mock.Close()
+	for _, s := range []struct {
+		spec    string
+		payload any
+	}{
+		{"0 * * * *", map[string]int{"hours": 1}},
+		{"@hourly", map[string]int{"hours": 1}},
+		{"0 * * * *", map[string]int{"hours": 2}},
+	} {
+		if err := q.Schedule(s.spec, "report", s.payload); err != nil {
+			t.Fatalf("Schedule(%q) error = %v", s.spec, err)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, s := range q.schedules {
+		if seen[s.key] {
+			t.Errorf("schedule %s %s shares key %s with another schedule", s.spec, s.payload, s.key)
+		}
+		seen[s.key] = true
+	}
+}
+
+func TestJobQueue_RunStopsOnCancel(t *testing.T) {
+	t.Parallel()
+
+	q, mock := newTestJobQueue(t, WithJobQueueConcurrency(3))
+	defer mock.Close()
+	if err := q.Schedule("@daily", "report", nil); err != nil {
+		t.Fatalf("Schedule() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- q.Run(ctx) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Run() error = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Run() did not return after cancel")
+	}
+}
+
+func TestJobQueue_RetryDead(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	q, mock := newTestJobQueue(t)
+	defer mock.Close()
+	pool := q.txManager.(*txManager).pool
+	retrySQL := regexp.QuoteMeta("UPDATE jobs SET status = $2, attempts = 0, run_at = now(), updated_at = now() WHERE id = $1 AND status = $3")
+	mock.ExpectExec(retrySQL).WithArgs(int64(4), jobStatusPending, jobStatusDead).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectExec(retrySQL).WithArgs(int64(5), jobStatusPending, jobStatusDead).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+	if err := q.RetryDead(ctx, pool, 4); err != nil {
+		t.Errorf("RetryDead() error = %v", err)
+	}
+	if err := q.RetryDead(ctx, pool, 5); !IsNotFound(err) {
+		t.Errorf("RetryDead() of missing job error = %v, want not found", err)
+	}
+}
+
+func TestJobQueueMigration(t *testing.T) {
+	t.Parallel()
+
+	up, down, err := JobQueueMigration("queue.jobs")
+	if err != nil {
+		t.Fatalf("JobQueueMigration() error = %v", err)
+	}
+	for _, want := range []string{
+		"CREATE TABLE queue.jobs (",
+		"payload      JSONB NOT NULL DEFAULT '{}'",
+		"CREATE INDEX queue_jobs_ready_idx ON queue.jobs (run_at, id) WHERE status = 'pending';",
+		"CREATE UNIQUE INDEX queue_jobs_unique_key_idx ON queue.jobs (unique_key) WHERE status = 'pending';",
+	} {
+		if !strings.Contains(up, want) {
+			t.Errorf("up migration missing %q:\n%s", want, up)
+		}
+	}
+	if down != "DROP TABLE IF EXISTS queue.jobs;\n" {
+		t.Errorf("down = %q", down)
+	}
+	if _, _, err := JobQueueMigration("jobs;"); !IsCode(err, CodeInvalidInput) {
+		t.Errorf("JobQueueMigration() error = %v, want invalid input", err)
+	}
+}
+
+func TestJob_Decode(t *testing.T) {
+	t.Parallel()
+
+	var v map[string]any
+	if err := (Job{ID: 1, Payload: json.RawMessage(`{`)}).Decode(&v); !IsCode(err, CodeInvalidInput) {
+		t.Errorf("Decode() error = %v, want invalid input", err)
+	}
+}
@@ -0,0 +1,136 @@
+// Package postgres provides PostgreSQL database utilities.
+package postgres
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// JSONPath returns an expression extracting the text at path from a JSONB column,
+// e.g. JSONPath("data", "address", "city") returns data #>> '{"address","city"}'.
+// Path elements are quoted, so they may come from user input, but column must not.
+// Use it in ColumnsRaw, OrderByRaw or GroupByRaw; use WhereJSONPathEquals to filter.
+func JSONPath(column string, path ...string) Raw {
+	elements := make([]string, len(path))
+	for i, p := range path {
+		p = strings.ReplaceAll(p, `\`, `\\`)
+		p = strings.ReplaceAll(p, `"`, `\"`)
+		elements[i] = `"` + strings.ReplaceAll(p, "'", "''") + `"`
+	}
+	return Raw(fmt.Sprintf("%s #>> '{%s}'", quoteIdent(column), strings.Join(elements, ",")))
+}
+
+// marshalJSON encodes a value as a JSONB parameter.
+// The result is []byte so pgx sends it unchanged rather than encoding it again.
+func marshalJSON(value any) ([]byte, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode jsonb value: %w", err)
+	}
+	return data, nil
+}
+
+// newJSONContainsClause creates a column @> ?::jsonb where clause.
+func newJSONContainsClause(column string, value any) whereClause {
+	data, err := marshalJSON(value)
+	w := newColumnClause(column, "@> ?::jsonb", data)
+	if err != nil {
+		w.err = err
+	}
+	return w
+}
+
+// newJSONPathClause creates a column #>> ? = ? where clause.
+func newJSONPathClause(column string, path []string, value string) whereClause {
+	return newColumnClause(column, "#>> ? = ?", path, value)
+}
+
+// WhereJSONContains adds a WHERE column @> value condition.
+// The value is encoded as JSON, e.g. map[string]any{"status": "vip"}.
+func (s *SelectBuilder) WhereJSONContains(column string, value any) *SelectBuilder {
+	s.where = append(s.where, newJSONContainsClause(column, value))
+	return s
+}
+
+// WhereJSONHasKey adds a WHERE column ? key condition: the top-level key exists.
+func (s *SelectBuilder) WhereJSONHasKey(column, key string) *SelectBuilder {
+	s.where = append(s.where, newColumnClause(column, "?? ?", key))
+	return s
+}
+
+// WhereJSONHasAnyKey adds a WHERE column ?| keys condition: any of the top-level keys exist.
+func (s *SelectBuilder) WhereJSONHasAnyKey(column string, keys ...string) *SelectBuilder {
+	s.where = append(s.where, newColumnClause(column, "??| ?", keys))
+	return s
+}
+
+// WhereJSONHasAllKeys adds a WHERE column ?& keys condition: all of the top-level keys exist.
+func (s *SelectBuilder) WhereJSONHasAllKeys(column string, keys ...string) *SelectBuilder {
+	s.where = append(s.where, newColumnClause(column, "??& ?", keys))
+	return s
+}
+
+// WhereJSONPathEquals adds a WHERE column #>> path = value condition comparing
+// the text at path with value. The path is bound as a text[] parameter.
+func (s *SelectBuilder) WhereJSONPathEquals(column string, path []string, value string) *SelectBuilder {
+	s.where = append(s.where, newJSONPathClause(column, path, value))
+	return s
+}
+
+// WhereJSONContains adds a WHERE column @> value condition.
+func (u *UpdateBuilder) WhereJSONContains(column string, value any) *UpdateBuilder {
+	u.where = append(u.where, newJSONContainsClause(column, value))
+	return u
+}
+
+// WhereJSONHasKey adds a WHERE column ? key condition.
+func (u *UpdateBuilder) WhereJSONHasKey(column, key string) *UpdateBuilder {
+	u.where = append(u.where, newColumnClause(column, "?? ?", key))
+	return u
+}
+
+// WhereJSONPathEquals adds a WHERE column #>> path = value condition.
+func (u *UpdateBuilder) WhereJSONPathEquals(column string, path []string, value string) *UpdateBuilder {
+	u.where = append(u.where, newJSONPathClause(column, path, value))
+	return u
+}
+
+// SetJSONPath sets the value at path within a JSONB column using jsonb_set,
+// creating the key if missing: column = jsonb_set(column, path, value).
+// The value is encoded as JSON.
+func (u *UpdateBuilder) SetJSONPath(column string, path []string, value any) *UpdateBuilder {
+	data, err := marshalJSON(value)
+	u.sets = append(u.sets, setClause{
+		column: column,
+		expr:   fmt.Sprintf("jsonb_set(COALESCE(%s, '{}'::jsonb), ?, ?::jsonb)", quoteIdent(column)),
+		args:   []any{path, data},
+		err:    err,
+	})
+	return u
+}
+
+// SetJSON sets a JSONB column to value encoded as JSON.
+func (u *UpdateBuilder) SetJSON(column string, value any) *UpdateBuilder {
+	data, err := marshalJSON(value)
+	u.sets = append(u.sets, setClause{column: column, expr: "?::jsonb", args: []any{data}, err: err})
+	return u
+}
+
+// WhereJSONContains adds a WHERE column @> value condition.
+func (d *DeleteBuilder) WhereJSONContains(column string, value any) *DeleteBuilder {
+	d.where = append(d.where, newJSONContainsClause(column, value))
+	return d
+}
+
+// WhereJSONHasKey adds a WHERE column ? key condition.
+func (d *DeleteBuilder) WhereJSONHasKey(column, key string) *DeleteBuilder {
+	d.where = append(d.where, newColumnClause(column, "?? ?", key))
+	return d
+}
+
+// WhereJSONPathEquals adds a WHERE column #>> path = value condition.
+func (d *DeleteBuilder) WhereJSONPathEquals(column string, path []string, value string) *DeleteBuilder {
+	d.where = append(d.where, newJSONPathClause(column, path, value))
+	return d
+}
@@ -0,0 +1,414 @@
+// Package postgres provides PostgreSQL database utilities.
+package postgres
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Dorico-Dynamics/txova-go-types/pagination"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// MinCursorSecretLength is the minimum length of a cursor signing secret.
+const MinCursorSecretLength = 32
+
+// Cursor holds the values of the ORDER BY columns of the row bounding a keyset page,
+// in ORDER BY order.
+type Cursor []any
+
+// keysetPage represents keyset pagination applied to a SELECT.
+type keysetPage struct {
+	cursor   Cursor
+	limit    int
+	backward bool
+}
+
+// PageAfter applies keyset pagination returning up to limit rows that follow
+// the cursor in ORDER BY order. A nil cursor returns the first page.
+// The ORDER BY columns form the key and must all share a direction and
+// uniquely identify rows, e.g. OrderByDesc("created_at").OrderByDesc("id").
+// One extra row is fetched so KeysetResult can detect further pages.
+func (s *SelectBuilder) PageAfter(cursor Cursor, limit int) *SelectBuilder {
+	s.keyset = &keysetPage{cursor: cursor, limit: limit}
+	return s
+}
+
+// PageBefore applies keyset pagination returning up to limit rows that precede
+// the cursor in ORDER BY order. A nil cursor returns the last page.
+// Rows are fetched in reverse order; NewKeysetResult restores ORDER BY order.
+func (s *SelectBuilder) PageBefore(cursor Cursor, limit int) *SelectBuilder {
+	s.keyset = &keysetPage{cursor: cursor, limit: limit, backward: true}
+	return s
+}
+
+// validateKeyset checks that keyset pagination has a usable key.
+func (s *SelectBuilder) validateKeyset() error {
+	k := s.keyset
+	if k == nil {
+		return nil
+	}
+	if k.limit <= 0 {
+		return fmt.Errorf("keyset pagination limit must be positive")
+	}
+	if s.limit != nil || s.offset != nil {
+		return fmt.Errorf("keyset pagination cannot be combined with LIMIT or OFFSET")
+	}
+	if len(s.orderBy) == 0 {
+		return fmt.Errorf("keyset pagination requires order by columns")
+	}
+	direction := normalizeSortDirection(s.orderBy[0].direction)
+	for _, o := range s.orderBy[1:] {
+		if normalizeSortDirection(o.direction) != direction {
+			return fmt.Errorf("keyset pagination requires all order by columns to share a direction")
+		}
+	}
+	if len(k.cursor) > 0 && len(k.cursor) != len(s.orderBy) {
+		return fmt.Errorf("cursor has %d values but %d order by columns", len(k.cursor), len(s.orderBy))
+	}
+	return nil
+}
+
+// normalizeSortDirection returns the direction, defaulting to ascending.
+func normalizeSortDirection(direction pagination.SortDirection) pagination.SortDirection {
+	if strings.EqualFold(string(direction), string(pagination.SortDesc)) {
+		return pagination.SortDesc
+	}
+	return pagination.SortAsc
+}
+
+// keysetWhere returns the WHERE clauses with the keyset predicate appended.
+// Existing clauses are grouped so that an OR, including one inside a raw
+// condition, cannot weaken the predicate.
+func (s *SelectBuilder) keysetWhere() []whereClause {
+	if s.keyset == nil || len(s.keyset.cursor) == 0 {
+		return s.where
+	}
+	var where []whereClause
+	if len(s.where) > 0 {
+		where = []whereClause{{hasCond: true, cond: &ConditionGroup{clauses: s.where}}}
+	}
+
+	// Rows after the cursor are greater in ascending order and smaller in
+	// descending order; PageBefore inverts the comparison.
+	operator := ">"
+	if (normalizeSortDirection(s.orderBy[0].direction) == pagination.SortDesc) != s.keyset.backward {
+		operator = "<"
+	}
+	columns := make([]string, len(s.orderBy))
+	placeholders := make([]string, len(s.orderBy))
+	for i, o := range s.orderBy {
+		columns[i] = o.column
+		if !o.raw {
+			columns[i] = quoteIdent(o.column)
+		}
+		placeholders[i] = "?"
+	}
+	var condition string
+	if len(columns) == 1 {
+		condition = fmt.Sprintf("%s %s ?", columns[0], operator)
+	} else {
+		condition = fmt.Sprintf("(%s) %s (%s)", strings.Join(columns, ", "), operator, strings.Join(placeholders, ", "))
+	}
+	return append(slices.Clip(where), whereClause{condition: condition, args: s.keyset.cursor})
+}
+
+// keysetOrderBy returns the ORDER BY clauses, reversed for PageBefore.
+func (s *SelectBuilder) keysetOrderBy() []orderByClause {
+	if s.keyset == nil || !s.keyset.backward {
+		return s.orderBy
+	}
+	reversed := make([]orderByClause, len(s.orderBy))
+	for i, o := range s.orderBy {
+		reversed[i] = orderByClause{column: o.column, direction: pagination.SortDesc, raw: o.raw}
+		if normalizeSortDirection(o.direction) == pagination.SortDesc {
+			reversed[i].direction = pagination.SortAsc
+		}
+	}
+	return reversed
+}
+
+// KeysetResult is a page of rows fetched with PageAfter or PageBefore.
+type KeysetResult[T any] struct {
+	// Items are the rows of the page in ORDER BY order.
+	Items []T
+
+	// NextCursor is the encoded cursor for PageAfter, empty if there is no next page.
+	NextCursor string
+
+	// PrevCursor is the encoded cursor for PageBefore, empty if there is no previous page.
+	PrevCursor string
+}
+
+// HasNext reports whether there is a next page.
+func (r *KeysetResult[T]) HasNext() bool {
+	return r.NextCursor != ""
+}
+
+// HasPrev reports whether there is a previous page.
+func (r *KeysetResult[T]) HasPrev() bool {
+	return r.PrevCursor != ""
+}
+
+// NewKeysetResult builds a page from rows scanned from a query built with
+// PageAfter or PageBefore. The key function returns the ORDER BY column values
+// of a row, which are encoded with the codec into the next and previous cursors.
+func NewKeysetResult[T any](codec *CursorCodec, query *SelectBuilder, rows []T, key func(T) Cursor) (*KeysetResult[T], error) {
+	if query == nil || query.keyset == nil {
+		return nil, New(CodeInvalidInput, "query does not use keyset pagination")
+	}
+	if codec == nil || key == nil {
+		return nil, New(CodeInvalidInput, "cursor codec and key function are required")
+	}
+	k := query.keyset
+
+	hasMore := len(rows) > k.limit
+	items := rows
+	if hasMore {
+		items = rows[:k.limit]
+	}
+	items = slices.Clone(items)
+	if k.backward {
+		slices.Reverse(items)
+	}
+
+	result := &KeysetResult[T]{Items: items}
+	if len(items) == 0 {
+		return result, nil
+	}
+
+	// Moving away from a cursor means there are rows on its side.
+	hasNext, hasPrev := hasMore, len(k.cursor) > 0
+	if k.backward {
+		hasNext, hasPrev = len(k.cursor) > 0, hasMore
+	}
+
+	var err error
+	if hasNext {
+		if result.NextCursor, err = codec.Encode(query, key(items[len(items)-1])); err != nil {
+			return nil, err
+		}
+	}
+	if hasPrev {
+		if result.PrevCursor, err = codec.Encode(query, key(items[0])); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// CursorCodec encodes cursors into opaque, tamper-evident strings signed with HMAC-SHA256.
+// Cursors are bound to the ORDER BY key of the query they were issued for, so a
+// cursor from one query is rejected by a query with other columns or directions.
+type CursorCodec struct {
+	secret []byte
+}
+
+// NewCursorCodec creates a CursorCodec signing cursors with the secret.
+// The secret must be at least MinCursorSecretLength bytes.
+func NewCursorCodec(secret []byte) (*CursorCodec, error) {
+	if len(secret) < MinCursorSecretLength {
+		return nil, New(CodeInvalidInput, fmt.Sprintf("cursor secret must be at least %d bytes", MinCursorSecretLength))
+	}
+	return &CursorCodec{secret: slices.Clone(secret)}, nil
+}
+
+// cursorValue is the serialized form of a cursor value, preserving its type.
+type cursorValue struct {
+	Type  string `json:"t"`
+	Value string `json:"v"`
+}
+
+// cursorPayload is the signed content of a cursor: the digest of the ORDER BY
+// key of its query and its values.
+type cursorPayload struct {
+	Key    string        `json:"k"`
+	Values []cursorValue `json:"v"`
+}
+
+// keysetKeyDigest returns a digest of the ORDER BY columns and directions of a
+// query. PageAfter and PageBefore share the digest, since both use the same key.
+func keysetKeyDigest(query *SelectBuilder) (string, error) {
+	if query == nil || len(query.orderBy) == 0 {
+		return "", New(CodeInvalidInput, "cursor query requires order by columns")
+	}
+	h := sha256.New()
+	for _, o := range query.orderBy {
+		fmt.Fprintf(h, "%t\x00%s\x00%s\x00", o.raw, o.column, normalizeSortDirection(o.direction))
+	}
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil)[:16]), nil
+}
+
+// Encode returns the opaque string form of the cursor for the query, whose
+// ORDER BY columns the cursor values belong to.
+// Supported value types are string, bool, int, int32, int64, float64, time.Time,
+// pgtype.UUID and [16]byte. Types based on [16]byte, such as uuid.UUID, are
+// encoded as UUIDs and decode as [16]byte, which pgx also sends as a uuid.
+func (c *CursorCodec) Encode(query *SelectBuilder, cursor Cursor) (string, error) {
+	key, err := keysetKeyDigest(query)
+	if err != nil {
+		return "", err
+	}
+	values := make([]cursorValue, len(cursor))
+	for i, v := range cursor {
+		cv, err := encodeCursorValue(v)
+		if err != nil {
+			return "", err
+		}
+		values[i] = cv
+	}
+	payload, err := json.Marshal(cursorPayload{Key: key, Values: values})
+	if err != nil {
+		return "", Wrap(CodeInternal, "failed to encode cursor", err)
+	}
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString(payload) + "." + enc.EncodeToString(c.sign(payload)), nil
+}
+
+// Decode verifies and parses a cursor produced by Encode for a query with the
+// same ORDER BY columns and directions; set them on the query before Decode.
+// Returns a CodeInvalidInput error if the cursor is malformed, has been modified
+// or was issued for a different ORDER BY.
+func (c *CursorCodec) Decode(query *SelectBuilder, token string) (Cursor, error) {
+	key, err := keysetKeyDigest(query)
+	if err != nil {
+		return nil, err
+	}
+	enc := base64.RawURLEncoding
+	encodedPayload, encodedSig, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, New(CodeInvalidInput, "malformed cursor")
+	}
+	payload, err := enc.DecodeString(encodedPayload)
+	if err != nil {
+		return nil, Wrap(CodeInvalidInput, "malformed cursor", err)
+	}
+	sig, err := enc.DecodeString(encodedSig)
+	if err != nil {
+		return nil, Wrap(CodeInvalidInput, "malformed cursor", err)
+	}
+	if !hmac.Equal(sig, c.sign(payload)) {
+		return nil, New(CodeInvalidInput, "invalid cursor signature")
+	}
+
+	var decoded cursorPayload
+	if err := json.Unmarshal(payload, &decoded); err != nil {
+		return nil, Wrap(CodeInvalidInput, "malformed cursor", err)
+	}
+	if decoded.Key != key {
+		return nil, New(CodeInvalidInput, "cursor does not match the query order")
+	}
+	cursor := make(Cursor, len(decoded.Values))
+	for i, cv := range decoded.Values {
+		v, err := decodeCursorValue(cv)
+		if err != nil {
+			return nil, err
+		}
+		cursor[i] = v
+	}
+	return cursor, nil
+}
+
+// sign returns the HMAC-SHA256 of the payload.
+func (c *CursorCodec) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, c.secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// encodeCursorValue serializes a cursor value with its type.
+func encodeCursorValue(v any) (cursorValue, error) {
+	switch val := v.(type) {
+	case string:
+		return cursorValue{Type: "string", Value: val}, nil
+	case bool:
+		return cursorValue{Type: "bool", Value: strconv.FormatBool(val)}, nil
+	case int:
+		return cursorValue{Type: "int", Value: strconv.Itoa(val)}, nil
+	case int32:
+		return cursorValue{Type: "int32", Value: strconv.FormatInt(int64(val), 10)}, nil
+	case int64:
+		return cursorValue{Type: "int64", Value: strconv.FormatInt(val, 10)}, nil
+	case float64:
+		return cursorValue{Type: "float64", Value: strconv.FormatFloat(val, 'g', -1, 64)}, nil
+	case time.Time:
+		return cursorValue{Type: "time", Value: val.Format(time.RFC3339Nano)}, nil
+	case pgtype.UUID:
+		if !val.Valid {
+			return cursorValue{}, New(CodeInvalidInput, "cursor value cannot be a NULL uuid")
+		}
+		return cursorValue{Type: "pgtype.uuid", Value: formatUUID(val.Bytes)}, nil
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Array && rv.Type().ConvertibleTo(uuidBytesType) {
+		return cursorValue{Type: "uuid", Value: formatUUID(rv.Convert(uuidBytesType).Interface().([16]byte))}, nil
+	}
+	return cursorValue{}, New(CodeInvalidInput, fmt.Sprintf("unsupported cursor value type %T", v))
+}
+
+// uuidBytesType is the type of UUID cursor values after decoding.
+var uuidBytesType = reflect.TypeFor[[16]byte]()
+
+// formatUUID formats a UUID in its canonical hyphenated form.
+func formatUUID(b [16]byte) string {
+	h := hex.EncodeToString(b[:])
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:32]
+}
+
+// parseUUID parses a UUID in its canonical hyphenated form.
+func parseUUID(s string) ([16]byte, error) {
+	var b [16]byte
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return b, fmt.Errorf("invalid uuid: %q", s)
+	}
+	h := s[0:8] + s[9:13] + s[14:18] + s[19:23] + s[24:36]
+	if _, err := hex.Decode(b[:], []byte(h)); err != nil {
+		return b, fmt.Errorf("invalid uuid: %w", err)
+	}
+	return b, nil
+}
+
+// decodeCursorValue parses a serialized cursor value.
+func decodeCursorValue(cv cursorValue) (any, error) {
+	var (
+		v   any
+		err error
+	)
+	switch cv.Type {
+	case "string":
+		v = cv.Value
+	case "bool":
+		v, err = strconv.ParseBool(cv.Value)
+	case "int":
+		v, err = strconv.Atoi(cv.Value)
+	case "int32":
+		var n int64
+		n, err = strconv.ParseInt(cv.Value, 10, 32)
+		v = int32(n)
+	case "int64":
+		v, err = strconv.ParseInt(cv.Value, 10, 64)
+	case "float64":
+		v, err = strconv.ParseFloat(cv.Value, 64)
+	case "time":
+		v, err = time.Parse(time.RFC3339Nano, cv.Value)
+	case "uuid":
+		v, err = parseUUID(cv.Value)
+	case "pgtype.uuid":
+		var b [16]byte
+		b, err = parseUUID(cv.Value)
+		v = pgtype.UUID{Bytes: b, Valid: true}
+	default:
+		return nil, New(CodeInvalidInput, fmt.Sprintf("unsupported cursor value type %q", cv.Type))
+	}
+	if err != nil {
+		return nil, Wrap(CodeInvalidInput, "malformed cursor value", err)
+	}
+	return v, nil
+}
@@ -0,0 +1,375 @@
+package postgres
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var testCursorSecret = []byte("0123456789abcdef0123456789abcdef")
+
+func TestSelectBuilder_Keyset(t *testing.T) {
+	t.Parallel()
+
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		builder     Builder
+		wantSQL     string
+		wantArgs    []any
+		errContains string
+	}{
+		{
+			name: "first page",
+			builder: Select("trips").
+				OrderByDesc("created_at").OrderByDesc("id").
+				PageAfter(nil, 20),
+			wantSQL: "SELECT * FROM trips ORDER BY created_at DESC, id DESC LIMIT 21",
+		},
+		{
+			name: "after cursor descending",
+			builder: Select("trips").
+				Where("driver_id = ?", 7).
+				OrderByDesc("created_at").OrderByDesc("id").
+				PageAfter(Cursor{created, int64(42)}, 20),
+			wantSQL:  "SELECT * FROM trips WHERE driver_id = $1 AND (created_at, id) < ($2, $3) ORDER BY created_at DESC, id DESC LIMIT 21",
+			wantArgs: []any{7, created, int64(42)},
+		},
+		{
+			name: "after cursor ascending single column",
+			builder: Select("trips").
+				OrderByAsc("id").
+				PageAfter(Cursor{int64(42)}, 10),
+			wantSQL:  "SELECT * FROM trips WHERE id > $1 ORDER BY id ASC LIMIT 11",
+			wantArgs: []any{int64(42)},
+		},
+		{
+			name: "before cursor reverses order",
+			builder: Select("trips").
+				OrderByDesc("created_at").OrderByDesc("id").
+				PageBefore(Cursor{created, int64(42)}, 20),
+			wantSQL:  "SELECT * FROM trips WHERE (created_at, id) > ($1, $2) ORDER BY created_at ASC, id ASC LIMIT 21",
+			wantArgs: []any{created, int64(42)},
+		},
+		{
+			name: "or conditions are grouped",
+			builder: Select("trips").
+				Where("status = ?", "done").OrWhere("status = ?", "cancelled").
+				OrderByAsc("id").
+				PageAfter(Cursor{int64(1)}, 5),
+			wantSQL:  "SELECT * FROM trips WHERE (status = $1 OR status = $2) AND id > $3 ORDER BY id ASC LIMIT 6",
+			wantArgs: []any{"done", "cancelled", int64(1)},
+		},
+		{
+			name: "raw or condition is grouped",
+			builder: Select("trips").
+				Where("status = ? OR status = ?", "done", "cancelled").
+				OrderByAsc("id").
+				PageAfter(Cursor{int64(1)}, 5),
+			wantSQL:  "SELECT * FROM trips WHERE (status = $1 OR status = $2) AND id > $3 ORDER BY id ASC LIMIT 6",
+			wantArgs: []any{"done", "cancelled", int64(1)},
+		},
+		{
+			name: "and conditions are grouped",
+			builder: Select("trips").
+				Where("driver_id = ?", 7).Where("status = ? OR status = ?", "done", "cancelled").
+				OrderByAsc("id").
+				PageAfter(Cursor{int64(1)}, 5),
+			wantSQL:  "SELECT * FROM trips WHERE (driver_id = $1 AND (status = $2 OR status = $3)) AND id > $4 ORDER BY id ASC LIMIT 6",
+			wantArgs: []any{7, "done", "cancelled", int64(1)},
+		},
+		{
+			name:        "requires order by",
+			builder:     Select("trips").PageAfter(nil, 20),
+			errContains: "requires order by columns",
+		},
+		{
+			name: "mixed directions",
+			builder: Select("trips").
+				OrderByDesc("created_at").OrderByAsc("id").
+				PageAfter(nil, 20),
+			errContains: "share a direction",
+		},
+		{
+			name: "cursor length mismatch",
+			builder: Select("trips").
+				OrderByDesc("created_at").OrderByDesc("id").
+				PageAfter(Cursor{created}, 20),
+			errContains: "cursor has 1 values but 2 order by columns",
+		},
+		{
+			name:        "invalid limit",
+			builder:     Select("trips").OrderByAsc("id").PageAfter(nil, 0),
+			errContains: "limit must be positive",
+		},
+		{
+			name:        "combined with limit",
+			builder:     Select("trips").OrderByAsc("id").Limit(10).PageAfter(nil, 20),
+			errContains: "cannot be combined with LIMIT or OFFSET",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assertBuild(t, tt.builder, tt.wantSQL, tt.wantArgs, tt.errContains)
+		})
+	}
+}
+
+func TestCursorCodec(t *testing.T) {
+	t.Parallel()
+
+	query := Select("trips").OrderByAsc("id")
+
+	t.Run("round trip preserves types", func(t *testing.T) {
+		t.Parallel()
+		codec, err := NewCursorCodec(testCursorSecret)
+		if err != nil {
+			t.Fatalf("NewCursorCodec() error = %v", err)
+		}
+		created := time.Date(2026, 1, 2, 3, 4, 5, 123456789, time.UTC)
+		cursor := Cursor{created, int64(42), 7, int32(3), "abc", true, 1.5}
+
+		token, err := codec.Encode(query, cursor)
+		if err != nil {
+			t.Fatalf("Encode() error = %v", err)
+		}
+		got, err := codec.Decode(query, token)
+		if err != nil {
+			t.Fatalf("Decode() error = %v", err)
+		}
+		if len(got) != len(cursor) {
+			t.Fatalf("Decode() = %v, want %v", got, cursor)
+		}
+		if !got[0].(time.Time).Equal(created) {
+			t.Errorf("time value = %v, want %v", got[0], created)
+		}
+		for i := 1; i < len(cursor); i++ {
+			if got[i] != cursor[i] {
+				t.Errorf("value %d = %#v, want %#v", i, got[i], cursor[i])
+			}
+		}
+	})
+
+	t.Run("round trip uuids", func(t *testing.T) {
+		t.Parallel()
+		codec, err := NewCursorCodec(testCursorSecret)
+		if err != nil {
+			t.Fatalf("NewCursorCodec() error = %v", err)
+		}
+		type testUUID [16]byte // as uuid.UUID
+		id := testUUID{0x6b, 0xa7, 0xb8, 0x10, 0x9d, 0xad, 0x11, 0xd1, 0x80, 0xb4, 0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc8}
+		cursor := Cursor{id, [16]byte(id), pgtype.UUID{Bytes: id, Valid: true}}
+
+		token, err := codec.Encode(query, cursor)
+		if err != nil {
+			t.Fatalf("Encode() error = %v", err)
+		}
+		got, err := codec.Decode(query, token)
+		if err != nil {
+			t.Fatalf("Decode() error = %v", err)
+		}
+		want := Cursor{[16]byte(id), [16]byte(id), pgtype.UUID{Bytes: id, Valid: true}}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Decode() = %#v, want %#v", got, want)
+		}
+
+		if _, err := codec.Encode(query, Cursor{pgtype.UUID{}}); !IsCode(err, CodeInvalidInput) {
+			t.Errorf("Encode() error = %v, want invalid input for NULL uuid", err)
+		}
+	})
+
+	t.Run("rejects tampered cursor", func(t *testing.T) {
+		t.Parallel()
+		codec, _ := NewCursorCodec(testCursorSecret)
+		token, err := codec.Encode(query, Cursor{int64(42)})
+		if err != nil {
+			t.Fatalf("Encode() error = %v", err)
+		}
+		forged, err := codec.Encode(query, Cursor{int64(43)})
+		if err != nil {
+			t.Fatalf("Encode() error = %v", err)
+		}
+		payload, _, _ := strings.Cut(forged, ".")
+		_, sig, _ := strings.Cut(token, ".")
+
+		_, err = codec.Decode(query, payload+"."+sig)
+		if !IsCode(err, CodeInvalidInput) {
+			t.Errorf("Decode() error = %v, want invalid input", err)
+		}
+	})
+
+	t.Run("rejects cursor for other order", func(t *testing.T) {
+		t.Parallel()
+		codec, _ := NewCursorCodec(testCursorSecret)
+		token, err := codec.Encode(Select("trips").OrderByDesc("created_at").OrderByDesc("id"), Cursor{"a", int64(1)})
+		if err != nil {
+			t.Fatalf("Encode() error = %v", err)
+		}
+
+		for _, other := range []*SelectBuilder{
+			Select("trips").OrderByAsc("created_at").OrderByAsc("id"),
+			Select("trips").OrderByDesc("updated_at").OrderByDesc("id"),
+			Select("trips").OrderByDesc("created_at"),
+			Select("trips"),
+		} {
+			if _, err := codec.Decode(other, token); !IsCode(err, CodeInvalidInput) {
+				t.Errorf("Decode(%s) error = %v, want invalid input", other.SQL(), err)
+			}
+		}
+
+		same := Select("users").Where("active = ?", true).OrderByDesc("created_at").OrderByDesc("id").PageBefore(nil, 10)
+		if _, err := codec.Decode(same, token); err != nil {
+			t.Errorf("Decode() error = %v for the same order", err)
+		}
+	})
+
+	t.Run("rejects other secret", func(t *testing.T) {
+		t.Parallel()
+		codec, _ := NewCursorCodec(testCursorSecret)
+		other, _ := NewCursorCodec([]byte("fedcba9876543210fedcba9876543210"))
+		token, _ := codec.Encode(query, Cursor{"a"})
+
+		if _, err := other.Decode(query, token); !IsCode(err, CodeInvalidInput) {
+			t.Errorf("Decode() error = %v, want invalid input", err)
+		}
+	})
+
+	t.Run("rejects malformed cursor", func(t *testing.T) {
+		t.Parallel()
+		codec, _ := NewCursorCodec(testCursorSecret)
+		for _, token := range []string{"", "abc", "!!.??"} {
+			if _, err := codec.Decode(query, token); !IsCode(err, CodeInvalidInput) {
+				t.Errorf("Decode(%q) error = %v, want invalid input", token, err)
+			}
+		}
+	})
+
+	t.Run("rejects unsupported value", func(t *testing.T) {
+		t.Parallel()
+		codec, _ := NewCursorCodec(testCursorSecret)
+		if _, err := codec.Encode(query, Cursor{struct{}{}}); !IsCode(err, CodeInvalidInput) {
+			t.Errorf("Encode() error = %v, want invalid input", err)
+		}
+	})
+
+	t.Run("rejects short secret", func(t *testing.T) {
+		t.Parallel()
+		if _, err := NewCursorCodec([]byte("short")); !IsCode(err, CodeInvalidInput) {
+			t.Errorf("NewCursorCodec() error = %v, want invalid input", err)
+		}
+	})
+}
+
+func TestNewKeysetResult(t *testing.T) {
+	t.Parallel()
+
+	type trip struct{ ID int64 }
+	key := func(tr trip) Cursor { return Cursor{tr.ID} }
+	codec, err := NewCursorCodec(testCursorSecret)
+	if err != nil {
+		t.Fatalf("NewCursorCodec() error = %v", err)
+	}
+	decode := func(t *testing.T, token string) int64 {
+		t.Helper()
+		cursor, err := codec.Decode(Select("trips").OrderByAsc("id"), token)
+		if err != nil {
+			t.Fatalf("Decode() error = %v", err)
+		}
+		return cursor[0].(int64)
+	}
+
+	tests := []struct {
+		name     string
+		query    *SelectBuilder
+		rows     []trip
+		wantIDs  []int64
+		wantNext int64
+		wantPrev int64
+	}{
+		{
+			name:     "first page with more",
+			query:    Select("trips").OrderByAsc("id").PageAfter(nil, 2),
+			rows:     []trip{{1}, {2}, {3}},
+			wantIDs:  []int64{1, 2},
+			wantNext: 2,
+		},
+		{
+			name:     "middle page",
+			query:    Select("trips").OrderByAsc("id").PageAfter(Cursor{int64(2)}, 2),
+			rows:     []trip{{3}, {4}, {5}},
+			wantIDs:  []int64{3, 4},
+			wantNext: 4,
+			wantPrev: 3,
+		},
+		{
+			name:     "last page",
+			query:    Select("trips").OrderByAsc("id").PageAfter(Cursor{int64(4)}, 2),
+			rows:     []trip{{5}},
+			wantIDs:  []int64{5},
+			wantPrev: 5,
+		},
+		{
+			name:     "page before with more",
+			query:    Select("trips").OrderByAsc("id").PageBefore(Cursor{int64(5)}, 2),
+			rows:     []trip{{4}, {3}, {2}},
+			wantIDs:  []int64{3, 4},
+			wantNext: 4,
+			wantPrev: 3,
+		},
+		{
+			name:     "page before reaching start",
+			query:    Select("trips").OrderByAsc("id").PageBefore(Cursor{int64(3)}, 2),
+			rows:     []trip{{2}, {1}},
+			wantIDs:  []int64{1, 2},
+			wantNext: 2,
+		},
+		{
+			name:    "empty page",
+			query:   Select("trips").OrderByAsc("id").PageAfter(Cursor{int64(9)}, 2),
+			rows:    nil,
+			wantIDs: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			result, err := NewKeysetResult(codec, tt.query, tt.rows, key)
+			if err != nil {
+				t.Fatalf("NewKeysetResult() error = %v", err)
+			}
+			if len(result.Items) != len(tt.wantIDs) {
+				t.Fatalf("Items = %v, want ids %v", result.Items, tt.wantIDs)
+			}
+			for i, item := range result.Items {
+				if item.ID != tt.wantIDs[i] {
+					t.Errorf("Items[%d].ID = %d, want %d", i, item.ID, tt.wantIDs[i])
+				}
+			}
+			if result.HasNext() != (tt.wantNext != 0) {
+				t.Errorf("HasNext() = %v, want %v", result.HasNext(), tt.wantNext != 0)
+			} else if tt.wantNext != 0 && decode(t, result.NextCursor) != tt.wantNext {
+				t.Errorf("NextCursor = %d, want %d", decode(t, result.NextCursor), tt.wantNext)
+			}
+			if result.HasPrev() != (tt.wantPrev != 0) {
+				t.Errorf("HasPrev() = %v, want %v", result.HasPrev(), tt.wantPrev != 0)
+			} else if tt.wantPrev != 0 && decode(t, result.PrevCursor) != tt.wantPrev {
+				t.Errorf("PrevCursor = %d, want %d", decode(t, result.PrevCursor), tt.wantPrev)
+			}
+		})
+	}
+
+	t.Run("requires keyset query", func(t *testing.T) {
+		t.Parallel()
+		_, err := NewKeysetResult(codec, Select("trips"), []trip{}, key)
+		if !IsCode(err, CodeInvalidInput) {
+			t.Errorf("NewKeysetResult() error = %v, want invalid input", err)
+		}
+	})
+}
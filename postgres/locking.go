@@ -0,0 +1,165 @@
+// Package postgres provides PostgreSQL database utilities.
+package postgres
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LockStrength is the strength of a row-level lock taken by SELECT ... FOR.
+type LockStrength string
+
+// Row-level lock strengths, from strongest to weakest.
+const (
+	// LockForUpdate blocks all other locks and modifications of the rows.
+	LockForUpdate LockStrength = "UPDATE"
+	// LockForNoKeyUpdate is like LockForUpdate but allows LockForKeyShare,
+	// so foreign key checks on the rows are not blocked. Use it when the key is not updated.
+	LockForNoKeyUpdate LockStrength = "NO KEY UPDATE"
+	// LockForShare blocks modifications of the rows but allows other share locks.
+	LockForShare LockStrength = "SHARE"
+	// LockForKeyShare only blocks deletes and key updates of the rows.
+	LockForKeyShare LockStrength = "KEY SHARE"
+)
+
+// LockWait controls what happens when a row is already locked.
+type LockWait string
+
+// Lock wait policies.
+const (
+	// LockWaitBlock waits for conflicting locks to be released. This is the default.
+	LockWaitBlock LockWait = ""
+	// LockNoWait fails with a CodeLockNotAvailable error instead of waiting.
+	LockNoWait LockWait = "NOWAIT"
+	// LockSkipLocked skips rows that cannot be locked immediately.
+	LockSkipLocked LockWait = "SKIP LOCKED"
+)
+
+// LockClause is a row-level locking clause: FOR strength [OF tables] [NOWAIT | SKIP LOCKED].
+type LockClause struct {
+	// Strength is the lock strength.
+	Strength LockStrength
+
+	// Of restricts the lock to rows of these tables or aliases. Empty locks rows of all tables.
+	// Names are quoted as needed; PostgreSQL only accepts unqualified names here, so
+	// schema-qualified names such as public.trips are rendered without the schema.
+	Of []string
+
+	// Wait is the policy for rows that are already locked.
+	Wait LockWait
+}
+
+// validate checks the lock clause.
+func (l LockClause) validate() error {
+	switch l.Strength {
+	case LockForUpdate, LockForNoKeyUpdate, LockForShare, LockForKeyShare:
+	case "":
+		return fmt.Errorf("lock modifier requires ForUpdate, ForNoKeyUpdate, ForShare or ForKeyShare")
+	default:
+		return fmt.Errorf("invalid lock strength: %q", l.Strength)
+	}
+	switch l.Wait {
+	case LockWaitBlock, LockNoWait, LockSkipLocked:
+	default:
+		return fmt.Errorf("invalid lock wait policy: %q", l.Wait)
+	}
+	for _, table := range l.Of {
+		if _, err := parseIdentifier(table, maxTableParts); err != nil {
+			return fmt.Errorf("invalid lock table: %q", table)
+		}
+	}
+	return nil
+}
+
+// lockTableName formats a table of a locking clause without its schema.
+// Names that do not parse are returned unchanged; validate rejects them.
+func lockTableName(table string) string {
+	parts, err := parseIdentifier(table, maxTableParts)
+	if err != nil {
+		return table
+	}
+	return formatIdentifier(parts[len(parts)-1:])
+}
+
+// String returns the clause as SQL, e.g. FOR UPDATE OF trips SKIP LOCKED.
+func (l LockClause) String() string {
+	var sb strings.Builder
+	sb.WriteString("FOR ")
+	sb.WriteString(string(l.Strength))
+	if len(l.Of) > 0 {
+		sb.WriteString(" OF ")
+		for i, table := range l.Of {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString(lockTableName(table))
+		}
+	}
+	if l.Wait != LockWaitBlock {
+		sb.WriteString(" ")
+		sb.WriteString(string(l.Wait))
+	}
+	return sb.String()
+}
+
+// ForNoKeyUpdate adds FOR NO KEY UPDATE locking.
+func (s *SelectBuilder) ForNoKeyUpdate() *SelectBuilder {
+	s.locks = []LockClause{{Strength: LockForNoKeyUpdate}}
+	return s
+}
+
+// ForKeyShare adds FOR KEY SHARE locking.
+func (s *SelectBuilder) ForKeyShare() *SelectBuilder {
+	s.locks = []LockClause{{Strength: LockForKeyShare}}
+	return s
+}
+
+// Lock adds a locking clause. Unlike ForUpdate and the other shorthands, which
+// replace any previous locking, Lock can be called repeatedly to lock the tables
+// of a join with different strengths.
+func (s *SelectBuilder) Lock(clause LockClause) *SelectBuilder {
+	clause.Of = append([]string(nil), clause.Of...)
+	s.locks = append(s.locks, clause)
+	return s
+}
+
+// Of restricts the last locking clause to rows of the given tables or aliases,
+// e.g. to lock trips but not the joined drivers: ForUpdate().Of("trips").
+func (s *SelectBuilder) Of(tables ...string) *SelectBuilder {
+	l := s.lastLock()
+	l.Of = append(l.Of, tables...)
+	return s
+}
+
+// NoWait makes the last locking clause fail with a CodeLockNotAvailable error
+// instead of waiting for a locked row.
+func (s *SelectBuilder) NoWait() *SelectBuilder {
+	s.lastLock().Wait = LockNoWait
+	return s
+}
+
+// SkipLocked makes the last locking clause skip rows that are already locked,
+// e.g. to let several workers claim different jobs from a queue table.
+func (s *SelectBuilder) SkipLocked() *SelectBuilder {
+	s.lastLock().Wait = LockSkipLocked
+	return s
+}
+
+// lastLock returns the last locking clause. Without one, an empty clause is added,
+// which fails validation.
+func (s *SelectBuilder) lastLock() *LockClause {
+	if len(s.locks) == 0 {
+		s.locks = append(s.locks, LockClause{})
+	}
+	return &s.locks[len(s.locks)-1]
+}
+
+// validateLocks checks the locking clauses.
+func (s *SelectBuilder) validateLocks() error {
+	for _, l := range s.locks {
+		if err := l.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
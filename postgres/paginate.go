@@ -0,0 +1,105 @@
+// Package postgres provides PostgreSQL database utilities.
+package postgres
+
+import (
+	"context"
+	"slices"
+
+	"github.com/Dorico-Dynamics/txova-go-types/pagination"
+	"github.com/jackc/pgx/v5"
+)
+
+// countSubqueryAlias is the alias of the wrapped query in derived count queries.
+const countSubqueryAlias = "counted"
+
+// clone returns a copy of the builder that can be modified without changing s.
+func (s *SelectBuilder) clone() *SelectBuilder {
+	c := *s
+	c.columns = slices.Clip(s.columns)
+	c.where = slices.Clip(s.where)
+	c.joins = slices.Clip(s.joins)
+	c.orderBy = slices.Clip(s.orderBy)
+	c.groupBy = slices.Clip(s.groupBy)
+	c.having = slices.Clip(s.having)
+	c.locks = slices.Clip(s.locks)
+	c.textHeadlines = slices.Clip(s.textHeadlines)
+	c.distances = slices.Clip(s.distances)
+	return &c
+}
+
+// CountBuilder returns a query counting the rows the select returns without
+// pagination. ORDER BY, LIMIT, OFFSET, keyset pagination and row locks are removed.
+// Queries with GROUP BY, HAVING or DISTINCT are wrapped so that groups and distinct
+// rows are counted: SELECT COUNT(*) FROM (query) AS counted.
+// The builder itself is not modified.
+func (s *SelectBuilder) CountBuilder() *SelectBuilder {
+	inner := s.clone()
+	inner.orderBy = nil
+	inner.limit = nil
+	inner.offset = nil
+	inner.keyset = nil
+	inner.locks = nil
+
+	if s.distinct || len(s.groupBy) > 0 || len(s.having) > 0 {
+		return Select("").FromSubquery(countSubqueryAlias, inner).ColumnsRaw("COUNT(*)")
+	}
+	inner.columns = []columnRef{{name: "COUNT(*)", raw: true}}
+	inner.textRank = ""
+	inner.textHeadlines = nil
+	inner.distances = nil
+	return inner
+}
+
+// Paginate returns one page of the select's rows, scanned into T as with ScanAll,
+// with the total number of rows from CountBuilder. The page is applied with Page;
+// the query itself is not modified. If q implements Batcher, both queries are sent
+// in one round trip; otherwise they run one after the other.
+// Run Paginate in a REPEATABLE READ transaction for a count consistent with the rows.
+func Paginate[T any](ctx context.Context, q Querier, query *SelectBuilder, req pagination.PageRequest) (pagination.PageResponse[T], error) {
+	var zero pagination.PageResponse[T]
+
+	pageSQL, pageArgs, err := query.clone().Page(req).Build()
+	if err != nil {
+		return zero, Wrap(CodeInvalidInput, "invalid page query", err)
+	}
+	countSQL, countArgs, err := query.CountBuilder().Build()
+	if err != nil {
+		return zero, Wrap(CodeInvalidInput, "invalid count query", err)
+	}
+
+	var items []T
+	var total int64
+	if batcher, ok := q.(Batcher); ok {
+		batch := &pgx.Batch{}
+		batch.Queue(pageSQL, pageArgs...)
+		batch.Queue(countSQL, countArgs...)
+		results := batcher.SendBatch(ctx, batch)
+		defer results.Close()
+
+		rows, err := results.Query()
+		if err != nil {
+			return zero, asDBError(err)
+		}
+		if items, err = ScanAll[T](rows); err != nil {
+			return zero, err
+		}
+		if err := results.QueryRow().Scan(&total); err != nil {
+			return zero, asDBError(err)
+		}
+		if err := results.Close(); err != nil {
+			return zero, asDBError(err)
+		}
+	} else {
+		rows, err := q.Query(ctx, pageSQL, pageArgs...)
+		if err != nil {
+			return zero, asDBError(err)
+		}
+		if items, err = ScanAll[T](rows); err != nil {
+			return zero, err
+		}
+		if err := q.QueryRow(ctx, countSQL, countArgs...).Scan(&total); err != nil {
+			return zero, asDBError(err)
+		}
+	}
+	return pagination.NewPageResponse(items, total, req), nil
+}
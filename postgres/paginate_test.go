@@ -0,0 +1,144 @@
+package postgres
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/Dorico-Dynamics/txova-go-types/pagination"
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+func TestSelectBuilder_CountBuilder(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		builder  *SelectBuilder
+		wantSQL  string
+		wantArgs []any
+	}{
+		{
+			name: "strips order, limit, offset and locks",
+			builder: Select("trips").Columns("id", "fare").Where("status = ?", "active").
+				OrderByDesc("created_at").Limit(20).Offset(40).ForUpdate().SkipLocked(),
+			wantSQL:  "SELECT COUNT(*) FROM trips WHERE status = $1",
+			wantArgs: []any{"active"},
+		},
+		{
+			name: "keeps joins and drops computed columns",
+			builder: Select("trips").Columns("trips.id").
+				Join("drivers", "drivers.id = trips.driver_id AND drivers.region = ?", "north").
+				SelectDistance("pickup", 1, 2, "distance").
+				OrderByDistance("pickup", 1, 2),
+			wantSQL:  "SELECT COUNT(*) FROM trips INNER JOIN drivers ON drivers.id = trips.driver_id AND drivers.region = $1",
+			wantArgs: []any{"north"},
+		},
+		{
+			name: "wraps group by",
+			builder: Select("trips").Columns("driver_id").ColumnsRaw("COUNT(*) AS trips").
+				Where("status = ?", "done").GroupBy("driver_id").Having("COUNT(*) > ?", 5).
+				OrderByDesc("driver_id").Limit(10),
+			wantSQL: "SELECT COUNT(*) FROM (SELECT driver_id, COUNT(*) AS trips FROM trips WHERE status = $1 " +
+				"GROUP BY driver_id HAVING COUNT(*) > $2) AS counted",
+			wantArgs: []any{"done", 5},
+		},
+		{
+			name:    "wraps distinct",
+			builder: Select("trips").Distinct().Columns("driver_id").OrderByAsc("driver_id").Limit(5),
+			wantSQL: "SELECT COUNT(*) FROM (SELECT DISTINCT driver_id FROM trips) AS counted",
+		},
+		{
+			name: "drops keyset pagination",
+			builder: Select("trips").Columns("id").Where("status = ?", "active").
+				OrderByDesc("id").PageAfter(Cursor{100}, 20),
+			wantSQL:  "SELECT COUNT(*) FROM trips WHERE status = $1",
+			wantArgs: []any{"active"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			original := tt.builder.SQL()
+			assertBuild(t, tt.builder.CountBuilder(), tt.wantSQL, tt.wantArgs, "")
+			if got := tt.builder.SQL(); got != original {
+				t.Errorf("CountBuilder() modified the builder: %q, was %q", got, original)
+			}
+		})
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	req := pagination.PageRequest{Limit: 2, Offset: 2, SortField: "name", SortDir: pagination.SortAsc}
+	pageSQL := regexp.QuoteMeta("SELECT id, name, email FROM users WHERE active = $1 ORDER BY name ASC LIMIT 2 OFFSET 2")
+	countSQL := regexp.QuoteMeta("SELECT COUNT(*) FROM users WHERE active = $1")
+
+	newQuery := func() *SelectBuilder {
+		return Select("users").Columns("id", "name", "email").Where("active = ?", true)
+	}
+	check := func(t *testing.T, page pagination.PageResponse[testUser], err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("Paginate() error = %v", err)
+		}
+		if len(page.Items) != 2 || page.Items[1].Name != "Dina" || page.Total != 5 || !page.HasMore {
+			t.Errorf("Paginate() = %+v", page)
+		}
+	}
+
+	t.Run("batch", func(t *testing.T) {
+		t.Parallel()
+		mock, err := pgxmock.NewPool()
+		if err != nil {
+			t.Fatalf("failed to create mock: %v", err)
+		}
+		defer mock.Close()
+
+		batch := mock.ExpectBatch()
+		batch.ExpectQuery(pageSQL).WithArgs(true).
+			WillReturnRows(userRows().AddRow(int64(3), "Carla", "c@x.co").AddRow(int64(4), "Dina", "d@x.co"))
+		batch.ExpectQuery(countSQL).WithArgs(true).
+			WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(int64(5)))
+
+		query := newQuery()
+		page, err := Paginate[testUser](ctx, mock, query, req)
+		check(t, page, err)
+		if query.SQL() != "SELECT id, name, email FROM users WHERE active = $1" {
+			t.Errorf("Paginate() modified the query: %s", query.SQL())
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("sequential", func(t *testing.T) {
+		t.Parallel()
+		pool, mock := newMockPool(t)
+		defer mock.Close()
+
+		mock.ExpectQuery(pageSQL).WithArgs(true).
+			WillReturnRows(userRows().AddRow(int64(3), "Carla", "c@x.co").AddRow(int64(4), "Dina", "d@x.co"))
+		mock.ExpectQuery(countSQL).WithArgs(true).
+			WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(int64(5)))
+
+		page, err := Paginate[testUser](ctx, pool, newQuery(), req)
+		check(t, page, err)
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("invalid query", func(t *testing.T) {
+		t.Parallel()
+		pool, mock := newMockPool(t)
+		defer mock.Close()
+
+		_, err := Paginate[testUser](ctx, pool, Select("users").Columns("COUNT(*)"), req)
+		if !IsCode(err, CodeInvalidInput) {
+			t.Errorf("Paginate() error = %v, want CodeInvalidInput", err)
+		}
+	})
+}
@@ -130,6 +130,13 @@ func (t *mockTx) Rollback(ctx context.Context) error {
 	return nil
 }
 
+func (t *mockTx) Prepare(ctx context.Context, gid string) error {
+	if _, err := t.Exec(ctx, "PREPARE TRANSACTION '"+gid+"'"); err != nil {
+		return err
+	}
+	return t.Commit(ctx)
+}
+
 func (t *mockTx) Conn() *pgx.Conn {
 	return t.tx.Conn()
 }
@@ -800,15 +807,30 @@ func TestTxManager_MaxRetriesExceeded(t *testing.T) {
 		_, err := tx.Exec(ctx, "UPDATE users SET name = 'test'")
 		return err
 	})
-	if err == nil {
-		t.Error("expected error after max retries")
+	if !IsSerialization(err) {
+		t.Errorf("expected serialization error after max retries, got: %v", err)
 	}
+	assertRetriesExhausted(t, err, "transaction failed after max retries")
 
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("unfulfilled expectations: %v", err)
 	}
 }
 
+// assertRetriesExhausted checks that a transaction that gave up retrying returns
+// an Error with the given message wrapping the last attempt's PostgreSQL error.
+func assertRetriesExhausted(t *testing.T, err error, message string) {
+	t.Helper()
+	dbErr := AsError(err)
+	if dbErr == nil || dbErr.Message() != message {
+		t.Errorf("error = %v, want message %q", err, message)
+	}
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != "40001" {
+		t.Errorf("error = %v, want wrapped serialization failure", err)
+	}
+}
+
 // =============================================================================
 // Error Mapping Tests with pgxmock
 // =============================================================================
@@ -1367,3 +1389,238 @@ func TestTxManager_BeginTxError(t *testing.T) {
 		t.Errorf("unfulfilled expectations: %v", mockErr)
 	}
 }
+
+func TestTxManager_RetryPolicy_CustomClassifier(t *testing.T) {
+	t.Parallel()
+
+	pool, mock := newMockPool(t)
+	defer mock.Close()
+
+	var attempts []TxAttempt
+	txMgr := NewTxManager(pool,
+		WithMaxRetries(2),
+		WithRetryPolicy(NewRetryPolicy(
+			AnyRetryClassifier(RetryOnBeginConnectionError, RetryOnSQLStates("55P03")),
+			ConstantBackoff(time.Millisecond),
+			nil,
+		)),
+		WithOnTxAttempt(func(_ context.Context, attempt TxAttempt) {
+			attempts = append(attempts, attempt)
+		}),
+	)
+	ctx := context.Background()
+
+	// First attempt - connection lost before any statement
+	mock.ExpectBegin().
+		WillReturnError(&pgconn.PgError{Code: "08006", Message: "connection failure"})
+
+	// Second attempt - lock not available
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE").
+		WillReturnError(&pgconn.PgError{Code: "55P03", Message: "could not obtain lock"})
+	mock.ExpectRollback()
+
+	// Third attempt - success
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectCommit()
+
+	err := txMgr.WithTx(ctx, func(tx Tx) error {
+		_, err := tx.Exec(ctx, "UPDATE trips SET status = 'expired'")
+		return err
+	})
+	if err != nil {
+		t.Fatalf("WithTx() error = %v", err)
+	}
+
+	if len(attempts) != 3 {
+		t.Fatalf("len(attempts) = %d, want 3", len(attempts))
+	}
+	if attempts[0].Phase != TxPhaseBegin || !attempts[0].WillRetry || attempts[0].Delay != time.Millisecond {
+		t.Errorf("attempts[0] = %+v, want retried begin failure", attempts[0])
+	}
+	if attempts[1].Phase != TxPhaseExec || !attempts[1].WillRetry {
+		t.Errorf("attempts[1] = %+v, want retried exec failure", attempts[1])
+	}
+	if attempts[2].Attempt != 3 || attempts[2].Err != nil || attempts[2].WillRetry {
+		t.Errorf("attempts[2] = %+v, want successful third attempt", attempts[2])
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestTxManager_RetryPolicy_BudgetExhausted(t *testing.T) {
+	t.Parallel()
+
+	pool, mock := newMockPool(t)
+	defer mock.Close()
+
+	var attempts []TxAttempt
+	txMgr := NewTxManager(pool,
+		WithMaxRetries(5),
+		WithRetryPolicy(NewRetryPolicy(nil, ConstantBackoff(time.Millisecond), NewRetryBudget(1, 0))),
+		WithOnTxAttempt(func(_ context.Context, attempt TxAttempt) {
+			attempts = append(attempts, attempt)
+		}),
+	)
+	ctx := context.Background()
+
+	// Only one retry is allowed by the budget.
+	for i := 0; i < 2; i++ {
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE").
+			WillReturnError(&pgconn.PgError{Code: "40001", Message: "could not serialize access"})
+		mock.ExpectRollback()
+	}
+
+	err := txMgr.WithTx(ctx, func(tx Tx) error {
+		_, err := tx.Exec(ctx, "UPDATE users SET name = 'test'")
+		return err
+	})
+	if !IsSerialization(err) {
+		t.Errorf("expected serialization error, got: %v", err)
+	}
+	if len(attempts) != 2 || attempts[1].WillRetry {
+		t.Errorf("attempts = %+v, want two attempts with the last not retried", attempts)
+	}
+	assertRetriesExhausted(t, err, "transaction failed after retry budget exhausted")
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestTxManager_WithTxSettings_SetLocal(t *testing.T) {
+	t.Parallel()
+
+	pool, mock := newMockPool(t)
+	defer mock.Close()
+
+	txMgr := NewTxManager(pool).(TxSettingsManager)
+	ctx := context.Background()
+
+	mock.ExpectBeginTx(pgx.TxOptions{IsoLevel: pgx.RepeatableRead})
+	mock.ExpectExec("SET LOCAL statement_timeout = 5000; SET LOCAL lock_timeout = 1000").
+		WillReturnResult(pgxmock.NewResult("SET", 0))
+	mock.ExpectExec("UPDATE").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectCommit()
+
+	settings := TxSettings{
+		Options:          pgx.TxOptions{IsoLevel: pgx.RepeatableRead},
+		StatementTimeout: 5 * time.Second,
+		LockTimeout:      time.Second,
+		MaxDuration:      time.Minute,
+	}
+	err := txMgr.WithTxSettings(ctx, settings, func(tx Tx) error {
+		_, err := tx.Exec(ctx, "UPDATE trips SET status = 'expired'")
+		return err
+	})
+	if err != nil {
+		t.Fatalf("WithTxSettings() error = %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestTxManager_WithTxSettings_MaxDurationExceeded(t *testing.T) {
+	t.Parallel()
+
+	pool, mock := newMockPool(t)
+	defer mock.Close()
+
+	txMgr := NewTxManager(pool).(TxSettingsManager)
+	ctx := context.Background()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1)).
+		WillDelayFor(time.Second)
+	mock.ExpectRollback()
+
+	start := time.Now()
+	err := txMgr.WithTxSettings(ctx, TxSettings{MaxDuration: 20 * time.Millisecond}, func(tx Tx) error {
+		_, err := tx.Exec(ctx, "UPDATE trips SET status = 'expired'")
+		return err
+	})
+	if !IsTimeout(err) {
+		t.Fatalf("expected timeout error, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("elapsed = %v, expected statement to be cancelled at the deadline", elapsed)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestTxManager_WithTxSettings_MaxDurationCoversRetries(t *testing.T) {
+	t.Parallel()
+
+	pool, mock := newMockPool(t)
+	defer mock.Close()
+
+	txMgr := NewTxManager(pool,
+		WithMaxRetries(5),
+		WithRetryBaseDelay(time.Millisecond),
+		WithRetryMaxDelay(time.Millisecond),
+	).(TxSettingsManager)
+	ctx := context.Background()
+
+	// Each attempt takes 60ms; only the second reaches the 100ms deadline.
+	for i := 0; i < 2; i++ {
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE").
+			WillDelayFor(60 * time.Millisecond).
+			WillReturnError(&pgconn.PgError{Code: "40001", Message: "could not serialize access"})
+		mock.ExpectRollback()
+	}
+
+	attempts := 0
+	err := txMgr.WithTxSettings(ctx, TxSettings{MaxDuration: 100 * time.Millisecond}, func(tx Tx) error {
+		attempts++
+		_, err := tx.Exec(ctx, "UPDATE trips SET status = 'expired'")
+		return err
+	})
+	if !IsTimeout(err) {
+		t.Fatalf("expected timeout error, got: %v", err)
+	}
+	if attempts != 2 {
+		t.Errorf("attempts = %d, want 2", attempts)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestTxManager_WithTxSettings_CommitAfterDeadline(t *testing.T) {
+	t.Parallel()
+
+	pool, mock := newMockPool(t)
+	defer mock.Close()
+
+	txMgr := NewTxManager(pool).(TxSettingsManager)
+	ctx := context.Background()
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	err := txMgr.WithTxSettings(ctx, TxSettings{MaxDuration: 10 * time.Millisecond}, func(tx Tx) error {
+		time.Sleep(20 * time.Millisecond)
+		return nil
+	})
+	if !IsTimeout(err) {
+		t.Fatalf("expected timeout error, got: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
@@ -304,6 +304,32 @@ func (p *pgxPool) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
 	return row
 }
 
+// SendBatch sends the queued queries of a batch in one round trip.
+// Query errors are returned by the methods of the BatchResults.
+func (p *pgxPool) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	return p.pool.SendBatch(ctx, b)
+}
+
+// CopyFrom copies rows into the table using the COPY protocol.
+func (p *pgxPool) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	start := time.Now()
+	n, err := p.pool.CopyFrom(ctx, tableName, columnNames, rowSrc)
+	duration := time.Since(start)
+
+	copySQL := "COPY " + tableName.Sanitize()
+	p.logSlowQuery(ctx, copySQL, duration)
+
+	if err != nil {
+		p.logger.ErrorContext(ctx, "copy failed",
+			"sql", copySQL,
+			"duration_ms", duration.Milliseconds(),
+			"error", err.Error(),
+		)
+		return n, FromPgError(err)
+	}
+	return n, nil
+}
+
 // logSlowQuery logs a warning if the query duration exceeds the threshold.
 func (p *pgxPool) logSlowQuery(ctx context.Context, sql string, duration time.Duration) {
 	if p.config.SlowQueryThreshold > 0 && duration >= p.config.SlowQueryThreshold {
@@ -4,6 +4,7 @@ package postgres
 import (
 	"fmt"
 	"regexp"
+	"slices"
 	"strings"
 
 	"github.com/Dorico-Dynamics/txova-go-types/pagination"
@@ -31,21 +32,39 @@ type join struct {
 }
 
 // whereClause represents a WHERE condition.
+// If hasSubquery is set, the condition is followed by the parenthesized subquery.
+// If hasCond is set, the condition tree cond is rendered instead of condition.
+// If in is set, the IN list is rendered instead of condition.
+// If isArray is set, the single arg is an array parameter.
+// If err is set, the condition could not be created and the query fails validation.
 type whereClause struct {
-	condition string
-	args      []any
-	isOr      bool
+	condition   string
+	args        []any
+	isOr        bool
+	hasSubquery bool
+	subquery    *SelectBuilder
+	hasCond     bool
+	cond        Condition
+	in          *inList
+	isArray     bool
+	err         error
 }
 
 // orderByClause represents an ORDER BY clause.
+// If raw is set, column is a Raw expression rather than a column name.
 type orderByClause struct {
 	column    string
 	direction pagination.SortDirection
+	raw       bool
+	rank      bool
+
+	distanceFrom *Point
 }
 
 // QueryBuilder provides common functionality for all query builders.
 type QueryBuilder struct {
 	allowedColumns map[string]struct{}
+	arrayThreshold int
 }
 
 // NewQueryBuilder creates a new QueryBuilder with optional column allowlist.
@@ -60,52 +79,64 @@ func NewQueryBuilder(allowedColumns ...string) *QueryBuilder {
 }
 
 // validateColumnName checks if a column name is valid and allowed.
-// When an allowlist is provided, the column must be in the allowlist.
-// When no allowlist is provided, only basic validation is performed for table/column names.
+// Column names may be qualified and quoted, e.g. trips."order"; expressions
+// such as COUNT(*) must be passed as Raw instead.
+// When an allowlist is provided, the column must also be in the allowlist.
 func (qb *QueryBuilder) validateColumnName(column string) error {
 	if column == "" {
 		return fmt.Errorf("column name cannot be empty")
 	}
-	// If an allowlist is provided, enforce strict validation
+	if err := validateColumnIdentifier(column); err != nil {
+		return err
+	}
 	if len(qb.allowedColumns) > 0 {
-		if !columnNameRegex.MatchString(column) {
-			return fmt.Errorf("invalid column name: %s", column)
-		}
 		if _, ok := qb.allowedColumns[column]; !ok {
 			return fmt.Errorf("column not in allowlist: %s", column)
 		}
 	}
-	// Without an allowlist, allow expressions like COUNT(*), SUM(x), etc.
-	// SQL injection is prevented by parameterized queries for values.
 	return nil
 }
 
 // validateTableName checks if a table name is valid.
+// Table names may be schema-qualified and quoted, e.g. public."Trips".
 func validateTableName(table string) error {
 	if table == "" {
 		return fmt.Errorf("table name cannot be empty")
 	}
-	if !columnNameRegex.MatchString(table) {
+	if _, err := parseIdentifier(table, maxTableParts); err != nil {
 		return fmt.Errorf("invalid table name: %s", table)
 	}
 	return nil
 }
 
+// selectExpr represents a SELECT list expression with ? placeholders.
+type selectExpr struct {
+	expr string
+	args []any
+}
+
 // SelectBuilder builds SELECT queries.
 type SelectBuilder struct {
 	*QueryBuilder
-	table     string
-	columns   []string
-	distinct  bool
-	where     []whereClause
-	joins     []join
-	orderBy   []orderByClause
-	limit     *int
-	offset    *int
-	groupBy   []string
-	having    []whereClause
-	forUpdate bool
-	forShare  bool
+	with     withClause
+	table    string
+	from     *SelectBuilder
+	columns  []columnRef
+	distinct bool
+	where    []whereClause
+	joins    []join
+	orderBy  []orderByClause
+	limit    *int
+	offset   *int
+	keyset   *keysetPage
+	groupBy  []columnRef
+	having   []whereClause
+	locks    []LockClause
+
+	textSearch    *textSearch
+	textRank      string
+	textHeadlines []textHeadline
+	distances     []distanceColumn
 }
 
 // Select creates a new SelectBuilder for the specified table.
@@ -113,11 +144,11 @@ func Select(table string) *SelectBuilder {
 	return &SelectBuilder{
 		QueryBuilder: NewQueryBuilder(),
 		table:        table,
-		columns:      []string{},
+		columns:      []columnRef{},
 		where:        []whereClause{},
 		joins:        []join{},
 		orderBy:      []orderByClause{},
-		groupBy:      []string{},
+		groupBy:      []columnRef{},
 		having:       []whereClause{},
 	}
 }
@@ -127,18 +158,26 @@ func SelectWithAllowlist(table string, allowedColumns ...string) *SelectBuilder
 	return &SelectBuilder{
 		QueryBuilder: NewQueryBuilder(allowedColumns...),
 		table:        table,
-		columns:      []string{},
+		columns:      []columnRef{},
 		where:        []whereClause{},
 		joins:        []join{},
 		orderBy:      []orderByClause{},
-		groupBy:      []string{},
+		groupBy:      []columnRef{},
 		having:       []whereClause{},
 	}
 }
 
 // Columns specifies which columns to select.
+// Names are quoted as needed, so reserved words such as order can be selected;
+// use ColumnsRaw for expressions.
 func (s *SelectBuilder) Columns(columns ...string) *SelectBuilder {
-	s.columns = append(s.columns, columns...)
+	s.columns = append(s.columns, columnRefs(columns)...)
+	return s
+}
+
+// ColumnsRaw adds expressions to the selected columns, e.g. Raw("COUNT(*) AS trips").
+func (s *SelectBuilder) ColumnsRaw(exprs ...Raw) *SelectBuilder {
+	s.columns = append(s.columns, rawColumnRefs(exprs)...)
 	return s
 }
 
@@ -166,12 +205,7 @@ func (s *SelectBuilder) WhereIn(column string, values ...any) *SelectBuilder {
 	if len(values) == 0 {
 		return s
 	}
-	placeholders := make([]string, len(values))
-	for i := range values {
-		placeholders[i] = "?"
-	}
-	condition := fmt.Sprintf("%s IN (%s)", column, strings.Join(placeholders, ", "))
-	s.where = append(s.where, whereClause{condition: condition, args: values, isOr: false})
+	s.where = append(s.where, newInClause(column, values, false, &s.arrayThreshold))
 	return s
 }
 
@@ -180,47 +214,81 @@ func (s *SelectBuilder) WhereNotIn(column string, values ...any) *SelectBuilder
 	if len(values) == 0 {
 		return s
 	}
-	placeholders := make([]string, len(values))
-	for i := range values {
-		placeholders[i] = "?"
-	}
-	condition := fmt.Sprintf("%s NOT IN (%s)", column, strings.Join(placeholders, ", "))
-	s.where = append(s.where, whereClause{condition: condition, args: values, isOr: false})
+	s.where = append(s.where, newInClause(column, values, true, &s.arrayThreshold))
 	return s
 }
 
 // WhereLike adds a WHERE column LIKE pattern condition.
 func (s *SelectBuilder) WhereLike(column, pattern string) *SelectBuilder {
-	condition := fmt.Sprintf("%s LIKE ?", column)
-	s.where = append(s.where, whereClause{condition: condition, args: []any{pattern}, isOr: false})
+	s.where = append(s.where, newColumnClause(column, "LIKE ?", pattern))
 	return s
 }
 
 // WhereILike adds a WHERE column ILIKE pattern condition (case-insensitive).
 func (s *SelectBuilder) WhereILike(column, pattern string) *SelectBuilder {
-	condition := fmt.Sprintf("%s ILIKE ?", column)
-	s.where = append(s.where, whereClause{condition: condition, args: []any{pattern}, isOr: false})
+	s.where = append(s.where, newColumnClause(column, "ILIKE ?", pattern))
 	return s
 }
 
 // WhereNull adds a WHERE column IS NULL condition.
 func (s *SelectBuilder) WhereNull(column string) *SelectBuilder {
-	condition := fmt.Sprintf("%s IS NULL", column)
-	s.where = append(s.where, whereClause{condition: condition, args: nil, isOr: false})
+	s.where = append(s.where, newColumnClause(column, "IS NULL"))
 	return s
 }
 
 // WhereNotNull adds a WHERE column IS NOT NULL condition.
 func (s *SelectBuilder) WhereNotNull(column string) *SelectBuilder {
-	condition := fmt.Sprintf("%s IS NOT NULL", column)
-	s.where = append(s.where, whereClause{condition: condition, args: nil, isOr: false})
+	s.where = append(s.where, newColumnClause(column, "IS NOT NULL"))
 	return s
 }
 
 // WhereBetween adds a WHERE column BETWEEN minVal AND maxVal condition.
 func (s *SelectBuilder) WhereBetween(column string, minVal, maxVal any) *SelectBuilder {
-	condition := fmt.Sprintf("%s BETWEEN ? AND ?", column)
-	s.where = append(s.where, whereClause{condition: condition, args: []any{minVal, maxVal}, isOr: false})
+	s.where = append(s.where, newColumnClause(column, "BETWEEN ? AND ?", minVal, maxVal))
+	return s
+}
+
+// WhereExists adds a WHERE EXISTS (subquery) condition.
+// The subquery's arguments are merged and its placeholders renumbered.
+func (s *SelectBuilder) WhereExists(subquery *SelectBuilder) *SelectBuilder {
+	s.where = append(s.where, whereClause{condition: "EXISTS ", hasSubquery: true, subquery: subquery})
+	return s
+}
+
+// WhereNotExists adds a WHERE NOT EXISTS (subquery) condition.
+func (s *SelectBuilder) WhereNotExists(subquery *SelectBuilder) *SelectBuilder {
+	s.where = append(s.where, whereClause{condition: "NOT EXISTS ", hasSubquery: true, subquery: subquery})
+	return s
+}
+
+// WhereInSubquery adds a WHERE column IN (subquery) condition.
+func (s *SelectBuilder) WhereInSubquery(column string, subquery *SelectBuilder) *SelectBuilder {
+	w := newColumnClause(column, "IN ")
+	w.hasSubquery = true
+	w.subquery = subquery
+	s.where = append(s.where, w)
+	return s
+}
+
+// newColumnClause creates a where clause applying operator to a column name,
+// e.g. email IS NULL. An invalid column name fails validation.
+func newColumnClause(column, operator string, args ...any) whereClause {
+	return whereClause{
+		condition: quoteIdent(column) + " " + operator,
+		args:      args,
+		err:       validateColumnIdentifier(column),
+	}
+}
+
+// FromSubquery selects from a subquery instead of a table: FROM (subquery) AS alias.
+// The subquery's arguments come before all other arguments.
+func (s *SelectBuilder) FromSubquery(alias string, subquery *SelectBuilder) *SelectBuilder {
+	if subquery == nil {
+		// Leave the table empty so Build reports the missing subquery.
+		alias = ""
+	}
+	s.table = alias
+	s.from = subquery
 	return s
 }
 
@@ -248,6 +316,12 @@ func (s *SelectBuilder) OrderBy(column string, direction pagination.SortDirectio
 	return s
 }
 
+// OrderByRaw adds an ORDER BY clause on an expression, e.g. Raw("lower(name)").
+func (s *SelectBuilder) OrderByRaw(expr Raw, direction pagination.SortDirection) *SelectBuilder {
+	s.orderBy = append(s.orderBy, orderByClause{column: string(expr), direction: direction, raw: true})
+	return s
+}
+
 // OrderByAsc adds an ascending ORDER BY clause.
 func (s *SelectBuilder) OrderByAsc(column string) *SelectBuilder {
 	return s.OrderBy(column, pagination.SortAsc)
@@ -286,7 +360,13 @@ func (s *SelectBuilder) Page(req pagination.PageRequest) *SelectBuilder {
 
 // GroupBy adds GROUP BY columns.
 func (s *SelectBuilder) GroupBy(columns ...string) *SelectBuilder {
-	s.groupBy = append(s.groupBy, columns...)
+	s.groupBy = append(s.groupBy, columnRefs(columns)...)
+	return s
+}
+
+// GroupByRaw adds GROUP BY expressions, e.g. Raw("date_trunc('day', created_at)").
+func (s *SelectBuilder) GroupByRaw(exprs ...Raw) *SelectBuilder {
+	s.groupBy = append(s.groupBy, rawColumnRefs(exprs)...)
 	return s
 }
 
@@ -297,21 +377,28 @@ func (s *SelectBuilder) Having(condition string, args ...any) *SelectBuilder {
 }
 
 // ForUpdate adds FOR UPDATE locking.
+// Use Of, NoWait and SkipLocked to refine it.
 func (s *SelectBuilder) ForUpdate() *SelectBuilder {
-	s.forUpdate = true
-	s.forShare = false
+	s.locks = []LockClause{{Strength: LockForUpdate}}
 	return s
 }
 
 // ForShare adds FOR SHARE locking.
 func (s *SelectBuilder) ForShare() *SelectBuilder {
-	s.forShare = true
-	s.forUpdate = false
+	s.locks = []LockClause{{Strength: LockForShare}}
 	return s
 }
 
+// validate implements Builder.
+func (s *SelectBuilder) validate() error {
+	return s.validateSelect()
+}
+
 // validateSelect checks that the select has valid table and columns.
 func (s *SelectBuilder) validateSelect() error {
+	if err := s.with.validate(); err != nil {
+		return err
+	}
 	if err := validateTableName(s.table); err != nil {
 		return err
 	}
@@ -324,16 +411,84 @@ func (s *SelectBuilder) validateSelect() error {
 	if err := s.validateOrderByColumns(); err != nil {
 		return err
 	}
+	if err := s.validateSubqueries(); err != nil {
+		return err
+	}
+	if err := s.validateKeyset(); err != nil {
+		return err
+	}
+	if err := s.validateTextSearch(); err != nil {
+		return err
+	}
+	if err := s.validateGeo(); err != nil {
+		return err
+	}
+	if err := s.validateLocks(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// validateSubqueries validates the FROM and WHERE subqueries.
+func (s *SelectBuilder) validateSubqueries() error {
+	if s.from != nil {
+		if err := s.from.validateSelect(); err != nil {
+			return fmt.Errorf("invalid subquery %s: %w", s.table, err)
+		}
+	}
+	if err := validateConditionClauses(s.where); err != nil {
+		return err
+	}
+	return validateConditionClauses(s.having)
+}
+
+// validateConditionClauses validates the subqueries and condition trees of condition clauses.
+func validateConditionClauses(clauses []whereClause) error {
+	for _, w := range clauses {
+		if w.err != nil {
+			return w.err
+		}
+		if w.hasCond {
+			if w.cond == nil {
+				return fmt.Errorf("condition cannot be nil")
+			}
+			if err := w.cond.validate(); err != nil {
+				return fmt.Errorf("invalid condition: %w", err)
+			}
+			continue
+		}
+		if w.isArray {
+			if err := validateArrayArg(w); err != nil {
+				return err
+			}
+			continue
+		}
+		if !w.hasSubquery {
+			continue
+		}
+		if w.subquery == nil {
+			return fmt.Errorf("subquery cannot be nil in %q", strings.TrimSpace(w.condition))
+		}
+		if err := w.subquery.validateSelect(); err != nil {
+			return fmt.Errorf("invalid subquery in %q: %w", strings.TrimSpace(w.condition), err)
+		}
+	}
 	return nil
 }
 
 // validateSelectColumns validates the SELECT columns.
 func (s *SelectBuilder) validateSelectColumns() error {
 	for _, col := range s.columns {
-		if col == "*" {
+		if col.raw || col.name == "*" {
 			continue
 		}
-		if err := s.validateColumnName(col); err != nil {
+		if table, ok := strings.CutSuffix(col.name, ".*"); ok {
+			if err := validateTableName(table); err != nil {
+				return fmt.Errorf("invalid column name: %s", col.name)
+			}
+			continue
+		}
+		if err := s.validateColumnName(col.name); err != nil {
 			return err
 		}
 	}
@@ -343,12 +498,11 @@ func (s *SelectBuilder) validateSelectColumns() error {
 // validateGroupByColumns validates the GROUP BY entries.
 func (s *SelectBuilder) validateGroupByColumns() error {
 	for _, entry := range s.groupBy {
-		entry = strings.TrimSpace(entry)
-		if entry == "" || entry == "*" {
+		if entry.raw {
 			continue
 		}
-		if err := s.validateColumnName(entry); err != nil {
-			return fmt.Errorf("invalid group by column: %q", entry)
+		if err := s.validateColumnName(entry.name); err != nil {
+			return fmt.Errorf("invalid group by column: %q", entry.name)
 		}
 	}
 	return nil
@@ -357,10 +511,10 @@ func (s *SelectBuilder) validateGroupByColumns() error {
 // validateOrderByColumns validates the ORDER BY entries.
 func (s *SelectBuilder) validateOrderByColumns() error {
 	for _, entry := range s.orderBy {
-		col := strings.TrimSpace(entry.column)
-		if col == "" || col == "*" {
+		if entry.raw || entry.rank {
 			continue
 		}
+		col := entry.column
 		if err := s.validateColumnName(col); err != nil {
 			return fmt.Errorf("invalid order by column: %q", col)
 		}
@@ -368,8 +522,8 @@ func (s *SelectBuilder) validateOrderByColumns() error {
 	return nil
 }
 
-// buildSelectClause generates the SELECT portion.
-func (s *SelectBuilder) buildSelectClause() string {
+// buildSelectClause generates the SELECT portion and returns args and next arg index.
+func (s *SelectBuilder) buildSelectClause(argIndex int) (string, []any, int) {
 	var sb strings.Builder
 	sb.WriteString("SELECT ")
 	if s.distinct {
@@ -378,11 +532,32 @@ func (s *SelectBuilder) buildSelectClause() string {
 	if len(s.columns) == 0 {
 		sb.WriteString("*")
 	} else {
-		sb.WriteString(strings.Join(s.columns, ", "))
+		sb.WriteString(joinColumnRefs(s.columns))
+	}
+	var args []any
+	for _, e := range s.selectExprs() {
+		var exprSQL string
+		exprSQL, argIndex = replacePlaceholders(e.expr, argIndex)
+		sb.WriteString(", ")
+		sb.WriteString(exprSQL)
+		args = append(args, e.args...)
 	}
 	sb.WriteString(" FROM ")
-	sb.WriteString(s.table)
-	return sb.String()
+	if s.from == nil {
+		sb.WriteString(quoteIdent(s.table))
+		return sb.String(), args, argIndex
+	}
+	fromSQL, fromArgs, argIndex := s.from.build(argIndex)
+	sb.WriteString("(")
+	sb.WriteString(fromSQL)
+	sb.WriteString(") AS ")
+	sb.WriteString(quoteIdent(s.table))
+	return sb.String(), append(args, fromArgs...), argIndex
+}
+
+// selectExprs returns the computed SELECT list expressions that follow the columns.
+func (s *SelectBuilder) selectExprs() []selectExpr {
+	return append(s.textSearchExprs(), s.distanceExprs()...)
 }
 
 // buildJoinClauses generates the JOIN portions and returns args and next arg index.
@@ -396,7 +571,7 @@ func (s *SelectBuilder) buildJoinClauses(argIndex int) (string, []any, int) {
 		sb.WriteString(" ")
 		sb.WriteString(string(j.joinType))
 		sb.WriteString(" ")
-		sb.WriteString(j.table)
+		sb.WriteString(quoteIdent(j.table))
 		sb.WriteString(" ON ")
 		joinCondition, newIndex := replacePlaceholders(j.condition, argIndex)
 		sb.WriteString(joinCondition)
@@ -422,51 +597,171 @@ func buildConditionClauses(clauses []whereClause, keyword string, argIndex int)
 				sb.WriteString(" AND ")
 			}
 		}
-		condition, newIndex := replacePlaceholders(w.condition, argIndex)
+		if w.hasCond {
+			condSQL, condArgs, newIndex := buildOperand(w.cond, argIndex)
+			sb.WriteString(condSQL)
+			args = append(args, condArgs...)
+			argIndex = newIndex
+			continue
+		}
+		condition, condArgs := w.condition, w.args
+		if w.in != nil {
+			condition, condArgs = w.in.render()
+		}
+		// Raw conditions with boolean operators are grouped so that their
+		// operators do not bind to the neighbouring clauses.
+		grouped := len(clauses) > 1 && booleanOperatorRegex.MatchString(condition)
+		if grouped {
+			sb.WriteString("(")
+		}
+		condition, newIndex := replacePlaceholders(condition, argIndex)
 		sb.WriteString(condition)
-		args = append(args, w.args...)
+		args = append(args, condArgs...)
 		argIndex = newIndex
+		if w.hasSubquery {
+			subSQL, subArgs, newIndex := w.subquery.build(argIndex)
+			sb.WriteString("(")
+			sb.WriteString(subSQL)
+			sb.WriteString(")")
+			args = append(args, subArgs...)
+			argIndex = newIndex
+		}
+		if grouped {
+			sb.WriteString(")")
+		}
 	}
 	return sb.String(), args, argIndex
 }
 
+// validateJoinTables validates the tables of FROM or USING clauses.
+func validateJoinTables(joins []join, keyword string) error {
+	for _, j := range joins {
+		if err := validateTableName(j.table); err != nil {
+			return fmt.Errorf("invalid %s table: %w", keyword, err)
+		}
+	}
+	return nil
+}
+
+// buildJoinTableList generates a FROM or USING table list, e.g. " FROM a, b".
+func buildJoinTableList(joins []join, keyword string) string {
+	if len(joins) == 0 {
+		return ""
+	}
+	tables := make([]string, len(joins))
+	for i, j := range joins {
+		tables[i] = quoteIdent(j.table)
+	}
+	return " " + keyword + " " + strings.Join(tables, ", ")
+}
+
+// hasJoinConditions reports whether any FROM or USING table has a join condition.
+func hasJoinConditions(joins []join) bool {
+	for _, j := range joins {
+		if j.condition != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// buildJoinedWhere generates a WHERE clause from FROM or USING join conditions
+// followed by the where clauses, and returns args and next arg index.
+// The where clauses are grouped, so that an OR, including one inside a raw
+// condition, cannot weaken the join conditions.
+func buildJoinedWhere(joins []join, where []whereClause, argIndex int) (string, []any, int) {
+	var parts []string
+	var args []any
+	for _, j := range joins {
+		if j.condition == "" {
+			continue
+		}
+		condition, newIndex := replacePlaceholders(j.condition, argIndex)
+		parts = append(parts, condition)
+		args = append(args, j.args...)
+		argIndex = newIndex
+	}
+
+	if len(parts) > 0 && len(where) > 0 {
+		where = []whereClause{{hasCond: true, cond: &ConditionGroup{clauses: where}}}
+	}
+	whereSQL, whereArgs, argIndex := buildConditionClauses(where, "", argIndex)
+	if whereSQL != "" {
+		parts = append(parts, whereSQL)
+		args = append(args, whereArgs...)
+	}
+
+	if len(parts) == 0 {
+		return "", nil, argIndex
+	}
+	return " WHERE " + strings.Join(parts, " AND "), args, argIndex
+}
+
 // buildOrderByClause generates the ORDER BY portion.
 func (s *SelectBuilder) buildOrderByClause() string {
-	if len(s.orderBy) == 0 {
+	orderBy := slices.Clone(s.keysetOrderBy())
+	for i, o := range orderBy {
+		if o.rank {
+			orderBy[i].column = s.textRank
+			orderBy[i].raw = true
+		}
+		if o.distanceFrom != nil {
+			orderBy[i].column = distanceOrderExpr(o.column, *o.distanceFrom)
+			orderBy[i].raw = true
+		}
+	}
+	return buildOrderByClauses(orderBy)
+}
+
+// buildOrderByClauses generates an ORDER BY portion from orderByClause slice.
+func buildOrderByClauses(orderBy []orderByClause) string {
+	if len(orderBy) == 0 {
 		return ""
 	}
-	orderParts := make([]string, len(s.orderBy))
-	for i, o := range s.orderBy {
+	orderParts := make([]string, len(orderBy))
+	for i, o := range orderBy {
 		dir := strings.ToUpper(string(o.direction))
 		if dir == "" {
 			dir = "ASC"
 		}
-		orderParts[i] = fmt.Sprintf("%s %s", o.column, dir)
+		column := o.column
+		if !o.raw {
+			column = quoteIdent(column)
+		}
+		orderParts[i] = column + " " + dir
 	}
 	return " ORDER BY " + strings.Join(orderParts, ", ")
 }
 
 // buildLimitOffsetClause generates the LIMIT and OFFSET portions.
 func (s *SelectBuilder) buildLimitOffsetClause() string {
+	if s.keyset != nil {
+		limit := s.keyset.limit + 1
+		return buildLimitOffset(&limit, nil)
+	}
+	return buildLimitOffset(s.limit, s.offset)
+}
+
+// buildLimitOffset generates LIMIT and OFFSET portions for the given values.
+func buildLimitOffset(limit, offset *int) string {
 	var sb strings.Builder
-	if s.limit != nil {
-		sb.WriteString(fmt.Sprintf(" LIMIT %d", *s.limit))
+	if limit != nil {
+		sb.WriteString(fmt.Sprintf(" LIMIT %d", *limit))
 	}
-	if s.offset != nil {
-		sb.WriteString(fmt.Sprintf(" OFFSET %d", *s.offset))
+	if offset != nil {
+		sb.WriteString(fmt.Sprintf(" OFFSET %d", *offset))
 	}
 	return sb.String()
 }
 
 // buildLockingClause generates the FOR UPDATE/FOR SHARE portion.
 func (s *SelectBuilder) buildLockingClause() string {
-	if s.forUpdate {
-		return " FOR UPDATE"
-	}
-	if s.forShare {
-		return " FOR SHARE"
+	var sb strings.Builder
+	for _, l := range s.locks {
+		sb.WriteString(" ")
+		sb.WriteString(l.String())
 	}
-	return ""
+	return sb.String()
 }
 
 // Build generates the SQL query and returns it with the arguments.
@@ -474,27 +769,39 @@ func (s *SelectBuilder) Build() (string, []any, error) {
 	if err := s.validateSelect(); err != nil {
 		return "", nil, err
 	}
+	sql, args, _ := s.build(1)
+	return sql, args, nil
+}
 
+// build generates the SQL with placeholders numbered from argIndex,
+// returning the arguments and the next arg index.
+// The builder must have been validated.
+func (s *SelectBuilder) build(argIndex int) (string, []any, int) {
 	var sb strings.Builder
 	args := make([]any, 0, len(s.joins)+len(s.where)+len(s.having))
-	argIndex := 1
 
-	sb.WriteString(s.buildSelectClause())
+	withClause, withArgs, argIndex := s.with.build(argIndex)
+	sb.WriteString(withClause)
+	args = append(args, withArgs...)
+
+	selectClause, selectArgs, argIndex := s.buildSelectClause(argIndex)
+	sb.WriteString(selectClause)
+	args = append(args, selectArgs...)
 
 	joinClause, joinArgs, argIndex := s.buildJoinClauses(argIndex)
 	sb.WriteString(joinClause)
 	args = append(args, joinArgs...)
 
-	whereClause, whereArgs, argIndex := buildConditionClauses(s.where, " WHERE ", argIndex)
+	whereClause, whereArgs, argIndex := buildConditionClauses(s.keysetWhere(), " WHERE ", argIndex)
 	sb.WriteString(whereClause)
 	args = append(args, whereArgs...)
 
 	if len(s.groupBy) > 0 {
 		sb.WriteString(" GROUP BY ")
-		sb.WriteString(strings.Join(s.groupBy, ", "))
+		sb.WriteString(joinColumnRefs(s.groupBy))
 	}
 
-	havingClause, havingArgs, _ := buildConditionClauses(s.having, " HAVING ", argIndex)
+	havingClause, havingArgs, argIndex := buildConditionClauses(s.having, " HAVING ", argIndex)
 	sb.WriteString(havingClause)
 	args = append(args, havingArgs...)
 
@@ -502,7 +809,7 @@ func (s *SelectBuilder) Build() (string, []any, error) {
 	sb.WriteString(s.buildLimitOffsetClause())
 	sb.WriteString(s.buildLockingClause())
 
-	return sb.String(), args, nil
+	return sb.String(), args, argIndex
 }
 
 // MustBuild generates the SQL query and panics on error.
@@ -535,16 +842,83 @@ func (s *SelectBuilder) Args() []any {
 }
 
 // replacePlaceholders replaces ? placeholders with $1, $2, etc.
+// Question marks inside string literals, quoted identifiers, dollar-quoted
+// strings and comments are left unchanged, and ?? is an escaped literal ?,
+// so JSONB operators are written as ??, ??| and ??&.
 func replacePlaceholders(condition string, startIndex int) (string, int) {
 	var result strings.Builder
 	index := startIndex
-	for _, ch := range condition {
-		if ch == '?' {
+	for i := 0; i < len(condition); {
+		ch := condition[i]
+		switch {
+		case ch == '?' && i+1 < len(condition) && condition[i+1] == '?':
+			result.WriteByte('?')
+			i += 2
+		case ch == '?':
 			result.WriteString(fmt.Sprintf("$%d", index))
 			index++
-		} else {
-			result.WriteRune(ch)
+			i++
+		case ch == '\'' || ch == '"':
+			escapes := ch == '\'' && i > 0 && (condition[i-1] == 'E' || condition[i-1] == 'e') &&
+				(i == 1 || !isIdentifierByte(condition[i-2]))
+			n := quotedLength(condition[i:], ch, escapes)
+			result.WriteString(condition[i : i+n])
+			i += n
+		case ch == '$' && dollarQuoteRegex.MatchString(condition[i:]):
+			tag := dollarQuoteRegex.FindString(condition[i:])
+			n := strings.Index(condition[i+len(tag):], tag)
+			if n < 0 {
+				n = len(condition) - i - len(tag)
+			} else {
+				n += len(tag)
+			}
+			result.WriteString(condition[i : i+len(tag)+n])
+			i += len(tag) + n
+		case strings.HasPrefix(condition[i:], "--"):
+			n := strings.IndexByte(condition[i:], '\n')
+			if n < 0 {
+				n = len(condition) - i
+			}
+			result.WriteString(condition[i : i+n])
+			i += n
+		case strings.HasPrefix(condition[i:], "/*"):
+			n := strings.Index(condition[i+2:], "*/")
+			if n < 0 {
+				n = len(condition) - i
+			} else {
+				n += 4
+			}
+			result.WriteString(condition[i : i+n])
+			i += n
+		default:
+			result.WriteByte(ch)
+			i++
 		}
 	}
 	return result.String(), index
 }
+
+// dollarQuoteRegex matches the opening tag of a dollar-quoted string, e.g. $$ or $body$.
+var dollarQuoteRegex = regexp.MustCompile(`^\$([A-Za-z_][A-Za-z0-9_]*)?\$`)
+
+// quotedLength returns the length of the quoted string or identifier at the start of s,
+// including the quotes. A doubled quote is part of the content, as is any character
+// after a backslash if escapes is set. An unterminated quote extends to the end of s.
+func quotedLength(s string, quote byte, escapes bool) int {
+	for i := 1; i < len(s); i++ {
+		switch {
+		case escapes && s[i] == '\\':
+			i++
+		case s[i] == quote && i+1 < len(s) && s[i+1] == quote:
+			i++
+		case s[i] == quote:
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
+// isIdentifierByte reports whether b can be part of an unquoted identifier.
+func isIdentifierByte(b byte) bool {
+	return b == '_' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9'
+}
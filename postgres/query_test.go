@@ -1,6 +1,7 @@
 package postgres
 
 import (
+	"reflect"
 	"strings"
 	"testing"
 
@@ -334,7 +335,7 @@ func TestSelectBuilder_GroupByHaving(t *testing.T) {
 	t.Parallel()
 
 	sql, args, err := Select("orders").
-		Columns("user_id", "COUNT(*) as order_count").
+		Columns("user_id").ColumnsRaw("COUNT(*) as order_count").
 		GroupBy("user_id").
 		Having("COUNT(*) > ?", 5).
 		Build()
@@ -381,6 +382,95 @@ func TestSelectBuilder_Locking(t *testing.T) {
 	})
 }
 
+func TestSelectBuilder_LockClauses(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		builder     Builder
+		wantSQL     string
+		wantArgs    []any
+		errContains string
+	}{
+		{
+			name:     "skip locked",
+			builder:  Select("jobs").Columns("id").Where("status = ?", "queued").Limit(10).ForUpdate().SkipLocked(),
+			wantSQL:  "SELECT id FROM jobs WHERE status = $1 LIMIT 10 FOR UPDATE SKIP LOCKED",
+			wantArgs: []any{"queued"},
+		},
+		{
+			name:     "nowait",
+			builder:  Select("trips").Columns("id").Where("id = ?", 1).ForNoKeyUpdate().NoWait(),
+			wantSQL:  "SELECT id FROM trips WHERE id = $1 FOR NO KEY UPDATE NOWAIT",
+			wantArgs: []any{1},
+		},
+		{
+			name:    "key share",
+			builder: Select("riders").Columns("id").ForKeyShare(),
+			wantSQL: "SELECT id FROM riders FOR KEY SHARE",
+		},
+		{
+			name: "of tables with join",
+			builder: Select("trips").Columns("trips.id").
+				Join("drivers", "drivers.id = trips.driver_id").
+				Where("trips.id = ?", 1).
+				ForUpdate().Of("trips").SkipLocked(),
+			wantSQL:  "SELECT trips.id FROM trips INNER JOIN drivers ON drivers.id = trips.driver_id WHERE trips.id = $1 FOR UPDATE OF trips SKIP LOCKED",
+			wantArgs: []any{1},
+		},
+		{
+			name: "multiple clauses",
+			builder: Select("trips").Columns("trips.id").
+				Join("drivers", "drivers.id = trips.driver_id").
+				Lock(LockClause{Strength: LockForUpdate, Of: []string{"trips"}, Wait: LockNoWait}).
+				Lock(LockClause{Strength: LockForShare, Of: []string{"drivers"}}),
+			wantSQL: "SELECT trips.id FROM trips INNER JOIN drivers ON drivers.id = trips.driver_id FOR UPDATE OF trips NOWAIT FOR SHARE OF drivers",
+		},
+		{
+			name:    "of quotes reserved table names",
+			builder: Select("order").ForUpdate().Of("order"),
+			wantSQL: `SELECT * FROM "order" FOR UPDATE OF "order"`,
+		},
+		{
+			name:    "of accepts qualified and quoted names",
+			builder: Select("public.trips").Join(`"Drivers"`, `"Drivers".id = trips.driver_id`).ForShare().Of("public.trips", `"Drivers"`),
+			wantSQL: `SELECT * FROM public.trips INNER JOIN "Drivers" ON "Drivers".id = trips.driver_id FOR SHARE OF trips, "Drivers"`,
+		},
+		{
+			name:    "shorthand replaces previous locking",
+			builder: Select("users").Columns("id").ForUpdate().SkipLocked().ForShare(),
+			wantSQL: "SELECT id FROM users FOR SHARE",
+		},
+		{
+			name:        "modifier without lock",
+			builder:     Select("jobs").SkipLocked(),
+			errContains: "lock modifier requires",
+		},
+		{
+			name:        "invalid of table",
+			builder:     Select("jobs").ForUpdate().Of("jobs; DROP"),
+			errContains: "invalid lock table",
+		},
+		{
+			name:        "invalid strength",
+			builder:     Select("jobs").Lock(LockClause{Strength: "EXCLUSIVE"}),
+			errContains: "invalid lock strength",
+		},
+		{
+			name:        "invalid wait",
+			builder:     Select("jobs").Lock(LockClause{Strength: LockForUpdate, Wait: "WAIT 5"}),
+			errContains: "invalid lock wait policy",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assertBuild(t, tt.builder, tt.wantSQL, tt.wantArgs, tt.errContains)
+		})
+	}
+}
+
 func TestSelectBuilder_Allowlist(t *testing.T) {
 	t.Parallel()
 
@@ -1138,6 +1228,18 @@ func TestReplacePlaceholders(t *testing.T) {
 		{"x = ? OR y = ?", 5, "x = $5 OR y = $6", 7},
 		{"no placeholders", 1, "no placeholders", 1},
 		{"? ? ?", 10, "$10 $11 $12", 13},
+		{"name = 'what?' AND id = ?", 1, "name = 'what?' AND id = $1", 2},
+		{"name = 'it''s?' OR x = ?", 1, "name = 'it''s?' OR x = $1", 2},
+		{`"odd?col" = ?`, 3, `"odd?col" = $3`, 4},
+		{`note = E'a\'?' AND id = ?`, 1, `note = E'a\'?' AND id = $1`, 2},
+		{"body = $$what?$$ AND id = ?", 1, "body = $$what?$$ AND id = $1", 2},
+		{"body = $tag$ $$? $tag$ AND id = ?", 1, "body = $tag$ $$? $tag$ AND id = $1", 2},
+		{"data ?? 'key' AND id = ?", 1, "data ? 'key' AND id = $1", 2},
+		{"data ??| ? AND data ??& ?", 1, "data ?| $1 AND data ?& $2", 3},
+		{"id = ? -- why?\nAND x = ?", 1, "id = $1 -- why?\nAND x = $2", 3},
+		{"id = ? /* really? */ AND x = ?", 1, "id = $1 /* really? */ AND x = $2", 3},
+		{"name = 'unterminated?", 1, "name = 'unterminated?", 1},
+		{"café = ?", 1, "café = $1", 2},
 	}
 
 	for _, tt := range tests {
@@ -1157,22 +1259,29 @@ func TestReplacePlaceholders(t *testing.T) {
 func TestValidateColumnName(t *testing.T) {
 	t.Parallel()
 
-	t.Run("without allowlist - lenient validation", func(t *testing.T) {
+	t.Run("without allowlist - identifiers only", func(t *testing.T) {
 		t.Parallel()
 		qb := NewQueryBuilder()
 
-		// Without allowlist, only empty column is rejected
 		if err := qb.validateColumnName(""); err == nil {
 			t.Error("empty column name should fail")
 		}
 
-		// Valid column names pass
-		validNames := []string{"id", "user_id", "COUNT(*)", "SUM(amount) as total"}
+		// Valid column names pass, including reserved words and quoted names
+		validNames := []string{"id", "user_id", "order", "users.id", "public.users.id", `"createdAt"`, `trips."Order"`, `"a""b"`}
 		for _, name := range validNames {
 			if err := qb.validateColumnName(name); err != nil {
 				t.Errorf("validateColumnName(%q) = %v, want nil", name, err)
 			}
 		}
+
+		// Expressions must use Raw
+		invalidNames := []string{"COUNT(*)", "SUM(amount) as total", `"unterminated`, `""`, "a.b.c.d", "users.", `"x"y`}
+		for _, name := range invalidNames {
+			if err := qb.validateColumnName(name); err == nil {
+				t.Errorf("validateColumnName(%q) = nil, want error", name)
+			}
+		}
 	})
 
 	t.Run("with allowlist - strict validation", func(t *testing.T) {
@@ -1247,3 +1356,1024 @@ func TestSelectBuilder_EmptyWhereInNotIn(t *testing.T) {
 		}
 	})
 }
+
+func TestSelectBuilder_Subqueries(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		builder     func() *SelectBuilder
+		wantSQL     string
+		wantArgs    []any
+		errContains string
+	}{
+		{
+			name: "where exists",
+			builder: func() *SelectBuilder {
+				return Select("drivers").
+					Columns("id").
+					Where("status = ?", "active").
+					WhereExists(Select("trips").ColumnsRaw("1").Where("trips.driver_id = drivers.id").Where("trips.status = ?", "ongoing"))
+			},
+			wantSQL:  "SELECT id FROM drivers WHERE status = $1 AND EXISTS (SELECT 1 FROM trips WHERE trips.driver_id = drivers.id AND trips.status = $2)",
+			wantArgs: []any{"active", "ongoing"},
+		},
+		{
+			name: "where not exists",
+			builder: func() *SelectBuilder {
+				return Select("riders").
+					WhereNotExists(Select("payments").ColumnsRaw("1").Where("payments.rider_id = riders.id").Where("payments.failed = ?", true)).
+					Where("region = ?", "maputo")
+			},
+			wantSQL:  "SELECT * FROM riders WHERE NOT EXISTS (SELECT 1 FROM payments WHERE payments.rider_id = riders.id AND payments.failed = $1) AND region = $2",
+			wantArgs: []any{true, "maputo"},
+		},
+		{
+			name: "where in subquery",
+			builder: func() *SelectBuilder {
+				return Select("users").
+					Columns("id", "name").
+					WhereInSubquery("id", Select("orders").Columns("user_id").Where("total > ?", 100)).
+					Limit(10)
+			},
+			wantSQL:  "SELECT id, name FROM users WHERE id IN (SELECT user_id FROM orders WHERE total > $1) LIMIT 10",
+			wantArgs: []any{100},
+		},
+		{
+			name: "from subquery",
+			builder: func() *SelectBuilder {
+				inner := Select("trips").
+					Columns("driver_id").ColumnsRaw("COUNT(*) AS trips").
+					Where("created_at > ?", "2024-01-01").
+					GroupBy("driver_id")
+				return Select("").
+					FromSubquery("t", inner).
+					Columns("driver_id").
+					Join("drivers", "drivers.id = t.driver_id AND drivers.region = ?", "beira").
+					Where("t.trips > ?", 5)
+			},
+			wantSQL:  "SELECT driver_id FROM (SELECT driver_id, COUNT(*) AS trips FROM trips WHERE created_at > $1 GROUP BY driver_id) AS t INNER JOIN drivers ON drivers.id = t.driver_id AND drivers.region = $2 WHERE t.trips > $3",
+			wantArgs: []any{"2024-01-01", "beira", 5},
+		},
+		{
+			name: "nested subqueries",
+			builder: func() *SelectBuilder {
+				return Select("a").
+					Where("x = ?", 1).
+					WhereInSubquery("id", Select("b").Columns("a_id").
+						WhereExists(Select("c").Where("c.b_id = b.id").Where("c.y = ?", 2))).
+					Where("z = ?", 3)
+			},
+			wantSQL:  "SELECT * FROM a WHERE x = $1 AND id IN (SELECT a_id FROM b WHERE EXISTS (SELECT * FROM c WHERE c.b_id = b.id AND c.y = $2)) AND z = $3",
+			wantArgs: []any{1, 2, 3},
+		},
+		{
+			name: "invalid subquery",
+			builder: func() *SelectBuilder {
+				return Select("users").WhereExists(Select("bad table"))
+			},
+			errContains: "invalid subquery",
+		},
+		{
+			name: "nil subquery",
+			builder: func() *SelectBuilder {
+				return Select("users").WhereExists(nil)
+			},
+			errContains: "subquery cannot be nil",
+		},
+		{
+			name: "nil from subquery",
+			builder: func() *SelectBuilder {
+				return Select("users").FromSubquery("u", nil)
+			},
+			errContains: "table name cannot be empty",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			sql, args, err := tt.builder().Build()
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("Build() error = %v, want error containing %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Build() error = %v", err)
+			}
+			if sql != tt.wantSQL {
+				t.Errorf("Build() SQL = %q, want %q", sql, tt.wantSQL)
+			}
+			if len(args) != len(tt.wantArgs) {
+				t.Fatalf("Build() args = %v, want %v", args, tt.wantArgs)
+			}
+			for i, arg := range args {
+				if arg != tt.wantArgs[i] {
+					t.Errorf("Build() args[%d] = %v, want %v", i, arg, tt.wantArgs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBuilders_With(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		builder     Builder
+		wantSQL     string
+		wantArgs    []any
+		errContains string
+	}{
+		{
+			name: "select with cte",
+			builder: Select("recent").
+				With("recent", Select("trips").Columns("id", "driver_id").Where("created_at > ?", "2024-06-01")).
+				Columns("driver_id").ColumnsRaw("COUNT(*)").
+				Where("driver_id <> ?", 7).
+				GroupBy("driver_id"),
+			wantSQL:  "WITH recent AS (SELECT id, driver_id FROM trips WHERE created_at > $1) SELECT driver_id, COUNT(*) FROM recent WHERE driver_id <> $2 GROUP BY driver_id",
+			wantArgs: []any{"2024-06-01", 7},
+		},
+		{
+			name: "multiple ctes",
+			builder: Select("a").
+				With("a", Select("x").Where("k = ?", 1)).
+				With("b", Select("y").Where("k = ?", 2)).
+				Join("b", "b.id = a.id").
+				Where("a.v = ?", 3),
+			wantSQL:  "WITH a AS (SELECT * FROM x WHERE k = $1), b AS (SELECT * FROM y WHERE k = $2) SELECT * FROM a INNER JOIN b ON b.id = a.id WHERE a.v = $3",
+			wantArgs: []any{1, 2, 3},
+		},
+		{
+			name: "recursive cte",
+			builder: Select("tree").
+				WithRecursive("tree", Select("referrals").Columns("id", "referrer_id").Where("referrer_id = ?", 42)).
+				Columns("id"),
+			wantSQL:  "WITH RECURSIVE tree AS (SELECT id, referrer_id FROM referrals WHERE referrer_id = $1) SELECT id FROM tree",
+			wantArgs: []any{42},
+		},
+		{
+			name: "recursive cte with union and column list",
+			builder: Select("tree").
+				WithRecursive("tree", UnionAll(
+					Select("referrals").Columns("id", "referrer_id").ColumnsRaw("1").Where("referrer_id = ?", 42),
+					Select("referrals").Columns("referrals.id", "referrals.referrer_id").ColumnsRaw("tree.depth + 1").
+						Join("tree", "referrals.referrer_id = tree.id").
+						Where("tree.depth < ?", 5),
+				), "id", "parent_id", "depth").
+				Columns("id", "depth").
+				Where("depth > ?", 1),
+			wantSQL: "WITH RECURSIVE tree(id, parent_id, depth) AS (" +
+				"SELECT id, referrer_id, 1 FROM referrals WHERE referrer_id = $1 UNION ALL " +
+				"SELECT referrals.id, referrals.referrer_id, tree.depth + 1 FROM referrals INNER JOIN tree ON referrals.referrer_id = tree.id WHERE tree.depth < $2" +
+				") SELECT id, depth FROM tree WHERE depth > $3",
+			wantArgs: []any{42, 5, 1},
+		},
+		{
+			name: "data modifying cte",
+			builder: Insert("trips_archive").
+				With("moved", Delete("trips").Where("completed_at < ?", "2023-01-01").Returning("*")).
+				Columns("id").
+				Values(1),
+			wantSQL:  "WITH moved AS (DELETE FROM trips WHERE completed_at < $1 RETURNING *) INSERT INTO trips_archive (id) VALUES ($2)",
+			wantArgs: []any{"2023-01-01", 1},
+		},
+		{
+			name: "update with cte",
+			builder: Update("drivers").
+				With("inserted", Insert("ratings").Columns("driver_id", "score").Values(7, 5).Returning("driver_id")).
+				Set("rated", true).
+				Where("id IN (SELECT driver_id FROM inserted)").
+				Where("status = ?", "active"),
+			wantSQL:  "WITH inserted AS (INSERT INTO ratings (driver_id, score) VALUES ($1, $2) RETURNING driver_id) UPDATE drivers SET rated = $3 WHERE id IN (SELECT driver_id FROM inserted) AND status = $4",
+			wantArgs: []any{7, 5, true, "active"},
+		},
+		{
+			name: "delete with cte",
+			builder: Delete("sessions").
+				With("expired", Update("users").Set("active", false).Where("last_seen < ?", "2024-01-01").Returning("id")).
+				Where("user_id IN (SELECT id FROM expired)").
+				Where("kind = ?", "web"),
+			wantSQL:  "WITH expired AS (UPDATE users SET active = $1 WHERE last_seen < $2 RETURNING id) DELETE FROM sessions WHERE user_id IN (SELECT id FROM expired) AND kind = $3",
+			wantArgs: []any{false, "2024-01-01", "web"},
+		},
+		{
+			name:        "invalid cte name",
+			builder:     Select("x").With("bad name", Select("y")),
+			errContains: "invalid cte name",
+		},
+		{
+			name:        "duplicate cte name",
+			builder:     Select("x").With("x", Select("y")).With("x", Select("z")),
+			errContains: "duplicate cte name",
+		},
+		{
+			name:        "invalid cte query",
+			builder:     Select("x").With("x", Delete("y")),
+			errContains: "invalid cte x",
+		},
+		{
+			name:        "qualified cte column",
+			builder:     Select("x").With("x", Select("y"), "y.id"),
+			errContains: "invalid column name in cte x",
+		},
+		{
+			name:        "nil cte query",
+			builder:     Select("x").With("x", nil),
+			errContains: "has no query",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			sql, args, err := tt.builder.Build()
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("Build() error = %v, want error containing %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Build() error = %v", err)
+			}
+			if sql != tt.wantSQL {
+				t.Errorf("Build() SQL = %q, want %q", sql, tt.wantSQL)
+			}
+			if len(args) != len(tt.wantArgs) {
+				t.Fatalf("Build() args = %v, want %v", args, tt.wantArgs)
+			}
+			for i, arg := range args {
+				if arg != tt.wantArgs[i] {
+					t.Errorf("Build() args[%d] = %v, want %v", i, arg, tt.wantArgs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCompoundSelectBuilder(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		builder     *CompoundSelectBuilder
+		wantSQL     string
+		wantArgs    []any
+		errContains string
+	}{
+		{
+			name: "union all with order and limit",
+			builder: UnionAll(
+				Select("rider_notifications").Columns("id", "created_at").Where("rider_id = ?", 1),
+				Select("driver_notifications").Columns("id", "created_at").Where("driver_id = ?", 2),
+			).OrderByDesc("created_at").Limit(20).Offset(40),
+			wantSQL:  "SELECT id, created_at FROM rider_notifications WHERE rider_id = $1 UNION ALL SELECT id, created_at FROM driver_notifications WHERE driver_id = $2 ORDER BY created_at DESC LIMIT 20 OFFSET 40",
+			wantArgs: []any{1, 2},
+		},
+		{
+			name: "union",
+			builder: Union(
+				Select("a").Columns("x"),
+				Select("b").Columns("x"),
+				Select("c").Columns("x").Where("y = ?", 3),
+			),
+			wantSQL:  "SELECT x FROM a UNION SELECT x FROM b UNION SELECT x FROM c WHERE y = $1",
+			wantArgs: []any{3},
+		},
+		{
+			name: "chained operators",
+			builder: Except(
+				Select("users").Columns("id").Where("active = ?", true),
+				Select("banned").Columns("user_id"),
+			).Union(Select("admins").Columns("id").Where("level > ?", 1)),
+			wantSQL:  "SELECT id FROM users WHERE active = $1 EXCEPT SELECT user_id FROM banned UNION SELECT id FROM admins WHERE level > $2",
+			wantArgs: []any{true, 1},
+		},
+		{
+			name: "intersect after union keeps left to right order",
+			builder: Union(Select("a").Columns("x"), Select("b").Columns("x")).
+				Intersect(Select("c").Columns("x")),
+			wantSQL: "(SELECT x FROM a UNION SELECT x FROM b) INTERSECT SELECT x FROM c",
+		},
+		{
+			name: "operand with limit is parenthesized",
+			builder: UnionAll(
+				Select("a").Columns("x").OrderByDesc("x").Limit(5),
+				Select("b").Columns("x").Where("x > ?", 10),
+			).OrderByAsc("1"),
+			wantSQL:  "(SELECT x FROM a ORDER BY x DESC LIMIT 5) UNION ALL SELECT x FROM b WHERE x > $1 ORDER BY 1 ASC",
+			wantArgs: []any{10},
+		},
+		{
+			name: "star columns skip count validation",
+			builder: Intersect(
+				Select("a"),
+				Select("b").Columns("x", "y"),
+			),
+			wantSQL: "SELECT * FROM a INTERSECT SELECT x, y FROM b",
+		},
+		{
+			name: "column count mismatch",
+			builder: Union(
+				Select("a").Columns("x", "y"),
+				Select("b").Columns("x"),
+			),
+			errContains: "has 1 columns but 2 expected",
+		},
+		{
+			name:        "single operand",
+			builder:     Union(Select("a")),
+			errContains: "at least two queries",
+		},
+		{
+			name:        "invalid operand",
+			builder:     Union(Select("a"), Select("bad table")),
+			errContains: "invalid set operation query 1",
+		},
+		{
+			name:        "nil operand",
+			builder:     Union(Select("a"), nil),
+			errContains: "query 1 is nil",
+		},
+		{
+			name:        "order by expression",
+			builder:     Union(Select("a"), Select("b")).OrderByAsc("lower(name)"),
+			errContains: "invalid order by column",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			sql, args, err := tt.builder.Build()
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("Build() error = %v, want error containing %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Build() error = %v", err)
+			}
+			if sql != tt.wantSQL {
+				t.Errorf("Build() SQL = %q, want %q", sql, tt.wantSQL)
+			}
+			if len(args) != len(tt.wantArgs) {
+				t.Fatalf("Build() args = %v, want %v", args, tt.wantArgs)
+			}
+			for i, arg := range args {
+				if arg != tt.wantArgs[i] {
+					t.Errorf("Build() args[%d] = %v, want %v", i, arg, tt.wantArgs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCompoundSelectBuilder_RecursiveCTE(t *testing.T) {
+	t.Parallel()
+
+	tree := UnionAll(
+		Select("referrals").Columns("id", "referrer_id").Where("referrer_id = ?", 42),
+		Select("referrals").Columns("referrals.id", "referrals.referrer_id").Join("tree", "referrals.referrer_id = tree.id"),
+	)
+	sql, args, err := Select("tree").WithRecursive("tree", tree).Columns("id").Where("id <> ?", 42).Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	want := "WITH RECURSIVE tree AS (SELECT id, referrer_id FROM referrals WHERE referrer_id = $1 UNION ALL SELECT referrals.id, referrals.referrer_id FROM referrals INNER JOIN tree ON referrals.referrer_id = tree.id) SELECT id FROM tree WHERE id <> $2"
+	if sql != want {
+		t.Errorf("Build() SQL = %q, want %q", sql, want)
+	}
+	if len(args) != 2 || args[0] != 42 || args[1] != 42 {
+		t.Errorf("Build() args = %v, want [42 42]", args)
+	}
+}
+
+// assertBuild builds the query and checks the SQL and arguments,
+// or that building fails with an error containing errContains.
+func assertBuild(t *testing.T, builder Builder, wantSQL string, wantArgs []any, errContains string) {
+	t.Helper()
+	sql, args, err := builder.Build()
+	if errContains != "" {
+		if err == nil || !strings.Contains(err.Error(), errContains) {
+			t.Fatalf("Build() error = %v, want error containing %q", err, errContains)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	if sql != wantSQL {
+		t.Errorf("Build() SQL = %q, want %q", sql, wantSQL)
+	}
+	if len(args) != len(wantArgs) {
+		t.Fatalf("Build() args = %v, want %v", args, wantArgs)
+	}
+	for i, arg := range args {
+		if !reflect.DeepEqual(arg, wantArgs[i]) {
+			t.Errorf("Build() args[%d] = %#v, want %#v", i, arg, wantArgs[i])
+		}
+	}
+}
+
+func TestInsertBuilder_OnConflictDoUpdate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		builder     *InsertBuilder
+		wantSQL     string
+		wantArgs    []any
+		errContains string
+	}{
+		{
+			name: "set excluded",
+			builder: Insert("drivers").
+				Columns("id", "name", "rating").
+				Values(1, "Ana", 4.8).
+				OnConflictDoUpdate("id").
+				SetExcluded("name", "rating"),
+			wantSQL:  "INSERT INTO drivers (id, name, rating) VALUES ($1, $2, $3) ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name, rating = EXCLUDED.rating",
+			wantArgs: []any{1, "Ana", 4.8},
+		},
+		{
+			name: "set values with conflict where and returning",
+			builder: Insert("drivers").
+				Columns("id", "name").
+				Values(1, "Ana").
+				OnConflictDoUpdate("id").
+				SetExcluded("name").
+				Set("updated_at", "now").
+				ConflictWhere("drivers.locked = ?", false).
+				Returning("id"),
+			wantSQL:  "INSERT INTO drivers (id, name) VALUES ($1, $2) ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name, updated_at = $3 WHERE drivers.locked = $4 RETURNING id",
+			wantArgs: []any{1, "Ana", "now", false},
+		},
+		{
+			name: "partial index target",
+			builder: Insert("vehicles").
+				Columns("plate", "driver_id").
+				Values("ABC-123", 7).
+				OnConflictDoUpdate("plate").
+				ConflictTargetWhere("deleted_at IS NULL").
+				ConflictTargetWhere("region = ?", "maputo").
+				SetExcluded("driver_id"),
+			wantSQL:  "INSERT INTO vehicles (plate, driver_id) VALUES ($1, $2) ON CONFLICT (plate) WHERE deleted_at IS NULL AND region = $3 DO UPDATE SET driver_id = EXCLUDED.driver_id",
+			wantArgs: []any{"ABC-123", 7, "maputo"},
+		},
+		{
+			name: "constraint target",
+			builder: Insert("users").
+				Columns("email", "name").
+				Values("a@example.com", "A").
+				OnConflictConstraintDoUpdate("users_email_key").
+				SetExcluded("name"),
+			wantSQL:  "INSERT INTO users (email, name) VALUES ($1, $2) ON CONFLICT ON CONSTRAINT users_email_key DO UPDATE SET name = EXCLUDED.name",
+			wantArgs: []any{"a@example.com", "A"},
+		},
+		{
+			name: "with cte arguments first",
+			builder: Insert("users").
+				With("src", Select("staging").Where("batch = ?", 9)).
+				Columns("email").
+				Values("a@example.com").
+				OnConflictDoUpdate("email").
+				Set("seen", 1),
+			wantSQL:  "WITH src AS (SELECT * FROM staging WHERE batch = $1) INSERT INTO users (email) VALUES ($2) ON CONFLICT (email) DO UPDATE SET seen = $3",
+			wantArgs: []any{9, "a@example.com", 1},
+		},
+		{
+			name: "allowlist rejects update column",
+			builder: InsertWithAllowlist("users", "email", "name").
+				Columns("email").
+				Values("a@example.com").
+				OnConflictDoUpdate("email").
+				Set("is_admin", true),
+			errContains: "invalid on conflict update column",
+		},
+		{
+			name: "qualified update column",
+			builder: Insert("users").
+				Columns("email").
+				Values("a@example.com").
+				OnConflictDoUpdate("email").
+				Set("users.seen", 1),
+			errContains: "invalid on conflict update column",
+		},
+		{
+			name: "qualified excluded column",
+			builder: Insert("users").
+				Columns("email", "name").
+				Values("a@example.com", "A").
+				OnConflictDoUpdate("email").
+				SetExcluded("users.name"),
+			errContains: "invalid on conflict update column",
+		},
+		{
+			name: "allowlist rejects target column",
+			builder: InsertWithAllowlist("users", "email", "name").
+				Columns("email").
+				Values("a@example.com").
+				OnConflictDoUpdate("password").
+				SetExcluded("name"),
+			errContains: "invalid on conflict column",
+		},
+		{
+			name: "no assignments",
+			builder: Insert("users").
+				Columns("email").
+				Values("a@example.com").
+				OnConflictDoUpdate("email"),
+			errContains: "no columns specified for on conflict do update",
+		},
+		{
+			name: "no target",
+			builder: Insert("users").
+				Columns("email").
+				Values("a@example.com").
+				OnConflictDoUpdate().
+				SetExcluded("email"),
+			errContains: "requires target columns",
+		},
+		{
+			name: "set without do update",
+			builder: Insert("users").
+				Columns("email").
+				Values("a@example.com").
+				Set("name", "x"),
+			errContains: "require OnConflictDoUpdate",
+		},
+		{
+			name: "target predicate with constraint",
+			builder: Insert("users").
+				Columns("email").
+				Values("a@example.com").
+				OnConflictConstraintDoUpdate("users_email_key").
+				ConflictTargetWhere("active").
+				SetExcluded("email"),
+			errContains: "cannot be used with a constraint",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assertBuild(t, tt.builder, tt.wantSQL, tt.wantArgs, tt.errContains)
+		})
+	}
+}
+
+func TestUpdateFromDeleteUsing(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		builder     Builder
+		wantSQL     string
+		wantArgs    []any
+		errContains string
+	}{
+		{
+			name: "update from",
+			builder: Update("trips").
+				Set("status", "expired").
+				From("drivers", "drivers.id = trips.driver_id AND drivers.status = ?", "offline").
+				Where("trips.status = ?", "pending"),
+			wantSQL:  "UPDATE trips SET status = $1 FROM drivers WHERE drivers.id = trips.driver_id AND drivers.status = $2 AND trips.status = $3",
+			wantArgs: []any{"expired", "offline", "pending"},
+		},
+		{
+			name: "update from multiple tables",
+			builder: Update("trips").
+				Set("fare", 0).
+				From("drivers", "drivers.id = trips.driver_id").
+				From("regions", "regions.id = drivers.region_id AND regions.code = ?", "MPM").
+				Returning("trips.id"),
+			wantSQL:  "UPDATE trips SET fare = $1 FROM drivers, regions WHERE drivers.id = trips.driver_id AND regions.id = drivers.region_id AND regions.code = $2 RETURNING trips.id",
+			wantArgs: []any{0, "MPM"},
+		},
+		{
+			name: "update from parenthesizes or",
+			builder: Update("trips").
+				Set("flagged", true).
+				From("drivers", "drivers.id = trips.driver_id").
+				Where("drivers.rating < ?", 3).
+				OrWhere("drivers.suspended = ?", true),
+			wantSQL:  "UPDATE trips SET flagged = $1 FROM drivers WHERE drivers.id = trips.driver_id AND (drivers.rating < $2 OR drivers.suspended = $3)",
+			wantArgs: []any{true, 3, true},
+		},
+		{
+			name: "delete using",
+			builder: Delete("sessions").
+				Using("users", "users.id = sessions.user_id AND users.status = ?", "banned").
+				Where("sessions.kind = ?", "web").
+				Returning("sessions.id"),
+			wantSQL:  "DELETE FROM sessions USING users WHERE users.id = sessions.user_id AND users.status = $1 AND sessions.kind = $2 RETURNING sessions.id",
+			wantArgs: []any{"banned", "web"},
+		},
+		{
+			name: "delete using condition counts as restriction",
+			builder: Delete("sessions").
+				Using("users", "users.id = sessions.user_id AND users.deleted = ?", true),
+			wantSQL:  "DELETE FROM sessions USING users WHERE users.id = sessions.user_id AND users.deleted = $1",
+			wantArgs: []any{true},
+		},
+		{
+			name:        "delete using without condition is unrestricted",
+			builder:     Delete("sessions").Using("users", ""),
+			errContains: "AllowUnrestrictedDelete",
+		},
+		{
+			name:        "invalid from table",
+			builder:     Update("trips").Set("a", 1).From("drivers; DROP TABLE x", "").Where("id = ?", 1),
+			errContains: "invalid from table",
+		},
+		{
+			name: "raw or condition is grouped after join condition",
+			builder: Update("trips").Set("status", "void").
+				From("drivers", "drivers.id = trips.driver_id").
+				Where("drivers.banned = ? OR drivers.deleted = ?", true, true),
+			wantSQL:  "UPDATE trips SET status = $1 FROM drivers WHERE drivers.id = trips.driver_id AND (drivers.banned = $2 OR drivers.deleted = $3)",
+			wantArgs: []any{"void", true, true},
+		},
+		{
+			name: "qualified set column",
+			builder: Update("trips").Set("drivers.status", "busy").
+				From("drivers", "drivers.id = trips.driver_id").Where("trips.id = ?", 1),
+			errContains: "invalid set column",
+		},
+		{
+			name:        "invalid using table",
+			builder:     Delete("trips").Using("", "a = b"),
+			errContains: "invalid using table",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assertBuild(t, tt.builder, tt.wantSQL, tt.wantArgs, tt.errContains)
+		})
+	}
+}
+
+func TestConditionTrees(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		builder     Builder
+		wantSQL     string
+		wantArgs    []any
+		errContains string
+	}{
+		{
+			name: "where group",
+			builder: Select("users").
+				WhereGroup(func(g *ConditionGroup) {
+					g.Where("role = ?", "admin").OrWhere("role = ?", "owner")
+				}).
+				Where("active = ?", true),
+			wantSQL:  "SELECT * FROM users WHERE (role = $1 OR role = $2) AND active = $3",
+			wantArgs: []any{"admin", "owner", true},
+		},
+		{
+			name: "or where group",
+			builder: Select("users").
+				Where("deleted_at IS NULL").
+				OrWhereGroup(func(g *ConditionGroup) {
+					g.Where("role = ?", "admin").Where("active = ?", true)
+				}),
+			wantSQL:  "SELECT * FROM users WHERE deleted_at IS NULL OR (role = $1 AND active = $2)",
+			wantArgs: []any{"admin", true},
+		},
+		{
+			name: "nested groups",
+			builder: Select("trips").
+				WhereGroup(func(g *ConditionGroup) {
+					g.Where("status = ?", "pending").
+						OrWhereGroup(func(g *ConditionGroup) {
+							g.Where("status = ?", "active").WhereIn("region", "north", "south")
+						})
+				}),
+			wantSQL:  "SELECT * FROM trips WHERE (status = $1 OR (status = $2 AND region IN ($3, $4)))",
+			wantArgs: []any{"pending", "active", "north", "south"},
+		},
+		{
+			name: "single raw condition with OR in group",
+			builder: Select("users").
+				WhereGroup(func(g *ConditionGroup) { g.Where("a = ? OR b = ?", 1, 2) }).
+				Where("c = ?", 3),
+			wantSQL:  "SELECT * FROM users WHERE (a = $1 OR b = $2) AND c = $3",
+			wantArgs: []any{1, 2, 3},
+		},
+		{
+			name: "raw condition with OR grouped among group clauses",
+			builder: Select("users").
+				WhereGroup(func(g *ConditionGroup) { g.Where("a = ? OR b = ?", 1, 2).Where("c = ?", 3) }),
+			wantSQL:  "SELECT * FROM users WHERE ((a = $1 OR b = $2) AND c = $3)",
+			wantArgs: []any{1, 2, 3},
+		},
+		{
+			name: "raw where with OR before condition tree",
+			builder: Select("users").
+				Where("a = ? OR b = ?", 1, 2).
+				WhereCondition(Expr("c = ?", 3)),
+			wantSQL:  "SELECT * FROM users WHERE (a = $1 OR b = $2) AND c = $3",
+			wantArgs: []any{1, 2, 3},
+		},
+		{
+			name: "update raw where with OR before condition tree",
+			builder: Update("users").Set("active", false).
+				Where("a = ? OR b = ?", 1, 2).
+				WhereCondition(Expr("c = ?", 3)),
+			wantSQL:  "UPDATE users SET active = $1 WHERE (a = $2 OR b = $3) AND c = $4",
+			wantArgs: []any{false, 1, 2, 3},
+		},
+		{
+			name: "and or not tree",
+			builder: Select("users").WhereCondition(And(
+				Or(Expr("role = ?", "admin"), Expr("role = ?", "owner")),
+				Not(Expr("email LIKE ?", "%@test.com")),
+				Expr("age BETWEEN ? AND ?", 18, 65),
+			)),
+			wantSQL:  "SELECT * FROM users WHERE ((role = $1 OR role = $2) AND NOT (email LIKE $3) AND (age BETWEEN $4 AND $5))",
+			wantArgs: []any{"admin", "owner", "%@test.com", 18, 65},
+		},
+		{
+			name: "not of or",
+			builder: Select("users").
+				Where("active = ?", true).
+				WhereCondition(Not(Or(Expr("banned"), Expr("suspended")))),
+			wantSQL:  "SELECT * FROM users WHERE active = $1 AND NOT (banned OR suspended)",
+			wantArgs: []any{true},
+		},
+		{
+			name: "raw expression with OR is parenthesized",
+			builder: Select("users").
+				Where("active = ?", true).
+				WhereCondition(Expr("a = ? OR b = ?", 1, 2)),
+			wantSQL:  "SELECT * FROM users WHERE active = $1 AND (a = $2 OR b = $3)",
+			wantArgs: []any{true, 1, 2},
+		},
+		{
+			name: "single raw condition with OR in and",
+			builder: Select("t").
+				Where("x = ?", 1).
+				WhereCondition(And(Expr("a = ? OR b = ?", 2, 3))),
+			wantSQL:  "SELECT * FROM t WHERE x = $1 AND (a = $2 OR b = $3)",
+			wantArgs: []any{1, 2, 3},
+		},
+		{
+			name: "single nested condition with OR in group",
+			builder: Select("t").
+				Where("x = ?", 1).
+				WhereGroup(func(g *ConditionGroup) { g.WhereCondition(Or(Expr("a = ? OR b = ?", 2, 3))) }),
+			wantSQL:  "SELECT * FROM t WHERE x = $1 AND (a = $2 OR b = $3)",
+			wantArgs: []any{1, 2, 3},
+		},
+		{
+			name:     "not of single raw condition with OR",
+			builder:  Select("t").WhereCondition(Not(And(Expr("a = ? OR b = ?", 2, 3)))),
+			wantSQL:  "SELECT * FROM t WHERE NOT (a = $1 OR b = $2)",
+			wantArgs: []any{2, 3},
+		},
+		{
+			name: "having group",
+			builder: Select("orders").Columns("user_id").ColumnsRaw("COUNT(*)").
+				GroupBy("user_id").
+				HavingGroup(func(g *ConditionGroup) {
+					g.Where("COUNT(*) > ?", 10).OrWhere("SUM(total) > ?", 1000)
+				}),
+			wantSQL:  "SELECT user_id, COUNT(*) FROM orders GROUP BY user_id HAVING (COUNT(*) > $1 OR SUM(total) > $2)",
+			wantArgs: []any{10, 1000},
+		},
+		{
+			name: "update with group",
+			builder: Update("trips").Set("status", "cancelled").
+				Where("driver_id = ?", 7).
+				WhereGroup(func(g *ConditionGroup) {
+					g.Where("status = ?", "pending").OrWhere("status = ?", "assigned")
+				}),
+			wantSQL:  "UPDATE trips SET status = $1 WHERE driver_id = $2 AND (status = $3 OR status = $4)",
+			wantArgs: []any{"cancelled", 7, "pending", "assigned"},
+		},
+		{
+			name: "delete with condition",
+			builder: Delete("sessions").
+				WhereCondition(Or(Expr("expires_at < ?", "now"), Expr("revoked = ?", true))),
+			wantSQL:  "DELETE FROM sessions WHERE (expires_at < $1 OR revoked = $2)",
+			wantArgs: []any{"now", true},
+		},
+		{
+			name: "delete using with or group",
+			builder: Delete("sessions").
+				Using("users", "users.id = sessions.user_id").
+				WhereGroup(func(g *ConditionGroup) {
+					g.Where("users.banned = ?", true).OrWhere("users.deleted = ?", true)
+				}),
+			wantSQL:  "DELETE FROM sessions USING users WHERE users.id = sessions.user_id AND (users.banned = $1 OR users.deleted = $2)",
+			wantArgs: []any{true, true},
+		},
+		{
+			name:        "empty group",
+			builder:     Select("users").WhereGroup(func(*ConditionGroup) {}),
+			errContains: "condition group cannot be empty",
+		},
+		{
+			name:        "nil condition",
+			builder:     Update("users").Set("a", 1).WhereCondition(nil),
+			errContains: "condition cannot be nil",
+		},
+		{
+			name:        "empty or",
+			builder:     Delete("users").WhereCondition(Or()),
+			errContains: "OR condition requires at least one condition",
+		},
+		{
+			name:        "nil not",
+			builder:     Select("users").WhereCondition(And(Expr("a"), Not(nil))),
+			errContains: "NOT condition cannot be nil",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assertBuild(t, tt.builder, tt.wantSQL, tt.wantArgs, tt.errContains)
+		})
+	}
+}
+
+func TestArrayParameters(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		builder     Builder
+		wantSQL     string
+		wantArgs    []any
+		errContains string
+	}{
+		{
+			name:     "where in array",
+			builder:  Select("users").WhereInArray("id", []int64{1, 2, 3}),
+			wantSQL:  "SELECT * FROM users WHERE id = ANY($1)",
+			wantArgs: []any{[]int64{1, 2, 3}},
+		},
+		{
+			name:     "where not in array converts any slice",
+			builder:  Select("users").Where("active = ?", true).WhereNotInArray("role", []any{"admin", "owner"}),
+			wantSQL:  "SELECT * FROM users WHERE active = $1 AND role <> ALL($2)",
+			wantArgs: []any{true, []string{"admin", "owner"}},
+		},
+		{
+			name: "array operators",
+			builder: Select("drivers").
+				WhereArrayContains("tags", []string{"vip"}).
+				WhereArrayOverlaps("zones", []string{"north", "south"}).
+				WhereArrayContainedBy("languages", []string{"pt", "en"}),
+			wantSQL:  "SELECT * FROM drivers WHERE tags @> $1 AND zones && $2 AND languages <@ $3",
+			wantArgs: []any{[]string{"vip"}, []string{"north", "south"}, []string{"pt", "en"}},
+		},
+		{
+			name:     "threshold switches where in to array",
+			builder:  Select("users").ArrayThreshold(3).WhereIn("id", 1, 2, 3),
+			wantSQL:  "SELECT * FROM users WHERE id = ANY($1)",
+			wantArgs: []any{[]int{1, 2, 3}},
+		},
+		{
+			name:     "threshold set after where in still applies",
+			builder:  Select("users").WhereNotIn("id", 1, 2, 3).ArrayThreshold(2),
+			wantSQL:  "SELECT * FROM users WHERE id <> ALL($1)",
+			wantArgs: []any{[]int{1, 2, 3}},
+		},
+		{
+			name:     "below threshold keeps placeholders",
+			builder:  Select("users").ArrayThreshold(4).WhereIn("id", 1, 2, 3),
+			wantSQL:  "SELECT * FROM users WHERE id IN ($1, $2, $3)",
+			wantArgs: []any{1, 2, 3},
+		},
+		{
+			name:     "mixed types stay untyped",
+			builder:  Select("users").ArrayThreshold(1).WhereIn("code", 1, "a"),
+			wantSQL:  "SELECT * FROM users WHERE code = ANY($1)",
+			wantArgs: []any{[]any{1, "a"}},
+		},
+		{
+			name: "update with threshold",
+			builder: Update("trips").Set("status", "cancelled").
+				ArrayThreshold(2).WhereIn("id", int64(7), int64(8)),
+			wantSQL:  "UPDATE trips SET status = $1 WHERE id = ANY($2)",
+			wantArgs: []any{"cancelled", []int64{7, 8}},
+		},
+		{
+			name:     "delete with array",
+			builder:  Delete("sessions").WhereInArray("user_id", []int64{4, 5}),
+			wantSQL:  "DELETE FROM sessions WHERE user_id = ANY($1)",
+			wantArgs: []any{[]int64{4, 5}},
+		},
+		{
+			name:        "array condition requires slice",
+			builder:     Select("users").WhereInArray("id", 42),
+			errContains: "requires a slice",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assertBuild(t, tt.builder, tt.wantSQL, tt.wantArgs, tt.errContains)
+		})
+	}
+}
+
+func TestJSONBHelpers(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		builder     Builder
+		wantSQL     string
+		wantArgs    []any
+		errContains string
+	}{
+		{
+			name:     "contains",
+			builder:  Select("users").WhereJSONContains("data", map[string]any{"tier": "vip"}),
+			wantSQL:  "SELECT * FROM users WHERE data @> $1::jsonb",
+			wantArgs: []any{[]byte(`{"tier":"vip"}`)},
+		},
+		{
+			name: "key operators",
+			builder: Select("users").
+				WhereJSONHasKey("data", "phone").
+				WhereJSONHasAnyKey("data", "a", "b").
+				WhereJSONHasAllKeys("data", "c"),
+			wantSQL:  "SELECT * FROM users WHERE data ? $1 AND data ?| $2 AND data ?& $3",
+			wantArgs: []any{"phone", []string{"a", "b"}, []string{"c"}},
+		},
+		{
+			name:     "raw jsonb operator with escape",
+			builder:  Select("users").Where("data ?? 'phone'").Where("id = ?", 1),
+			wantSQL:  "SELECT * FROM users WHERE data ? 'phone' AND id = $1",
+			wantArgs: []any{1},
+		},
+		{
+			name:     "path equals",
+			builder:  Select("users").WhereJSONPathEquals("data", []string{"address", "city"}, "Maputo"),
+			wantSQL:  "SELECT * FROM users WHERE data #>> $1 = $2",
+			wantArgs: []any{[]string{"address", "city"}, "Maputo"},
+		},
+		{
+			name: "path extraction column",
+			builder: Select("users").
+				Columns("id").ColumnsRaw(JSONPath("data", "address", "it's \"x\"")),
+			wantSQL: `SELECT id, data #>> '{"address","it''s \"x\""}' FROM users`,
+		},
+		{
+			name: "update jsonb set",
+			builder: Update("users").
+				SetJSONPath("data", []string{"prefs", "lang"}, "pt").
+				Set("updated", true).
+				WhereJSONHasKey("data", "prefs"),
+			wantSQL:  "UPDATE users SET data = jsonb_set(COALESCE(data, '{}'::jsonb), $1, $2::jsonb), updated = $3 WHERE data ? $4",
+			wantArgs: []any{[]string{"prefs", "lang"}, []byte(`"pt"`), true, "prefs"},
+		},
+		{
+			name:     "update set json",
+			builder:  Update("users").SetJSON("data", []int{1, 2}).Where("id = ?", 9),
+			wantSQL:  "UPDATE users SET data = $1::jsonb WHERE id = $2",
+			wantArgs: []any{[]byte(`[1,2]`), 9},
+		},
+		{
+			name:     "delete json contains",
+			builder:  Delete("events").WhereJSONContains("payload", map[string]bool{"test": true}),
+			wantSQL:  "DELETE FROM events WHERE payload @> $1::jsonb",
+			wantArgs: []any{[]byte(`{"test":true}`)},
+		},
+		{
+			name:        "unencodable contains value",
+			builder:     Select("users").WhereJSONContains("data", make(chan int)),
+			errContains: "failed to encode jsonb value",
+		},
+		{
+			name:        "unencodable set value",
+			builder:     Update("users").SetJSON("data", func() {}).Where("id = ?", 1),
+			errContains: "failed to encode jsonb value",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assertBuild(t, tt.builder, tt.wantSQL, tt.wantArgs, tt.errContains)
+		})
+	}
+}
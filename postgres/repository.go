@@ -0,0 +1,235 @@
+// Package postgres provides PostgreSQL database utilities.
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+
+	"github.com/Dorico-Dynamics/txova-go-types/pagination"
+)
+
+// DefaultRepositoryIDColumn is the default primary key column of a Repository.
+const DefaultRepositoryIDColumn = "id"
+
+// Filter restricts List results by column equality.
+// A nil value matches NULL. Columns must be mapped by the repository's struct.
+type Filter map[string]any
+
+// RepositoryConfig holds configuration for a Repository.
+type RepositoryConfig struct {
+	// IDColumn is the primary key column.
+	// Default: "id".
+	IDColumn string
+}
+
+// RepositoryOption is a functional option for configuring a Repository.
+type RepositoryOption func(*RepositoryConfig)
+
+// WithRepositoryIDColumn sets the primary key column.
+func WithRepositoryIDColumn(column string) RepositoryOption {
+	return func(c *RepositoryConfig) {
+		c.IDColumn = column
+	}
+}
+
+// Repository provides CRUD operations for a table described by the db struct tags of T,
+// keyed by a primary key of type ID.
+// Queries run in the transaction stored in the context, if any, and only columns
+// mapped by T are accepted for filters and sorting.
+type Repository[T any, ID comparable] struct {
+	querier Querier
+	table   string
+	config  RepositoryConfig
+	meta    *structMeta
+	columns []string
+}
+
+// NewRepository creates a Repository for the table.
+// Queries run on querier unless the context carries a transaction.
+func NewRepository[T any, ID comparable](querier Querier, table string, opts ...RepositoryOption) (*Repository[T, ID], error) {
+	if querier == nil {
+		return nil, New(CodeInvalidInput, "querier is required")
+	}
+	if err := validateTableName(table); err != nil {
+		return nil, Wrap(CodeInvalidInput, "invalid repository table", err)
+	}
+	config := RepositoryConfig{IDColumn: DefaultRepositoryIDColumn}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	meta, err := getStructMeta(reflect.TypeFor[T]())
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := meta.lookup(config.IDColumn); !ok {
+		return nil, New(CodeInvalidInput, fmt.Sprintf("id column %s is not mapped by %s", config.IDColumn, reflect.TypeFor[T]()))
+	}
+	return &Repository[T, ID]{
+		querier: querier,
+		table:   table,
+		config:  config,
+		meta:    meta,
+		columns: meta.columns(),
+	}, nil
+}
+
+// Get returns the row with the id.
+// Returns a CodeNotFound error if there is none.
+func (r *Repository[T, ID]) Get(ctx context.Context, id ID) (T, error) {
+	var zero T
+	query := r.selectBuilder().Where(quoteIdent(r.config.IDColumn)+" = ?", id)
+	items, err := r.query(ctx, query)
+	if err != nil {
+		return zero, err
+	}
+	if len(items) == 0 {
+		return zero, New(CodeNotFound, fmt.Sprintf("%s not found", r.table))
+	}
+	return items[0], nil
+}
+
+// List returns the rows matching the filter, paginated and sorted by the page request.
+// The sort field must be a mapped column; rows are ordered by id otherwise.
+func (r *Repository[T, ID]) List(ctx context.Context, filter Filter, page pagination.PageRequest) ([]T, error) {
+	query := r.selectBuilder()
+	columns := make([]string, 0, len(filter))
+	for column := range filter {
+		columns = append(columns, column)
+	}
+	slices.Sort(columns)
+	for _, column := range columns {
+		if _, ok := r.meta.lookup(column); !ok {
+			return nil, New(CodeInvalidInput, fmt.Sprintf("column not in allowlist: %s", column))
+		}
+		if value := filter[column]; value == nil {
+			query.WhereNull(column)
+		} else {
+			query.Where(quoteIdent(column)+" = ?", value)
+		}
+	}
+	query.Page(page)
+	if page.SortField != r.config.IDColumn {
+		// Keep pagination stable when the sort field has duplicates.
+		query.OrderByAsc(r.config.IDColumn)
+	}
+	return r.query(ctx, query)
+}
+
+// Create inserts v and updates it with the inserted row, including
+// read-only columns set by the database such as generated ids and timestamps.
+func (r *Repository[T, ID]) Create(ctx context.Context, v *T) error {
+	query := InsertStruct(r.table, v).Returning(r.columns...)
+	items, err := r.query(ctx, query)
+	if err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		return New(CodeInternal, fmt.Sprintf("insert into %s returned no row", r.table))
+	}
+	*v = items[0]
+	return nil
+}
+
+// Update saves v by its id and updates it with the stored row.
+// If fields are given, only those columns are updated; otherwise all
+// writable columns are updated. Returns a CodeNotFound error if the row does not exist.
+func (r *Repository[T, ID]) Update(ctx context.Context, v *T, fields ...string) error {
+	if v == nil {
+		return New(CodeInvalidInput, "struct value cannot be nil")
+	}
+	id, err := r.idOf(v)
+	if err != nil {
+		return err
+	}
+	query := UpdateStruct(r.table, v, fields...).
+		Where(quoteIdent(r.config.IDColumn)+" = ?", id).
+		Returning(r.columns...)
+	items, err := r.query(ctx, query)
+	if err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		return New(CodeNotFound, fmt.Sprintf("%s not found", r.table))
+	}
+	*v = items[0]
+	return nil
+}
+
+// Delete deletes the row with the id.
+// Returns a CodeNotFound error if there is none.
+func (r *Repository[T, ID]) Delete(ctx context.Context, id ID) error {
+	sql, args, err := Delete(r.table).Where(quoteIdent(r.config.IDColumn)+" = ?", id).Build()
+	if err != nil {
+		return Wrap(CodeInvalidInput, "invalid delete query", err)
+	}
+	tag, err := r.querierFor(ctx).Exec(ctx, sql, args...)
+	if err != nil {
+		return asDBError(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return New(CodeNotFound, fmt.Sprintf("%s not found", r.table))
+	}
+	return nil
+}
+
+// Exists reports whether a row with the id exists.
+func (r *Repository[T, ID]) Exists(ctx context.Context, id ID) (bool, error) {
+	sql, args, err := Select(r.table).ColumnsRaw("1").Where(quoteIdent(r.config.IDColumn)+" = ?", id).Build()
+	if err != nil {
+		return false, Wrap(CodeInvalidInput, "invalid exists query", err)
+	}
+	var exists bool
+	if err := r.querierFor(ctx).QueryRow(ctx, "SELECT EXISTS ("+sql+")", args...).Scan(&exists); err != nil {
+		return false, asDBError(err)
+	}
+	return exists, nil
+}
+
+// selectBuilder returns a SELECT of all mapped columns restricted to them.
+func (r *Repository[T, ID]) selectBuilder() *SelectBuilder {
+	return SelectWithAllowlist(r.table, r.columns...).Columns(r.columns...)
+}
+
+// query builds and runs a query returning rows of T.
+func (r *Repository[T, ID]) query(ctx context.Context, builder Builder) ([]T, error) {
+	sql, args, err := buildQuery(builder)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := r.querierFor(ctx).Query(ctx, sql, args...)
+	if err != nil {
+		return nil, asDBError(err)
+	}
+	return ScanAll[T](rows)
+}
+
+// querierFor returns the transaction in the context or the repository's querier.
+func (r *Repository[T, ID]) querierFor(ctx context.Context) Querier {
+	return QuerierFromContext(ctx, r.querier)
+}
+
+// idOf returns the id field of v.
+func (r *Repository[T, ID]) idOf(v *T) (ID, error) {
+	var zero ID
+	idx, _ := r.meta.lookup(r.config.IDColumn)
+	field := r.meta.fields[idx]
+	fv, err := reflect.ValueOf(v).Elem().FieldByIndexErr(field.index)
+	if err != nil {
+		return zero, New(CodeInvalidInput, fmt.Sprintf("id column %s is not set", r.config.IDColumn))
+	}
+	id, ok := fv.Interface().(ID)
+	if !ok {
+		return zero, New(CodeInvalidInput, fmt.Sprintf("id column %s has type %s, not %s", r.config.IDColumn, fv.Type(), reflect.TypeFor[ID]()))
+	}
+	return id, nil
+}
+
+// asDBError returns err as a domain Error, converting PostgreSQL errors.
+func asDBError(err error) error {
+	if AsError(err) != nil {
+		return err
+	}
+	return FromPgError(err)
+}
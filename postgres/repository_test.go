@@ -0,0 +1,304 @@
+package postgres
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/Dorico-Dynamics/txova-go-types/pagination"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+type testUser struct {
+	ID    int64  `db:"id,readonly"`
+	Name  string `db:"name"`
+	Email string `db:"email,omitempty"`
+}
+
+func newTestRepository(t *testing.T) (*Repository[testUser, int64], pgxmock.PgxPoolIface) {
+	t.Helper()
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	t.Cleanup(mock.Close)
+	repo, err := NewRepository[testUser, int64](mock, "users")
+	if err != nil {
+		t.Fatalf("NewRepository() error = %v", err)
+	}
+	return repo, mock
+}
+
+func userRows() *pgxmock.Rows {
+	return pgxmock.NewRows([]string{"id", "name", "email"})
+}
+
+func TestNewRepository(t *testing.T) {
+	t.Parallel()
+
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("failed to create mock: %v", err)
+	}
+	defer mock.Close()
+
+	tests := []struct {
+		name string
+		fn   func() error
+	}{
+		{"nil querier", func() error {
+			_, err := NewRepository[testUser, int64](nil, "users")
+			return err
+		}},
+		{"invalid table", func() error {
+			_, err := NewRepository[testUser, int64](mock, "users; DROP")
+			return err
+		}},
+		{"unmapped id column", func() error {
+			_, err := NewRepository[testUser, int64](mock, "users", WithRepositoryIDColumn("uuid"))
+			return err
+		}},
+		{"not a struct", func() error {
+			_, err := NewRepository[string, int64](mock, "users")
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if err := tt.fn(); !IsCode(err, CodeInvalidInput) {
+				t.Errorf("NewRepository() error = %v, want invalid input", err)
+			}
+		})
+	}
+}
+
+func TestRepository_Get(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	query := regexp.QuoteMeta("SELECT id, name, email FROM users WHERE id = $1")
+
+	t.Run("found", func(t *testing.T) {
+		t.Parallel()
+		repo, mock := newTestRepository(t)
+		mock.ExpectQuery(query).WithArgs(int64(7)).
+			WillReturnRows(userRows().AddRow(int64(7), "Ana", "ana@example.com"))
+
+		user, err := repo.Get(ctx, 7)
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		if user.ID != 7 || user.Name != "Ana" || user.Email != "ana@example.com" {
+			t.Errorf("Get() = %+v", user)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		t.Parallel()
+		repo, mock := newTestRepository(t)
+		mock.ExpectQuery(query).WithArgs(int64(7)).WillReturnRows(userRows())
+
+		if _, err := repo.Get(ctx, 7); !IsNotFound(err) {
+			t.Errorf("Get() error = %v, want not found", err)
+		}
+	})
+
+	t.Run("query error is mapped", func(t *testing.T) {
+		t.Parallel()
+		repo, mock := newTestRepository(t)
+		mock.ExpectQuery(query).WithArgs(int64(7)).
+			WillReturnError(&pgconn.PgError{Code: "57014", Message: "canceled"})
+
+		if _, err := repo.Get(ctx, 7); !IsCode(err, CodeTimeout) {
+			t.Errorf("Get() error = %v, want timeout", err)
+		}
+	})
+}
+
+func TestRepository_List(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	t.Run("filters and pagination", func(t *testing.T) {
+		t.Parallel()
+		repo, mock := newTestRepository(t)
+		mock.ExpectQuery(regexp.QuoteMeta(
+			"SELECT id, name, email FROM users WHERE email IS NULL AND name = $1 ORDER BY name DESC, id ASC LIMIT 10 OFFSET 20",
+		)).WithArgs("Ana").
+			WillReturnRows(userRows().AddRow(int64(1), "Ana", "").AddRow(int64(2), "Ana", ""))
+
+		page := pagination.PageRequest{Limit: 10, Offset: 20, SortField: "name", SortDir: pagination.SortDesc}
+		users, err := repo.List(ctx, Filter{"name": "Ana", "email": nil}, page)
+		if err != nil {
+			t.Fatalf("List() error = %v", err)
+		}
+		if len(users) != 2 {
+			t.Errorf("List() returned %d rows, want 2", len(users))
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("unknown filter column", func(t *testing.T) {
+		t.Parallel()
+		repo, _ := newTestRepository(t)
+		_, err := repo.List(ctx, Filter{"password": "x"}, pagination.PageRequest{})
+		if !IsCode(err, CodeInvalidInput) {
+			t.Errorf("List() error = %v, want invalid input", err)
+		}
+	})
+
+	t.Run("unknown sort column", func(t *testing.T) {
+		t.Parallel()
+		repo, _ := newTestRepository(t)
+		_, err := repo.List(ctx, nil, pagination.PageRequest{SortField: "password"})
+		if !IsCode(err, CodeInvalidInput) {
+			t.Errorf("List() error = %v, want invalid input", err)
+		}
+	})
+}
+
+func TestRepository_Create(t *testing.T) {
+	t.Parallel()
+	repo, mock := newTestRepository(t)
+	mock.ExpectQuery(regexp.QuoteMeta(
+		"INSERT INTO users (name) VALUES ($1) RETURNING id, name, email",
+	)).WithArgs("Ana").
+		WillReturnRows(userRows().AddRow(int64(5), "Ana", "default@example.com"))
+
+	user := testUser{Name: "Ana"}
+	if err := repo.Create(context.Background(), &user); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if user.ID != 5 || user.Email != "default@example.com" {
+		t.Errorf("Create() user = %+v", user)
+	}
+}
+
+func TestRepository_Update(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	t.Run("selected fields", func(t *testing.T) {
+		t.Parallel()
+		repo, mock := newTestRepository(t)
+		mock.ExpectQuery(regexp.QuoteMeta(
+			"UPDATE users SET email = $1 WHERE id = $2 RETURNING id, name, email",
+		)).WithArgs("new@example.com", int64(5)).
+			WillReturnRows(userRows().AddRow(int64(5), "Ana", "new@example.com"))
+
+		user := testUser{ID: 5, Email: "new@example.com"}
+		if err := repo.Update(ctx, &user, "email"); err != nil {
+			t.Fatalf("Update() error = %v", err)
+		}
+		if user.Name != "Ana" {
+			t.Errorf("Update() user = %+v", user)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		t.Parallel()
+		repo, mock := newTestRepository(t)
+		mock.ExpectQuery("UPDATE users").WithArgs("Ana", int64(5)).WillReturnRows(userRows())
+
+		user := testUser{ID: 5, Name: "Ana"}
+		if err := repo.Update(ctx, &user); !IsNotFound(err) {
+			t.Errorf("Update() error = %v, want not found", err)
+		}
+	})
+
+	t.Run("read-only field", func(t *testing.T) {
+		t.Parallel()
+		repo, _ := newTestRepository(t)
+		user := testUser{ID: 5}
+		if err := repo.Update(ctx, &user, "id"); !IsCode(err, CodeInvalidInput) {
+			t.Errorf("Update() error = %v, want invalid input", err)
+		}
+	})
+}
+
+func TestRepository_Delete(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	query := regexp.QuoteMeta("DELETE FROM users WHERE id = $1")
+
+	t.Run("deleted", func(t *testing.T) {
+		t.Parallel()
+		repo, mock := newTestRepository(t)
+		mock.ExpectExec(query).WithArgs(int64(5)).WillReturnResult(pgxmock.NewResult("DELETE", 1))
+
+		if err := repo.Delete(ctx, 5); err != nil {
+			t.Errorf("Delete() error = %v", err)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		t.Parallel()
+		repo, mock := newTestRepository(t)
+		mock.ExpectExec(query).WithArgs(int64(5)).WillReturnResult(pgxmock.NewResult("DELETE", 0))
+
+		if err := repo.Delete(ctx, 5); !IsNotFound(err) {
+			t.Errorf("Delete() error = %v, want not found", err)
+		}
+	})
+
+	t.Run("foreign key violation", func(t *testing.T) {
+		t.Parallel()
+		repo, mock := newTestRepository(t)
+		mock.ExpectExec(query).WithArgs(int64(5)).
+			WillReturnError(&pgconn.PgError{Code: "23503", Message: "fk"})
+
+		if err := repo.Delete(ctx, 5); !IsForeignKey(err) {
+			t.Errorf("Delete() error = %v, want foreign key", err)
+		}
+	})
+}
+
+func TestRepository_Exists(t *testing.T) {
+	t.Parallel()
+	repo, mock := newTestRepository(t)
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)")).
+		WithArgs(int64(5)).
+		WillReturnRows(pgxmock.NewRows([]string{"exists"}).AddRow(true))
+
+	exists, err := repo.Exists(context.Background(), 5)
+	if err != nil {
+		t.Fatalf("Exists() error = %v", err)
+	}
+	if !exists {
+		t.Error("Exists() = false, want true")
+	}
+}
+
+func TestRepository_UsesContextTx(t *testing.T) {
+	t.Parallel()
+	repo, fallback := newTestRepository(t)
+	pool, txMock := newMockPool(t)
+	defer txMock.Close()
+	ctx := context.Background()
+
+	txMock.ExpectBegin()
+	txMock.ExpectExec("DELETE FROM users").WithArgs(int64(5)).WillReturnResult(pgxmock.NewResult("DELETE", 1))
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	if err := repo.Delete(ContextWithTx(ctx, tx), 5); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := txMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled tx expectations: %v", err)
+	}
+	if err := fallback.ExpectationsWereMet(); err != nil {
+		t.Errorf("unexpected fallback calls: %v", err)
+	}
+	if q := QuerierFromContext(ctx, fallback); q != fallback {
+		t.Error("QuerierFromContext() without tx should return fallback")
+	}
+}
@@ -0,0 +1,257 @@
+// Package postgres provides PostgreSQL database utilities for the Txova platform.
+package postgres
+
+import (
+	"errors"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// TxPhase identifies the stage of a transaction attempt in which an error occurred.
+type TxPhase string
+
+const (
+	// TxPhaseBegin indicates the error occurred while starting the transaction,
+	// before any statement was executed.
+	TxPhaseBegin TxPhase = "begin"
+	// TxPhaseExec indicates the error was returned by the transaction function.
+	TxPhaseExec TxPhase = "exec"
+	// TxPhaseCommit indicates the error occurred while committing the transaction.
+	TxPhaseCommit TxPhase = "commit"
+)
+
+// RetryClassifier reports whether an error returned during the given phase
+// of a transaction attempt may be retried.
+type RetryClassifier func(phase TxPhase, err error) bool
+
+// BackoffStrategy returns the delay to wait before the given retry (1-based).
+type BackoffStrategy func(retry int) time.Duration
+
+// RetryBudget limits the number of retries across all transactions sharing it.
+// It prevents retry storms during an outage by refusing retries once exhausted.
+type RetryBudget interface {
+	// Withdraw spends one retry from the budget.
+	// Returns false if the budget is exhausted and the retry must not happen.
+	Withdraw() bool
+
+	// Deposit credits the budget after a successful transaction.
+	Deposit()
+}
+
+// RetryPolicy controls how TxManager retries failed transaction attempts.
+type RetryPolicy interface {
+	// ShouldRetry reports whether an error from the given phase may be retried.
+	ShouldRetry(phase TxPhase, err error) bool
+
+	// Backoff returns the delay to wait before the given retry (1-based).
+	Backoff(retry int) time.Duration
+
+	// Budget returns the retry budget shared by this policy, or nil for no limit.
+	Budget() RetryBudget
+}
+
+// TxAttempt describes the outcome of a single transaction attempt.
+// It is passed to the TxManagerConfig.OnAttempt callback.
+type TxAttempt struct {
+	// Attempt is the 1-based attempt number.
+	Attempt int
+
+	// Phase is the phase in which the attempt failed, or TxPhaseCommit on success.
+	Phase TxPhase
+
+	// Err is the error returned by the attempt, or nil on success.
+	Err error
+
+	// Duration is how long the attempt took.
+	Duration time.Duration
+
+	// WillRetry reports whether another attempt will be made.
+	WillRetry bool
+
+	// Delay is the delay before the next attempt when WillRetry is true.
+	Delay time.Duration
+}
+
+// retryPolicy implements the RetryPolicy interface.
+type retryPolicy struct {
+	classifier RetryClassifier
+	backoff    BackoffStrategy
+	budget     RetryBudget
+}
+
+// NewRetryPolicy creates a RetryPolicy from a classifier, a backoff strategy and a budget.
+// A nil classifier uses DefaultRetryClassifier, a nil backoff uses ExponentialBackoff
+// with the TxManager defaults, and a nil budget allows unlimited retries.
+func NewRetryPolicy(classifier RetryClassifier, backoff BackoffStrategy, budget RetryBudget) RetryPolicy {
+	if classifier == nil {
+		classifier = DefaultRetryClassifier
+	}
+	if backoff == nil {
+		defaults := DefaultTxManagerConfig()
+		backoff = ExponentialBackoff(defaults.RetryBaseDelay, defaults.RetryMaxDelay)
+	}
+	return &retryPolicy{classifier: classifier, backoff: backoff, budget: budget}
+}
+
+// ShouldRetry reports whether an error from the given phase may be retried.
+func (p *retryPolicy) ShouldRetry(phase TxPhase, err error) bool {
+	return p.classifier(phase, err)
+}
+
+// Backoff returns the delay to wait before the given retry.
+func (p *retryPolicy) Backoff(retry int) time.Duration {
+	return p.backoff(retry)
+}
+
+// Budget returns the retry budget, or nil for no limit.
+func (p *retryPolicy) Budget() RetryBudget {
+	return p.budget
+}
+
+// DefaultRetryClassifier retries serialization failures and deadlocks in any phase.
+func DefaultRetryClassifier(_ TxPhase, err error) bool {
+	return isRetryable(err)
+}
+
+// RetryOnCodes returns a classifier that retries errors with any of the given codes.
+func RetryOnCodes(codes ...Code) RetryClassifier {
+	return func(_ TxPhase, err error) bool {
+		for _, code := range codes {
+			if IsCode(err, code) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// RetryOnSQLStates returns a classifier that retries errors with any of the given
+// PostgreSQL SQLSTATE codes, e.g. "55P03" (lock_not_available).
+func RetryOnSQLStates(states ...string) RetryClassifier {
+	return func(_ TxPhase, err error) bool {
+		dbErr := AsError(err)
+		if dbErr == nil {
+			return false
+		}
+		for _, state := range states {
+			if dbErr.SQLState() == state {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// RetryOnBeginConnectionError retries connection errors that occur while starting
+// the transaction. No statement has run at that point, so the retry is always safe.
+func RetryOnBeginConnectionError(phase TxPhase, err error) bool {
+	return phase == TxPhaseBegin && IsConnection(err)
+}
+
+// AnyRetryClassifier returns a classifier that retries when any of the given classifiers does.
+func AnyRetryClassifier(classifiers ...RetryClassifier) RetryClassifier {
+	return func(phase TxPhase, err error) bool {
+		for _, c := range classifiers {
+			if c(phase, err) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// ExponentialBackoff returns a backoff strategy using exponential growth from
+// baseDelay, capped at maxDelay, with ±25% jitter.
+func ExponentialBackoff(baseDelay, maxDelay time.Duration) BackoffStrategy {
+	return func(retry int) time.Duration {
+		// Bound retry to prevent overflow (max 30 gives ~1 billion multiplier)
+		if retry < 1 {
+			retry = 1
+		}
+		if retry > 30 {
+			retry = 30
+		}
+
+		// Exponential backoff: baseDelay * 2^(retry-1)
+		delay := baseDelay * time.Duration(1<<(retry-1))
+
+		// Cap at max delay.
+		if delay > maxDelay {
+			delay = maxDelay
+		}
+
+		// Add jitter (±25%) using math/rand which is acceptable for non-security purposes.
+		// Guard against zero/very-small delays that would cause rand.Int64N to panic.
+		var jitter time.Duration
+		halfWindow := int64(delay) / 2
+		if halfWindow > 0 {
+			jitter = time.Duration(rand.Int64N(halfWindow)) // #nosec G404 -- not security-sensitive
+		}
+		return delay - delay/4 + jitter
+	}
+}
+
+// ConstantBackoff returns a backoff strategy that always waits the same delay.
+func ConstantBackoff(delay time.Duration) BackoffStrategy {
+	return func(int) time.Duration {
+		return delay
+	}
+}
+
+// tokenRetryBudget implements RetryBudget as a token bucket.
+type tokenRetryBudget struct {
+	mu        sync.Mutex
+	tokens    float64
+	maxTokens float64
+	ratio     float64
+}
+
+// NewRetryBudget creates a token bucket RetryBudget shared by all transactions using it.
+// The bucket starts with maxTokens; each retry costs one token and each successful
+// transaction deposits ratio tokens (e.g. 0.1 earns one retry per ten successes).
+// During an outage no transaction succeeds, so retries stop once the bucket is empty.
+func NewRetryBudget(maxTokens int, ratio float64) RetryBudget {
+	if maxTokens < 0 {
+		maxTokens = 0
+	}
+	if ratio < 0 {
+		ratio = 0
+	}
+	return &tokenRetryBudget{
+		tokens:    float64(maxTokens),
+		maxTokens: float64(maxTokens),
+		ratio:     ratio,
+	}
+}
+
+// Withdraw spends one token if available.
+func (b *tokenRetryBudget) Withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// Deposit credits the budget with ratio tokens, up to maxTokens.
+func (b *tokenRetryBudget) Deposit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens += b.ratio
+	if b.tokens > b.maxTokens {
+		b.tokens = b.maxTokens
+	}
+}
+
+// isRetryable checks if the error is retryable (serialization failure or deadlock).
+func isRetryable(err error) bool {
+	var dbErr *Error
+	if errors.As(err, &dbErr) {
+		code := dbErr.Code()
+		return code == CodeSerialization || code == CodeDeadlock
+	}
+	return false
+}
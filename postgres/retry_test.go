@@ -0,0 +1,195 @@
+package postgres
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestRetryClassifiers(t *testing.T) {
+	t.Parallel()
+
+	lockNotAvailable := FromPgError(&pgconn.PgError{Code: "55P03", Message: "could not obtain lock"})
+
+	tests := []struct {
+		name       string
+		classifier RetryClassifier
+		phase      TxPhase
+		err        error
+		expected   bool
+	}{
+		{
+			name:       "default retries serialization",
+			classifier: DefaultRetryClassifier,
+			phase:      TxPhaseExec,
+			err:        New(CodeSerialization, "serialization failure"),
+			expected:   true,
+		},
+		{
+			name:       "default does not retry connection",
+			classifier: DefaultRetryClassifier,
+			phase:      TxPhaseBegin,
+			err:        New(CodeConnection, "connection refused"),
+			expected:   false,
+		},
+		{
+			name:       "codes match",
+			classifier: RetryOnCodes(CodeTimeout, CodeDeadlock),
+			phase:      TxPhaseExec,
+			err:        New(CodeTimeout, "timeout"),
+			expected:   true,
+		},
+		{
+			name:       "codes do not match",
+			classifier: RetryOnCodes(CodeTimeout),
+			phase:      TxPhaseExec,
+			err:        New(CodeDuplicate, "duplicate"),
+			expected:   false,
+		},
+		{
+			name:       "sqlstate match",
+			classifier: RetryOnSQLStates("55P03"),
+			phase:      TxPhaseExec,
+			err:        lockNotAvailable,
+			expected:   true,
+		},
+		{
+			name:       "sqlstate on non database error",
+			classifier: RetryOnSQLStates("55P03"),
+			phase:      TxPhaseExec,
+			err:        nil,
+			expected:   false,
+		},
+		{
+			name:       "connection error during begin",
+			classifier: RetryOnBeginConnectionError,
+			phase:      TxPhaseBegin,
+			err:        New(CodeConnection, "connection refused"),
+			expected:   true,
+		},
+		{
+			name:       "connection error during commit",
+			classifier: RetryOnBeginConnectionError,
+			phase:      TxPhaseCommit,
+			err:        New(CodeConnection, "connection reset"),
+			expected:   false,
+		},
+		{
+			name:       "any classifier",
+			classifier: AnyRetryClassifier(DefaultRetryClassifier, RetryOnSQLStates("55P03")),
+			phase:      TxPhaseExec,
+			err:        lockNotAvailable,
+			expected:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tt.classifier(tt.phase, tt.err); got != tt.expected {
+				t.Errorf("classifier(%s, %v) = %v, want %v", tt.phase, tt.err, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNewRetryPolicy_Defaults(t *testing.T) {
+	t.Parallel()
+
+	policy := NewRetryPolicy(nil, nil, nil)
+
+	if !policy.ShouldRetry(TxPhaseExec, New(CodeDeadlock, "deadlock")) {
+		t.Error("default policy should retry deadlocks")
+	}
+	if policy.ShouldRetry(TxPhaseExec, New(CodeNotFound, "not found")) {
+		t.Error("default policy should not retry not found")
+	}
+	if delay := policy.Backoff(1); delay <= 0 || delay > 100*time.Millisecond {
+		t.Errorf("Backoff(1) = %v, expected default exponential delay", delay)
+	}
+	if policy.Budget() != nil {
+		t.Error("default policy should not have a budget")
+	}
+}
+
+func TestConstantBackoff(t *testing.T) {
+	t.Parallel()
+
+	backoff := ConstantBackoff(30 * time.Millisecond)
+	for retry := 1; retry <= 5; retry++ {
+		if got := backoff(retry); got != 30*time.Millisecond {
+			t.Errorf("backoff(%d) = %v, want 30ms", retry, got)
+		}
+	}
+}
+
+func TestRetryBudget(t *testing.T) {
+	t.Parallel()
+
+	budget := NewRetryBudget(2, 0.5)
+
+	if !budget.Withdraw() || !budget.Withdraw() {
+		t.Fatal("expected two withdrawals to succeed")
+	}
+	if budget.Withdraw() {
+		t.Fatal("expected budget to be exhausted")
+	}
+
+	// Two successes earn one retry at ratio 0.5.
+	budget.Deposit()
+	if budget.Withdraw() {
+		t.Fatal("expected half a token to be insufficient")
+	}
+	budget.Deposit()
+	budget.Deposit()
+	if !budget.Withdraw() {
+		t.Fatal("expected deposits to restore a retry")
+	}
+}
+
+func TestRetryBudget_CappedAtMax(t *testing.T) {
+	t.Parallel()
+
+	budget := NewRetryBudget(1, 1)
+	for i := 0; i < 10; i++ {
+		budget.Deposit()
+	}
+
+	if !budget.Withdraw() {
+		t.Fatal("expected one withdrawal to succeed")
+	}
+	if budget.Withdraw() {
+		t.Error("expected deposits to be capped at max tokens")
+	}
+}
+
+func TestRetryBudget_Concurrent(t *testing.T) {
+	t.Parallel()
+
+	budget := NewRetryBudget(50, 0)
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		granted int
+	)
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if budget.Withdraw() {
+				mu.Lock()
+				granted++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if granted != 50 {
+		t.Errorf("granted = %d, want 50", granted)
+	}
+}
@@ -0,0 +1,259 @@
+// Package postgres provides PostgreSQL database utilities.
+package postgres
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Dorico-Dynamics/txova-go-types/pagination"
+)
+
+// SetOperator represents a SQL set operation combining SELECT queries.
+type SetOperator string
+
+const (
+	SetUnion     SetOperator = "UNION"
+	SetUnionAll  SetOperator = "UNION ALL"
+	SetIntersect SetOperator = "INTERSECT"
+	SetExcept    SetOperator = "EXCEPT"
+)
+
+// outputColumnRegex validates ORDER BY entries of a compound query, which may
+// only reference output column names or positions.
+var outputColumnRegex = regexp.MustCompile(`^([a-zA-Z_][a-zA-Z0-9_]*|[1-9][0-9]*)$`)
+
+// setOperand represents one SELECT of a compound query and the operator
+// combining it with the operands before it.
+type setOperand struct {
+	operator SetOperator
+	query    *SelectBuilder
+}
+
+// CompoundSelectBuilder builds queries combining SELECTs with
+// UNION, UNION ALL, INTERSECT and EXCEPT.
+// ORDER BY, LIMIT and OFFSET apply to the combined result.
+type CompoundSelectBuilder struct {
+	operands []setOperand
+	orderBy  []orderByClause
+	limit    *int
+	offset   *int
+}
+
+// newCompound creates a CompoundSelectBuilder combining queries with the operator.
+func newCompound(operator SetOperator, queries []*SelectBuilder) *CompoundSelectBuilder {
+	c := &CompoundSelectBuilder{
+		operands: make([]setOperand, 0, len(queries)),
+		orderBy:  []orderByClause{},
+	}
+	for _, q := range queries {
+		c.add(operator, q)
+	}
+	return c
+}
+
+// Union combines queries with UNION, removing duplicate rows.
+func Union(queries ...*SelectBuilder) *CompoundSelectBuilder {
+	return newCompound(SetUnion, queries)
+}
+
+// UnionAll combines queries with UNION ALL, keeping duplicate rows.
+func UnionAll(queries ...*SelectBuilder) *CompoundSelectBuilder {
+	return newCompound(SetUnionAll, queries)
+}
+
+// Intersect combines queries with INTERSECT.
+func Intersect(queries ...*SelectBuilder) *CompoundSelectBuilder {
+	return newCompound(SetIntersect, queries)
+}
+
+// Except combines queries with EXCEPT.
+func Except(queries ...*SelectBuilder) *CompoundSelectBuilder {
+	return newCompound(SetExcept, queries)
+}
+
+// add appends an operand combined with the operator.
+func (c *CompoundSelectBuilder) add(operator SetOperator, query *SelectBuilder) {
+	c.operands = append(c.operands, setOperand{operator: operator, query: query})
+}
+
+// Union adds a query combined with UNION.
+// Operations are applied left to right.
+func (c *CompoundSelectBuilder) Union(query *SelectBuilder) *CompoundSelectBuilder {
+	c.add(SetUnion, query)
+	return c
+}
+
+// UnionAll adds a query combined with UNION ALL.
+func (c *CompoundSelectBuilder) UnionAll(query *SelectBuilder) *CompoundSelectBuilder {
+	c.add(SetUnionAll, query)
+	return c
+}
+
+// Intersect adds a query combined with INTERSECT.
+func (c *CompoundSelectBuilder) Intersect(query *SelectBuilder) *CompoundSelectBuilder {
+	c.add(SetIntersect, query)
+	return c
+}
+
+// Except adds a query combined with EXCEPT.
+func (c *CompoundSelectBuilder) Except(query *SelectBuilder) *CompoundSelectBuilder {
+	c.add(SetExcept, query)
+	return c
+}
+
+// OrderBy adds an ORDER BY clause on the combined result.
+// Only output column names or positions may be used.
+func (c *CompoundSelectBuilder) OrderBy(column string, direction pagination.SortDirection) *CompoundSelectBuilder {
+	c.orderBy = append(c.orderBy, orderByClause{column: column, direction: direction})
+	return c
+}
+
+// OrderByAsc adds an ascending ORDER BY clause on the combined result.
+func (c *CompoundSelectBuilder) OrderByAsc(column string) *CompoundSelectBuilder {
+	return c.OrderBy(column, pagination.SortAsc)
+}
+
+// OrderByDesc adds a descending ORDER BY clause on the combined result.
+func (c *CompoundSelectBuilder) OrderByDesc(column string) *CompoundSelectBuilder {
+	return c.OrderBy(column, pagination.SortDesc)
+}
+
+// Limit sets the LIMIT clause on the combined result.
+func (c *CompoundSelectBuilder) Limit(limit int) *CompoundSelectBuilder {
+	c.limit = &limit
+	return c
+}
+
+// Offset sets the OFFSET clause on the combined result.
+func (c *CompoundSelectBuilder) Offset(offset int) *CompoundSelectBuilder {
+	c.offset = &offset
+	return c
+}
+
+// validate implements Builder.
+func (c *CompoundSelectBuilder) validate() error {
+	if len(c.operands) < 2 {
+		return fmt.Errorf("set operation requires at least two queries")
+	}
+	columnCount := -1
+	for idx, operand := range c.operands {
+		if operand.query == nil {
+			return fmt.Errorf("set operation query %d is nil", idx)
+		}
+		if err := operand.query.validateSelect(); err != nil {
+			return fmt.Errorf("invalid set operation query %d: %w", idx, err)
+		}
+		count, ok := operand.query.explicitColumnCount()
+		if !ok {
+			continue
+		}
+		if columnCount >= 0 && count != columnCount {
+			return fmt.Errorf("set operation query %d has %d columns but %d expected", idx, count, columnCount)
+		}
+		columnCount = count
+	}
+	for _, entry := range c.orderBy {
+		if !outputColumnRegex.MatchString(entry.column) {
+			return fmt.Errorf("invalid order by column: %q", entry.column)
+		}
+	}
+	return nil
+}
+
+// explicitColumnCount returns the number of selected columns,
+// or false if the columns are not listed explicitly.
+func (s *SelectBuilder) explicitColumnCount() (int, bool) {
+	if len(s.columns) == 0 {
+		return 0, false
+	}
+	for _, col := range s.columns {
+		if strings.HasSuffix(strings.TrimSpace(col.name), "*") {
+			return 0, false
+		}
+	}
+	return len(s.columns) + len(s.selectExprs()), true
+}
+
+// needsParentheses reports whether a SELECT must be parenthesized as a set operand.
+func (s *SelectBuilder) needsParentheses() bool {
+	return len(s.with.ctes) > 0 || len(s.orderBy) > 0 || s.limit != nil || s.offset != nil ||
+		s.keyset != nil || len(s.locks) > 0
+}
+
+// Build generates the SQL query and returns it with the arguments.
+func (c *CompoundSelectBuilder) Build() (string, []any, error) {
+	if err := c.validate(); err != nil {
+		return "", nil, err
+	}
+	sql, args, _ := c.build(1)
+	return sql, args, nil
+}
+
+// build generates the SQL with placeholders numbered from argIndex,
+// returning the arguments and the next arg index.
+// The builder must have been validated.
+func (c *CompoundSelectBuilder) build(argIndex int) (string, []any, int) {
+	var sb strings.Builder
+	var args []any
+	mixed := false
+	for idx, operand := range c.operands {
+		if idx > 0 {
+			// INTERSECT binds tighter than UNION and EXCEPT, so parenthesize
+			// the preceding operations to keep left-to-right evaluation.
+			if operand.operator == SetIntersect && mixed {
+				left := sb.String()
+				sb.Reset()
+				sb.WriteString("(")
+				sb.WriteString(left)
+				sb.WriteString(")")
+				mixed = false
+			}
+			if operand.operator != SetIntersect {
+				mixed = true
+			}
+			sb.WriteString(" ")
+			sb.WriteString(string(operand.operator))
+			sb.WriteString(" ")
+		}
+		querySQL, queryArgs, newIndex := operand.query.build(argIndex)
+		if operand.query.needsParentheses() {
+			querySQL = "(" + querySQL + ")"
+		}
+		sb.WriteString(querySQL)
+		args = append(args, queryArgs...)
+		argIndex = newIndex
+	}
+	sb.WriteString(buildOrderByClauses(c.orderBy))
+	sb.WriteString(buildLimitOffset(c.limit, c.offset))
+	return sb.String(), args, argIndex
+}
+
+// MustBuild generates the SQL query and panics on error.
+func (c *CompoundSelectBuilder) MustBuild() (string, []any) {
+	sql, args, err := c.Build()
+	if err != nil {
+		panic(fmt.Sprintf("query build error: %v", err))
+	}
+	return sql, args
+}
+
+// SQL returns only the SQL string (for debugging).
+// Returns empty string if build fails.
+func (c *CompoundSelectBuilder) SQL() string {
+	sql, _, err := c.Build()
+	if err != nil {
+		return ""
+	}
+	return sql
+}
+
+// Args returns only the arguments (for debugging).
+// Returns nil if build fails.
+func (c *CompoundSelectBuilder) Args() []any {
+	_, args, err := c.Build()
+	if err != nil {
+		return nil
+	}
+	return args
+}
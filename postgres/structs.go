@@ -0,0 +1,342 @@
+// Package postgres provides PostgreSQL database utilities.
+package postgres
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Struct mapping uses `db` struct tags:
+//
+//	type Trip struct {
+//	    ID        int64     `db:"id,readonly"`
+//	    Status    string    `db:"status"`
+//	    Notes     string    `db:"notes,omitempty"`
+//	    CreatedAt time.Time `db:"created_at,readonly"`
+//	    Audit               // embedded structs are flattened
+//	    Internal  string    `db:"-"`
+//	}
+//
+// Only tagged fields are mapped. Column names are unqualified identifiers and fold
+// to lower case as in SQL; quote them to keep their case, e.g. `db:"\"CreatedAt\""`.
+// Options:
+//   - omitempty: zero values are left out of INSERT and UPDATE, so column defaults apply.
+//   - readonly: the column is selected and scanned but never inserted or updated.
+
+// structField describes a struct field mapped to a column.
+// column is the name as written in SQL, quoted if needed.
+type structField struct {
+	column    string
+	index     []int
+	omitEmpty bool
+	readOnly  bool
+}
+
+// structMeta describes the columns of a struct type.
+// byColumn is keyed by the column name as PostgreSQL reports it in results.
+type structMeta struct {
+	fields   []structField
+	byColumn map[string]int
+}
+
+// structMetaCache caches structMeta by reflect.Type.
+var structMetaCache sync.Map
+
+// getStructMeta returns the cached column metadata of a struct type.
+func getStructMeta(t reflect.Type) (*structMeta, error) {
+	if cached, ok := structMetaCache.Load(t); ok {
+		return cached.(*structMeta), nil
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, New(CodeInvalidInput, fmt.Sprintf("expected struct, got %s", t))
+	}
+	meta := &structMeta{byColumn: make(map[string]int)}
+	if err := meta.collect(t, nil); err != nil {
+		return nil, err
+	}
+	if len(meta.fields) == 0 {
+		return nil, New(CodeInvalidInput, fmt.Sprintf("struct %s has no db tagged fields", t))
+	}
+	cached, _ := structMetaCache.LoadOrStore(t, meta)
+	return cached.(*structMeta), nil
+}
+
+// collect adds the tagged fields of t, flattening untagged embedded structs.
+func (m *structMeta) collect(t reflect.Type, parent []int) error {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		index := append(append([]int{}, parent...), i)
+		tag, tagged := f.Tag.Lookup("db")
+		if tag == "-" {
+			continue
+		}
+		if !tagged {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				if !f.IsExported() {
+					// Unexported embedded pointers cannot be allocated when scanning.
+					continue
+				}
+				ft = ft.Elem()
+			}
+			if f.Anonymous && ft.Kind() == reflect.Struct {
+				if err := m.collect(ft, index); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if !f.IsExported() {
+			return New(CodeInvalidInput, fmt.Sprintf("db tagged field %s is unexported", f.Name))
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+		parts, err := parseIdentifier(name, 1)
+		if err != nil {
+			return New(CodeInvalidInput, fmt.Sprintf("invalid column name in db tag of %s: %q", f.Name, name))
+		}
+		if _, ok := m.byColumn[parts[0]]; ok {
+			return New(CodeInvalidInput, fmt.Sprintf("duplicate db column %s", name))
+		}
+		field := structField{column: formatIdentifier(parts), index: index}
+		for _, opt := range strings.Split(opts, ",") {
+			switch opt {
+			case "":
+			case "omitempty":
+				field.omitEmpty = true
+			case "readonly":
+				field.readOnly = true
+			default:
+				return New(CodeInvalidInput, fmt.Sprintf("unknown db tag option on %s: %q", f.Name, opt))
+			}
+		}
+		m.byColumn[parts[0]] = len(m.fields)
+		m.fields = append(m.fields, field)
+	}
+	return nil
+}
+
+// lookup returns the index of the field mapped to a column name written as in SQL.
+func (m *structMeta) lookup(column string) (int, bool) {
+	parts, err := parseIdentifier(column, 1)
+	if err != nil {
+		return 0, false
+	}
+	idx, ok := m.byColumn[parts[0]]
+	return idx, ok
+}
+
+// columns returns all mapped column names in field order.
+func (m *structMeta) columns() []string {
+	cols := make([]string, len(m.fields))
+	for i, f := range m.fields {
+		cols[i] = f.column
+	}
+	return cols
+}
+
+// writable returns the columns and values to write from v.
+// If fields is empty, all non-readonly columns are returned, leaving out
+// zero omitempty fields. Otherwise only the named columns are returned.
+func (m *structMeta) writable(v reflect.Value, fields []string) ([]string, []any, error) {
+	var cols []string
+	var vals []any
+	if len(fields) > 0 {
+		for _, col := range fields {
+			idx, ok := m.lookup(col)
+			if !ok {
+				return nil, nil, New(CodeInvalidInput, fmt.Sprintf("unknown column %s", col))
+			}
+			f := m.fields[idx]
+			if f.readOnly {
+				return nil, nil, New(CodeInvalidInput, fmt.Sprintf("column %s is read-only", col))
+			}
+			fv, _ := v.FieldByIndexErr(f.index)
+			cols = append(cols, f.column)
+			vals = append(vals, fieldValue(fv))
+		}
+		return cols, vals, nil
+	}
+	for _, f := range m.fields {
+		if f.readOnly {
+			continue
+		}
+		fv, err := v.FieldByIndexErr(f.index)
+		if f.omitEmpty && (err != nil || fv.IsZero()) {
+			continue
+		}
+		cols = append(cols, f.column)
+		vals = append(vals, fieldValue(fv))
+	}
+	return cols, vals, nil
+}
+
+// fieldValue returns the value of a field, or nil if it is behind a nil embedded pointer.
+func fieldValue(fv reflect.Value) any {
+	if !fv.IsValid() {
+		return nil
+	}
+	return fv.Interface()
+}
+
+// structValue dereferences v and returns it with its metadata.
+func structValue(v any) (reflect.Value, *structMeta, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return reflect.Value{}, nil, New(CodeInvalidInput, "struct value cannot be nil")
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return reflect.Value{}, nil, New(CodeInvalidInput, "struct value cannot be nil")
+	}
+	meta, err := getStructMeta(rv.Type())
+	if err != nil {
+		return reflect.Value{}, nil, err
+	}
+	return rv, meta, nil
+}
+
+// ColumnsOf returns the db tagged column names of struct type T in field order,
+// including read-only columns. It returns nil if T is not a struct with db tags.
+// Use it for column lists and allowlists:
+//
+//	Select("trips").Columns(ColumnsOf[Trip]()...)
+func ColumnsOf[T any]() []string {
+	meta, err := getStructMeta(reflect.TypeFor[T]())
+	if err != nil {
+		return nil
+	}
+	return meta.columns()
+}
+
+// InsertStruct creates an InsertBuilder inserting the db tagged fields of v,
+// a struct or pointer to struct. Read-only fields and zero omitempty fields are
+// left out. The allowlist is derived from the struct, so RETURNING may use any
+// of its columns.
+func InsertStruct(table string, v any) *InsertBuilder {
+	rv, meta, err := structValue(v)
+	if err != nil {
+		b := Insert(table)
+		b.structErr = err
+		return b
+	}
+	cols, vals, _ := meta.writable(rv, nil)
+	return InsertWithAllowlist(table, meta.columns()...).Columns(cols...).Values(vals...)
+}
+
+// UpdateStruct creates an UpdateBuilder setting the db tagged fields of v,
+// a struct or pointer to struct. If fields are given, only those columns are set;
+// otherwise all fields except read-only and zero omitempty fields are set.
+// The allowlist is derived from the struct.
+func UpdateStruct(table string, v any, fields ...string) *UpdateBuilder {
+	rv, meta, err := structValue(v)
+	if err == nil {
+		var cols []string
+		var vals []any
+		cols, vals, err = meta.writable(rv, fields)
+		if err == nil {
+			b := UpdateWithAllowlist(table, meta.columns()...)
+			for i, col := range cols {
+				b.Set(col, vals[i])
+			}
+			return b
+		}
+	}
+	b := Update(table)
+	b.structErr = err
+	return b
+}
+
+// ScanOne scans the first row into a T, a struct with db tags, and closes rows.
+// Every result column must map to a field.
+// Returns a CodeNotFound error wrapping pgx.ErrNoRows if there are no rows.
+func ScanOne[T any](rows pgx.Rows) (T, error) {
+	var zero T
+	defer rows.Close()
+	scan, err := newStructScanner[T](rows)
+	if err != nil {
+		return zero, err
+	}
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return zero, FromPgError(err)
+		}
+		return zero, Wrap(CodeNotFound, "no rows in result set", pgx.ErrNoRows)
+	}
+	var item T
+	if err := scan(&item); err != nil {
+		return zero, err
+	}
+	return item, nil
+}
+
+// ScanAll scans all rows into a slice of T, a struct with db tags, and closes rows.
+// Every result column must map to a field.
+func ScanAll[T any](rows pgx.Rows) ([]T, error) {
+	defer rows.Close()
+	scan, err := newStructScanner[T](rows)
+	if err != nil {
+		return nil, err
+	}
+	items := []T{}
+	for rows.Next() {
+		var item T
+		if err := scan(&item); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, FromPgError(err)
+	}
+	return items, nil
+}
+
+// newStructScanner returns a function scanning the current row into a *T,
+// mapping result columns to fields by name.
+func newStructScanner[T any](rows pgx.Rows) (func(*T) error, error) {
+	meta, err := getStructMeta(reflect.TypeFor[T]())
+	if err != nil {
+		return nil, err
+	}
+	descriptions := rows.FieldDescriptions()
+	indexes := make([][]int, len(descriptions))
+	for i, fd := range descriptions {
+		idx, ok := meta.byColumn[fd.Name]
+		if !ok {
+			return nil, New(CodeInvalidInput, fmt.Sprintf("column %s has no matching struct field", fd.Name))
+		}
+		indexes[i] = meta.fields[idx].index
+	}
+	return func(item *T) error {
+		rv := reflect.ValueOf(item).Elem()
+		dest := make([]any, len(indexes))
+		for i, index := range indexes {
+			dest[i] = fieldByIndexAlloc(rv, index).Addr().Interface()
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return FromPgError(err)
+		}
+		return nil
+	}, nil
+}
+
+// fieldByIndexAlloc returns the nested field, allocating nil embedded pointers.
+func fieldByIndexAlloc(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
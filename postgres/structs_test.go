@@ -0,0 +1,305 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+type testAudit struct {
+	CreatedBy string `db:"created_by,readonly"`
+	UpdatedBy string `db:"updated_by"`
+}
+
+type testTrip struct {
+	ID     int64  `db:"id,readonly"`
+	Status string `db:"status"`
+	Notes  string `db:"notes,omitempty"`
+	testAudit
+	Internal string `db:"-"`
+	Ignored  string
+}
+
+type testDriverProfile struct {
+	Rating float64 `db:"rating"`
+}
+
+type testDriver struct {
+	ID int64 `db:"id"`
+	*testDriverProfile
+	*DriverBio
+}
+
+type DriverBio struct {
+	Bio string `db:"bio"`
+}
+
+type testMixedCase struct {
+	ID        int64  `db:"ID"`
+	UpdatedBy string `db:"\"UpdatedBy\""`
+}
+
+func TestColumnsOf(t *testing.T) {
+	t.Parallel()
+
+	got := ColumnsOf[testTrip]()
+	want := []string{"id", "status", "notes", "created_by", "updated_by"}
+	if len(got) != len(want) {
+		t.Fatalf("ColumnsOf() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("ColumnsOf()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	if cols := ColumnsOf[int](); cols != nil {
+		t.Errorf("ColumnsOf[int]() = %v, want nil", cols)
+	}
+	if cols := ColumnsOf[testDriver](); len(cols) != 2 || cols[1] != "bio" {
+		t.Errorf("ColumnsOf[testDriver]() = %v, want [id bio]", cols)
+	}
+	if cols := ColumnsOf[testMixedCase](); len(cols) != 2 || cols[0] != "id" || cols[1] != `"UpdatedBy"` {
+		t.Errorf("ColumnsOf[testMixedCase]() = %v, want [id \"UpdatedBy\"]", cols)
+	}
+}
+
+func TestStructBuilders(t *testing.T) {
+	t.Parallel()
+
+	trip := testTrip{ID: 1, Status: "active", testAudit: testAudit{CreatedBy: "a", UpdatedBy: "b"}}
+
+	tests := []struct {
+		name        string
+		builder     Builder
+		wantSQL     string
+		wantArgs    []any
+		errContains string
+	}{
+		{
+			name:     "insert skips readonly and empty omitempty",
+			builder:  InsertStruct("trips", trip).Returning("id", "created_by"),
+			wantSQL:  "INSERT INTO trips (status, updated_by) VALUES ($1, $2) RETURNING id, created_by",
+			wantArgs: []any{"active", "b"},
+		},
+		{
+			name:     "insert includes set omitempty",
+			builder:  InsertStruct("trips", &testTrip{Status: "done", Notes: "late"}),
+			wantSQL:  "INSERT INTO trips (status, notes, updated_by) VALUES ($1, $2, $3)",
+			wantArgs: []any{"done", "late", ""},
+		},
+		{
+			name:        "insert returning outside struct allowlist",
+			builder:     InsertStruct("trips", trip).Returning("secret"),
+			errContains: "invalid returning column",
+		},
+		{
+			name:     "update all writable fields",
+			builder:  UpdateStruct("trips", trip).Where("id = ?", trip.ID),
+			wantSQL:  "UPDATE trips SET status = $1, updated_by = $2 WHERE id = $3",
+			wantArgs: []any{"active", "b", int64(1)},
+		},
+		{
+			name:     "update selected fields",
+			builder:  UpdateStruct("trips", &trip, "notes").Where("id = ?", trip.ID),
+			wantSQL:  "UPDATE trips SET notes = $1 WHERE id = $2",
+			wantArgs: []any{"", int64(1)},
+		},
+		{
+			name:        "update readonly field",
+			builder:     UpdateStruct("trips", trip, "id"),
+			errContains: "column id is read-only",
+		},
+		{
+			name:        "update unknown field",
+			builder:     UpdateStruct("trips", trip, "missing"),
+			errContains: "unknown column missing",
+		},
+		{
+			name:        "insert non struct",
+			builder:     InsertStruct("trips", 42),
+			errContains: "expected struct",
+		},
+		{
+			name:        "insert nil pointer",
+			builder:     InsertStruct("trips", (*testTrip)(nil)),
+			errContains: "struct value cannot be nil",
+		},
+		{
+			name:     "insert with nil embedded pointer",
+			builder:  InsertStruct("drivers", testDriver{ID: 3}),
+			wantSQL:  "INSERT INTO drivers (id, bio) VALUES ($1, $2)",
+			wantArgs: []any{int64(3), nil},
+		},
+		{
+			name:     "insert folded and quoted columns",
+			builder:  InsertStruct("drivers", testMixedCase{ID: 3, UpdatedBy: "ana"}),
+			wantSQL:  `INSERT INTO drivers (id, "UpdatedBy") VALUES ($1, $2)`,
+			wantArgs: []any{int64(3), "ana"},
+		},
+		{
+			name:     "update quoted field",
+			builder:  UpdateStruct("drivers", testMixedCase{ID: 3, UpdatedBy: "ana"}, `"UpdatedBy"`).Where("id = ?", 3),
+			wantSQL:  `UPDATE drivers SET "UpdatedBy" = $1 WHERE id = $2`,
+			wantArgs: []any{"ana", 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assertBuild(t, tt.builder, tt.wantSQL, tt.wantArgs, tt.errContains)
+		})
+	}
+}
+
+func TestStructMetaErrors(t *testing.T) {
+	t.Parallel()
+
+	type badOption struct {
+		ID int `db:"id,primary"`
+	}
+	type duplicate struct {
+		A int `db:"id"`
+		B int `db:"id"`
+	}
+	type badName struct {
+		A int `db:"id; DROP"`
+	}
+	type dottedName struct {
+		A int `db:"trips.id"`
+	}
+	type foldedDuplicate struct {
+		A int `db:"Id"`
+		B int `db:"id"`
+	}
+	type untagged struct {
+		A int
+	}
+
+	tests := []struct {
+		name  string
+		value any
+	}{
+		{"unknown option", badOption{}},
+		{"duplicate column", duplicate{}},
+		{"invalid column name", badName{}},
+		{"qualified column name", dottedName{}},
+		{"duplicate folded column", foldedDuplicate{}},
+		{"no tagged fields", untagged{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if _, _, err := InsertStruct("t", tt.value).Build(); !IsCode(err, CodeInvalidInput) {
+				t.Errorf("Build() error = %v, want invalid input", err)
+			}
+		})
+	}
+}
+
+func TestScanStructs(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	query := func(t *testing.T, rows *pgxmock.Rows) pgx.Rows {
+		t.Helper()
+		mock, err := pgxmock.NewPool()
+		if err != nil {
+			t.Fatalf("failed to create mock: %v", err)
+		}
+		t.Cleanup(mock.Close)
+		mock.ExpectQuery("SELECT").WillReturnRows(rows)
+		result, err := mock.Query(ctx, "SELECT")
+		if err != nil {
+			t.Fatalf("Query() error = %v", err)
+		}
+		return result
+	}
+
+	t.Run("scan all", func(t *testing.T) {
+		t.Parallel()
+		rows := pgxmock.NewRows([]string{"id", "status", "created_by"}).
+			AddRow(int64(1), "active", "a").
+			AddRow(int64(2), "done", "b")
+
+		trips, err := ScanAll[testTrip](query(t, rows))
+		if err != nil {
+			t.Fatalf("ScanAll() error = %v", err)
+		}
+		if len(trips) != 2 {
+			t.Fatalf("ScanAll() returned %d rows, want 2", len(trips))
+		}
+		if trips[1].ID != 2 || trips[1].Status != "done" || trips[1].CreatedBy != "b" {
+			t.Errorf("ScanAll()[1] = %+v", trips[1])
+		}
+	})
+
+	t.Run("scan all empty", func(t *testing.T) {
+		t.Parallel()
+		trips, err := ScanAll[testTrip](query(t, pgxmock.NewRows([]string{"id"})))
+		if err != nil {
+			t.Fatalf("ScanAll() error = %v", err)
+		}
+		if trips == nil || len(trips) != 0 {
+			t.Errorf("ScanAll() = %v, want empty slice", trips)
+		}
+	})
+
+	t.Run("scan one allocates embedded pointers", func(t *testing.T) {
+		t.Parallel()
+		rows := pgxmock.NewRows([]string{"id", "bio"}).AddRow(int64(7), "hi")
+
+		driver, err := ScanOne[testDriver](query(t, rows))
+		if err != nil {
+			t.Fatalf("ScanOne() error = %v", err)
+		}
+		if driver.ID != 7 || driver.DriverBio == nil || driver.Bio != "hi" {
+			t.Errorf("ScanOne() = %+v", driver)
+		}
+		if driver.testDriverProfile != nil {
+			t.Errorf("unexported embedded pointer should be skipped, got %+v", driver.testDriverProfile)
+		}
+	})
+
+	t.Run("scan folded and quoted columns", func(t *testing.T) {
+		t.Parallel()
+		rows := pgxmock.NewRows([]string{"id", "UpdatedBy"}).AddRow(int64(3), "ana")
+
+		item, err := ScanOne[testMixedCase](query(t, rows))
+		if err != nil {
+			t.Fatalf("ScanOne() error = %v", err)
+		}
+		if item.ID != 3 || item.UpdatedBy != "ana" {
+			t.Errorf("ScanOne() = %+v", item)
+		}
+	})
+
+	t.Run("scan one no rows", func(t *testing.T) {
+		t.Parallel()
+		_, err := ScanOne[testTrip](query(t, pgxmock.NewRows([]string{"id"})))
+		if !IsNotFound(err) || !errors.Is(err, pgx.ErrNoRows) {
+			t.Errorf("ScanOne() error = %v, want not found", err)
+		}
+	})
+
+	t.Run("unknown column", func(t *testing.T) {
+		t.Parallel()
+		rows := pgxmock.NewRows([]string{"id", "secret"}).AddRow(int64(1), "x")
+		_, err := ScanAll[testTrip](query(t, rows))
+		if !IsCode(err, CodeInvalidInput) {
+			t.Errorf("ScanAll() error = %v, want invalid input", err)
+		}
+	})
+
+	t.Run("rows error", func(t *testing.T) {
+		t.Parallel()
+		rows := pgxmock.NewRows([]string{"id"}).AddRow(int64(1)).RowError(0, errors.New("boom"))
+		if _, err := ScanAll[testTrip](query(t, rows)); err == nil {
+			t.Error("ScanAll() error = nil, want error")
+		}
+	})
+}
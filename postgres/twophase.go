@@ -0,0 +1,496 @@
+// Package postgres provides PostgreSQL database utilities for the Txova platform.
+package postgres
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Dorico-Dynamics/txova-go-core/logging"
+)
+
+// gidRegex restricts global transaction identifiers to characters that are safe
+// to embed in a string literal. PostgreSQL limits identifiers to 200 bytes.
+var gidRegex = regexp.MustCompile(`^[A-Za-z0-9_.:-]{1,199}$`)
+
+// participantNameRegex validates two-phase participant names.
+var participantNameRegex = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// validateGID checks if a global transaction identifier is valid.
+func validateGID(gid string) error {
+	if !gidRegex.MatchString(gid) {
+		return fmt.Errorf("invalid gid: %q", gid)
+	}
+	return nil
+}
+
+// Default two-phase commit settings.
+const (
+	// DefaultTwoPhaseGIDPrefix is the default prefix for global transaction identifiers.
+	DefaultTwoPhaseGIDPrefix = "txova2pc_"
+	// DefaultTwoPhaseRecoveryMinAge is the default minimum age of an undecided
+	// prepared transaction before recovery rolls it back.
+	DefaultTwoPhaseRecoveryMinAge = time.Minute
+)
+
+// DecisionLog durably records decisions for two-phase transactions.
+// The coordinator records a commit decision after all participants have prepared
+// and before any COMMIT PREPARED is issued. Recovery records an abort decision
+// before rolling back an undecided transaction, so that a coordinator resuming
+// after recovery can no longer commit it. Only one decision can be recorded per
+// global transaction.
+type DecisionLog interface {
+	// RecordCommit durably records that the global transaction must be committed.
+	// It fails if an abort decision was already recorded.
+	RecordCommit(ctx context.Context, gid string) error
+
+	// RecordAbort durably records that the global transaction must be rolled back,
+	// unless a commit decision was recorded first. It reports whether the global
+	// transaction is committed.
+	RecordAbort(ctx context.Context, gid string) (bool, error)
+
+	// IsCommitted reports whether a commit decision was recorded for the global transaction.
+	IsCommitted(ctx context.Context, gid string) (bool, error)
+
+	// Forget removes the decision once every participant has committed.
+	Forget(ctx context.Context, gid string) error
+}
+
+// TwoPhaseParticipant is a database taking part in a two-phase commit.
+type TwoPhaseParticipant struct {
+	// Name identifies the participant. It must be stable across restarts,
+	// since recovery uses it to match prepared transactions to pools.
+	Name string
+
+	// Pool is the connection pool of the participant database.
+	Pool Pool
+
+	// Fn performs the participant's work within its transaction.
+	Fn func(tx Tx) error
+}
+
+// TwoPhaseRecovery reports the prepared transactions resolved by Recover.
+type TwoPhaseRecovery struct {
+	// Committed lists the participant gids finished with COMMIT PREPARED.
+	Committed []string
+
+	// RolledBack lists the participant gids finished with ROLLBACK PREPARED.
+	RolledBack []string
+}
+
+// TwoPhaseCoordinator runs transactions atomically across several databases
+// using PREPARE TRANSACTION and COMMIT PREPARED.
+// Participant databases must have max_prepared_transactions greater than zero.
+type TwoPhaseCoordinator struct {
+	decisions      DecisionLog
+	logger         *logging.Logger
+	gidPrefix      string
+	recoveryMinAge time.Duration
+}
+
+// TwoPhaseOption is a functional option for configuring the TwoPhaseCoordinator.
+type TwoPhaseOption func(*TwoPhaseCoordinator)
+
+// WithTwoPhaseGIDPrefix sets the prefix used for global transaction identifiers.
+// Recovery only touches prepared transactions with this prefix.
+func WithTwoPhaseGIDPrefix(prefix string) TwoPhaseOption {
+	return func(c *TwoPhaseCoordinator) {
+		c.gidPrefix = prefix
+	}
+}
+
+// WithTwoPhaseRecoveryMinAge sets the minimum age of an undecided prepared
+// transaction before recovery rolls it back. It must exceed the time a
+// coordinator takes between preparing and recording its decision.
+func WithTwoPhaseRecoveryMinAge(d time.Duration) TwoPhaseOption {
+	return func(c *TwoPhaseCoordinator) {
+		c.recoveryMinAge = d
+	}
+}
+
+// WithTwoPhaseLogger sets the logger for two-phase commit events.
+func WithTwoPhaseLogger(logger *logging.Logger) TwoPhaseOption {
+	return func(c *TwoPhaseCoordinator) {
+		c.logger = logger
+	}
+}
+
+// NewTwoPhaseCoordinator creates a new TwoPhaseCoordinator using the given decision log.
+func NewTwoPhaseCoordinator(decisions DecisionLog, opts ...TwoPhaseOption) *TwoPhaseCoordinator {
+	c := &TwoPhaseCoordinator{
+		decisions:      decisions,
+		logger:         logging.Default(),
+		gidPrefix:      DefaultTwoPhaseGIDPrefix,
+		recoveryMinAge: DefaultTwoPhaseRecoveryMinAge,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Execute runs every participant's function in its own transaction and commits
+// them atomically. If any function or PREPARE fails, all prepared participants
+// are rolled back and the error is returned. Once the commit decision is recorded,
+// participants that fail to commit are left for Recover to finish, and an error
+// with CodeConnection is returned. If Recover aborted the transaction before its
+// commit decision was recorded, all participants are rolled back and an error
+// with CodeDuplicate is returned.
+func (c *TwoPhaseCoordinator) Execute(ctx context.Context, participants ...TwoPhaseParticipant) error {
+	if err := c.validateParticipants(participants); err != nil {
+		return Wrap(CodeInvalidInput, "invalid two-phase participants", err)
+	}
+
+	gid, err := c.newGID()
+	if err != nil {
+		return Wrap(CodeInternal, "failed to generate global transaction identifier", err)
+	}
+
+	// Phase 1: run and prepare every participant.
+	prepared := make([]TwoPhaseParticipant, 0, len(participants))
+	for _, p := range participants {
+		if err := c.prepare(ctx, gid, p); err != nil {
+			c.rollbackPrepared(ctx, gid, prepared)
+			return err
+		}
+		prepared = append(prepared, p)
+	}
+
+	// The decision must be durable before any participant commits.
+	if err := c.decisions.RecordCommit(ctx, gid); err != nil {
+		c.rollbackPrepared(ctx, gid, prepared)
+		if IsDuplicate(err) {
+			return Wrap(CodeDuplicate, "two-phase transaction "+gid+" was aborted by recovery", err)
+		}
+		return Wrap(GetCode(err), "failed to record two-phase commit decision", err)
+	}
+
+	// Phase 2: commit every participant.
+	var failed []string
+	for _, p := range prepared {
+		participantGID := participantGID(gid, p.Name)
+		if _, err := p.Pool.Exec(ctx, "COMMIT PREPARED '"+participantGID+"'"); err != nil {
+			c.logger.ErrorContext(ctx, "commit prepared failed, left for recovery",
+				"gid", participantGID,
+				"error", err.Error(),
+			)
+			failed = append(failed, p.Name)
+		}
+	}
+	if len(failed) > 0 {
+		return New(CodeConnection, fmt.Sprintf(
+			"two-phase commit %s decided but not completed on %s; run Recover to finish",
+			gid, strings.Join(failed, ", ")))
+	}
+
+	if err := c.decisions.Forget(ctx, gid); err != nil {
+		c.logger.WarnContext(ctx, "failed to forget two-phase commit decision",
+			"gid", gid,
+			"error", err.Error(),
+		)
+	}
+
+	c.logger.DebugContext(ctx, "two-phase commit completed", "gid", gid, "participants", len(prepared))
+	return nil
+}
+
+// Recover resolves prepared transactions left behind by crashed or failed
+// coordinators. The pools map must contain every participant by name.
+// Prepared transactions with a recorded commit decision are committed; undecided
+// ones older than the recovery minimum age are recorded as aborted in the decision
+// log and rolled back. Commit decisions are
+// forgotten once all of their participants have committed. If a pool cannot be
+// listed or a prepared transaction belongs to a participant missing from pools,
+// no decision is forgotten in that run, since the unseen participants may still
+// need them.
+func (c *TwoPhaseCoordinator) Recover(ctx context.Context, pools map[string]Pool) (TwoPhaseRecovery, error) {
+	var (
+		result     TwoPhaseRecovery
+		errs       []error
+		committed  = make(map[string]bool)
+		incomplete = make(map[string]bool)
+		unknown    = make(map[string]bool)
+		listFailed bool
+	)
+
+	for name, pool := range pools {
+		orphans, err := c.listPrepared(ctx, pool)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("listing prepared transactions on %s: %w", name, err))
+			listFailed = true
+			continue
+		}
+
+		for _, orphan := range orphans {
+			gid, participant, ok := splitParticipantGID(orphan.gid)
+			if !ok {
+				continue
+			}
+			if participant != name {
+				// Participants sharing a database see each other's transactions.
+				if _, known := pools[participant]; !known && !unknown[orphan.gid] {
+					unknown[orphan.gid] = true
+					errs = append(errs, fmt.Errorf("no pool for participant %s of %s", participant, gid))
+				}
+				continue
+			}
+
+			isCommitted, err := c.decisions.IsCommitted(ctx, gid)
+			if err == nil && !isCommitted && orphan.stale {
+				// The abort must be durable before rolling back, so the coordinator
+				// cannot record a commit for participants already rolled back.
+				isCommitted, err = c.decisions.RecordAbort(ctx, gid)
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("reading decision for %s: %w", gid, err))
+				incomplete[gid] = true
+				continue
+			}
+
+			switch {
+			case isCommitted:
+				committed[gid] = true
+				if _, err := pool.Exec(ctx, "COMMIT PREPARED '"+orphan.gid+"'"); err != nil {
+					errs = append(errs, fmt.Errorf("committing %s: %w", orphan.gid, err))
+					incomplete[gid] = true
+					continue
+				}
+				result.Committed = append(result.Committed, orphan.gid)
+			case orphan.stale:
+				if _, err := pool.Exec(ctx, "ROLLBACK PREPARED '"+orphan.gid+"'"); err != nil {
+					errs = append(errs, fmt.Errorf("rolling back %s: %w", orphan.gid, err))
+					continue
+				}
+				result.RolledBack = append(result.RolledBack, orphan.gid)
+			}
+		}
+	}
+
+	for gid := range committed {
+		if incomplete[gid] || listFailed || len(unknown) > 0 {
+			continue
+		}
+		if err := c.decisions.Forget(ctx, gid); err != nil {
+			errs = append(errs, fmt.Errorf("forgetting decision for %s: %w", gid, err))
+		}
+	}
+
+	c.logger.InfoContext(ctx, "two-phase recovery completed",
+		"committed", len(result.Committed),
+		"rolled_back", len(result.RolledBack),
+		"errors", len(errs),
+	)
+
+	if len(errs) > 0 {
+		return result, Wrap(CodeInternal, "two-phase recovery incomplete", errors.Join(errs...))
+	}
+	return result, nil
+}
+
+// validateParticipants checks participant names are valid and unique, and that
+// every participant gid is a valid prepared transaction identifier.
+func (c *TwoPhaseCoordinator) validateParticipants(participants []TwoPhaseParticipant) error {
+	if len(participants) == 0 {
+		return fmt.Errorf("at least one participant is required")
+	}
+	gid := c.gidPrefix + strings.Repeat("0", 32)
+	seen := make(map[string]struct{}, len(participants))
+	for _, p := range participants {
+		if !participantNameRegex.MatchString(p.Name) {
+			return fmt.Errorf("invalid participant name: %q", p.Name)
+		}
+		if err := validateGID(participantGID(gid, p.Name)); err != nil {
+			return err
+		}
+		if _, ok := seen[p.Name]; ok {
+			return fmt.Errorf("duplicate participant name: %q", p.Name)
+		}
+		seen[p.Name] = struct{}{}
+		if p.Pool == nil || p.Fn == nil {
+			return fmt.Errorf("participant %q requires a pool and a function", p.Name)
+		}
+	}
+	return nil
+}
+
+// prepare runs a participant's function and prepares its transaction.
+func (c *TwoPhaseCoordinator) prepare(ctx context.Context, gid string, p TwoPhaseParticipant) error {
+	tx, err := p.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	preparer, ok := tx.(Preparer)
+	if !ok {
+		_ = tx.Rollback(ctx) //nolint:errcheck // Best-effort rollback, the unsupported transaction is reported.
+		return New(CodeInvalidInput, fmt.Sprintf("participant %s transaction does not support two-phase commit", p.Name))
+	}
+
+	if err := p.Fn(tx); err != nil {
+		if rbErr := tx.Rollback(ctx); rbErr != nil {
+			c.logger.ErrorContext(ctx, "rollback failed",
+				"participant", p.Name,
+				"original_error", err.Error(),
+				"rollback_error", rbErr.Error(),
+			)
+		}
+		return err
+	}
+
+	if err := preparer.Prepare(ctx, participantGID(gid, p.Name)); err != nil {
+		_ = tx.Rollback(ctx) //nolint:errcheck // Best-effort rollback, prepare error is returned.
+		return err
+	}
+	return nil
+}
+
+// rollbackPrepared rolls back participants that were already prepared.
+// Failures are logged; Recover rolls back anything left behind.
+func (c *TwoPhaseCoordinator) rollbackPrepared(ctx context.Context, gid string, prepared []TwoPhaseParticipant) {
+	for _, p := range prepared {
+		participantGID := participantGID(gid, p.Name)
+		if _, err := p.Pool.Exec(ctx, "ROLLBACK PREPARED '"+participantGID+"'"); err != nil {
+			c.logger.ErrorContext(ctx, "rollback prepared failed, left for recovery",
+				"gid", participantGID,
+				"error", err.Error(),
+			)
+		}
+	}
+}
+
+// preparedXact is a row from pg_prepared_xacts.
+type preparedXact struct {
+	gid   string
+	stale bool
+}
+
+// listPrepared lists this coordinator's prepared transactions in the pool's database.
+func (c *TwoPhaseCoordinator) listPrepared(ctx context.Context, pool Pool) ([]preparedXact, error) {
+	rows, err := pool.Query(ctx,
+		"SELECT gid, prepared < now() - make_interval(secs => $2) FROM pg_prepared_xacts "+
+			"WHERE database = current_database() AND starts_with(gid, $1)",
+		c.gidPrefix, c.recoveryMinAge.Seconds(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []preparedXact
+	for rows.Next() {
+		var x preparedXact
+		if err := rows.Scan(&x.gid, &x.stale); err != nil {
+			return nil, FromPgError(err)
+		}
+		result = append(result, x)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, FromPgError(err)
+	}
+	return result, nil
+}
+
+// newGID generates a new global transaction identifier.
+func (c *TwoPhaseCoordinator) newGID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return c.gidPrefix + hex.EncodeToString(b), nil
+}
+
+// participantGID returns the identifier of a participant's prepared transaction.
+// Prepared transaction identifiers are unique per cluster, so participants
+// sharing a cluster need distinct identifiers.
+func participantGID(gid, participant string) string {
+	return gid + ":" + participant
+}
+
+// splitParticipantGID splits a participant gid into the global gid and participant name.
+func splitParticipantGID(participantGID string) (gid, participant string, ok bool) {
+	idx := strings.LastIndex(participantGID, ":")
+	if idx <= 0 || idx == len(participantGID)-1 {
+		return "", "", false
+	}
+	return participantGID[:idx], participantGID[idx+1:], true
+}
+
+// pgDecisionLog implements DecisionLog using a PostgreSQL table.
+type pgDecisionLog struct {
+	querier Querier
+	table   string
+}
+
+// NewPostgresDecisionLog creates a DecisionLog stored in the given table.
+// The table is created by EnsurePostgresDecisionLog. It should live in a database
+// that outlives the coordinator, typically one of the participants.
+// Abort decisions are never forgotten; delete those older than any coordinator
+// can run, e.g. DELETE FROM table WHERE NOT committed AND decided_at < now() - interval '1 day'.
+func NewPostgresDecisionLog(querier Querier, table string) (DecisionLog, error) {
+	if err := validateTableName(table); err != nil {
+		return nil, Wrap(CodeInvalidInput, "invalid decision log table", err)
+	}
+	return &pgDecisionLog{querier: querier, table: quoteIdent(table)}, nil
+}
+
+// EnsurePostgresDecisionLog creates the decision log table if it does not exist.
+func EnsurePostgresDecisionLog(ctx context.Context, querier Querier, table string) error {
+	if err := validateTableName(table); err != nil {
+		return Wrap(CodeInvalidInput, "invalid decision log table", err)
+	}
+	_, err := querier.Exec(ctx, "CREATE TABLE IF NOT EXISTS "+quoteIdent(table)+
+		" (gid TEXT PRIMARY KEY, committed BOOLEAN NOT NULL, decided_at TIMESTAMPTZ NOT NULL DEFAULT now())")
+	return err
+}
+
+// RecordCommit durably records a commit decision.
+// Returns a CodeDuplicate error if an abort decision was already recorded.
+func (l *pgDecisionLog) RecordCommit(ctx context.Context, gid string) error {
+	if _, err := l.querier.Exec(ctx, "INSERT INTO "+l.table+" (gid, committed) VALUES ($1, TRUE)", gid); err != nil {
+		return FromPgError(err)
+	}
+	return nil
+}
+
+// RecordAbort durably records an abort decision unless a commit decision exists.
+func (l *pgDecisionLog) RecordAbort(ctx context.Context, gid string) (bool, error) {
+	tag, err := l.querier.Exec(ctx,
+		"INSERT INTO "+l.table+" (gid, committed) VALUES ($1, FALSE) ON CONFLICT (gid) DO NOTHING", gid)
+	if err != nil {
+		return false, FromPgError(err)
+	}
+	if tag.RowsAffected() == 1 {
+		return false, nil
+	}
+
+	// A decision already exists; a new statement sees it even if it was
+	// committed while the insert waited on the conflicting row.
+	var committed bool
+	err = l.querier.QueryRow(ctx, "SELECT committed FROM "+l.table+" WHERE gid = $1", gid).Scan(&committed)
+	if err != nil {
+		return false, FromPgError(err)
+	}
+	return committed, nil
+}
+
+// IsCommitted reports whether a commit decision was recorded.
+func (l *pgDecisionLog) IsCommitted(ctx context.Context, gid string) (bool, error) {
+	var exists bool
+	err := l.querier.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM "+l.table+" WHERE gid = $1 AND committed)", gid).Scan(&exists)
+	if err != nil {
+		return false, FromPgError(err)
+	}
+	return exists, nil
+}
+
+// Forget removes a decision.
+func (l *pgDecisionLog) Forget(ctx context.Context, gid string) error {
+	_, err := l.querier.Exec(ctx, "DELETE FROM "+l.table+" WHERE gid = $1", gid)
+	return err
+}
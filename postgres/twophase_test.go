@@ -0,0 +1,458 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+// memoryDecisionLog is an in-memory DecisionLog for tests.
+// Decisions map global transaction identifiers to whether they are committed.
+type memoryDecisionLog struct {
+	mu        sync.Mutex
+	decisions map[string]bool
+
+	// beforeCommit is called before a commit decision is recorded.
+	beforeCommit func(gid string)
+}
+
+func newMemoryDecisionLog() *memoryDecisionLog {
+	return &memoryDecisionLog{decisions: make(map[string]bool)}
+}
+
+func (l *memoryDecisionLog) RecordCommit(ctx context.Context, gid string) error {
+	if l.beforeCommit != nil {
+		l.beforeCommit(gid)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.decisions[gid]; ok {
+		return New(CodeDuplicate, "decision already recorded")
+	}
+	l.decisions[gid] = true
+	return nil
+}
+
+func (l *memoryDecisionLog) RecordAbort(ctx context.Context, gid string) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if committed, ok := l.decisions[gid]; ok {
+		return committed, nil
+	}
+	l.decisions[gid] = false
+	return false, nil
+}
+
+func (l *memoryDecisionLog) IsCommitted(ctx context.Context, gid string) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.decisions[gid], nil
+}
+
+func (l *memoryDecisionLog) Forget(ctx context.Context, gid string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.decisions, gid)
+	return nil
+}
+
+func (l *memoryDecisionLog) len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.decisions)
+}
+
+func TestValidateGID(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		gid     string
+		wantErr bool
+	}{
+		{"simple", "txova2pc_abc:orders", false},
+		{"dots and dashes", "a.b-c", false},
+		{"empty", "", true},
+		{"quote", "abc'; DROP TABLE users; --", true},
+		{"space", "abc def", true},
+		{"too long", strings.Repeat("a", 200), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := validateGID(tt.gid)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateGID(%q) error = %v, wantErr %v", tt.gid, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSplitParticipantGID(t *testing.T) {
+	t.Parallel()
+
+	gid, participant, ok := splitParticipantGID(participantGID("txova2pc_abc", "orders"))
+	if !ok || gid != "txova2pc_abc" || participant != "orders" {
+		t.Errorf("splitParticipantGID() = %q, %q, %v", gid, participant, ok)
+	}
+	if _, _, ok := splitParticipantGID("txova2pc_abc"); ok {
+		t.Error("expected gid without participant to be rejected")
+	}
+}
+
+// unpreparedTxPool is a Pool whose transactions do not implement Preparer.
+type unpreparedTxPool struct {
+	*mockPool
+}
+
+func (p unpreparedTxPool) Begin(ctx context.Context) (Tx, error) {
+	tx, err := p.mockPool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return struct{ Tx }{tx}, nil
+}
+
+func TestTwoPhaseCoordinator_Execute(t *testing.T) {
+	t.Parallel()
+
+	t.Run("commits all participants", func(t *testing.T) {
+		t.Parallel()
+		orders, ordersMock := newMockPool(t)
+		defer ordersMock.Close()
+		ledger, ledgerMock := newMockPool(t)
+		defer ledgerMock.Close()
+
+		for _, mock := range []pgxmock.PgxPoolIface{ordersMock, ledgerMock} {
+			mock.ExpectBegin()
+			mock.ExpectExec("INSERT").WillReturnResult(pgxmock.NewResult("INSERT", 1))
+			mock.ExpectExec("PREPARE TRANSACTION").WillReturnResult(pgxmock.NewResult("PREPARE TRANSACTION", 0))
+			mock.ExpectCommit()
+		}
+		ordersMock.ExpectExec("COMMIT PREPARED").WillReturnResult(pgxmock.NewResult("COMMIT PREPARED", 0))
+		ledgerMock.ExpectExec("COMMIT PREPARED").WillReturnResult(pgxmock.NewResult("COMMIT PREPARED", 0))
+
+		decisions := newMemoryDecisionLog()
+		coordinator := NewTwoPhaseCoordinator(decisions)
+		insert := func(tx Tx) error {
+			_, err := tx.Exec(context.Background(), "INSERT INTO t VALUES (1)")
+			return err
+		}
+
+		err := coordinator.Execute(context.Background(),
+			TwoPhaseParticipant{Name: "orders", Pool: orders, Fn: insert},
+			TwoPhaseParticipant{Name: "ledger", Pool: ledger, Fn: insert},
+		)
+		if err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+		if decisions.len() != 0 {
+			t.Error("expected decision to be forgotten after commit")
+		}
+
+		for _, mock := range []pgxmock.PgxPoolIface{ordersMock, ledgerMock} {
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		}
+	})
+
+	t.Run("rolls back prepared participants on failure", func(t *testing.T) {
+		t.Parallel()
+		orders, ordersMock := newMockPool(t)
+		defer ordersMock.Close()
+		ledger, ledgerMock := newMockPool(t)
+		defer ledgerMock.Close()
+
+		ordersMock.ExpectBegin()
+		ordersMock.ExpectExec("PREPARE TRANSACTION").WillReturnResult(pgxmock.NewResult("PREPARE TRANSACTION", 0))
+		ordersMock.ExpectCommit()
+		ordersMock.ExpectExec("ROLLBACK PREPARED").WillReturnResult(pgxmock.NewResult("ROLLBACK PREPARED", 0))
+		ledgerMock.ExpectBegin()
+		ledgerMock.ExpectRollback()
+
+		errLedger := errors.New("insufficient funds")
+		decisions := newMemoryDecisionLog()
+		coordinator := NewTwoPhaseCoordinator(decisions)
+
+		err := coordinator.Execute(context.Background(),
+			TwoPhaseParticipant{Name: "orders", Pool: orders, Fn: func(tx Tx) error { return nil }},
+			TwoPhaseParticipant{Name: "ledger", Pool: ledger, Fn: func(tx Tx) error { return errLedger }},
+		)
+		if !errors.Is(err, errLedger) {
+			t.Fatalf("Execute() error = %v, want %v", err, errLedger)
+		}
+		if decisions.len() != 0 {
+			t.Error("expected no decision to be recorded")
+		}
+
+		for _, mock := range []pgxmock.PgxPoolIface{ordersMock, ledgerMock} {
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		}
+	})
+
+	t.Run("keeps decision when commit prepared fails", func(t *testing.T) {
+		t.Parallel()
+		orders, ordersMock := newMockPool(t)
+		defer ordersMock.Close()
+
+		ordersMock.ExpectBegin()
+		ordersMock.ExpectExec("PREPARE TRANSACTION").WillReturnResult(pgxmock.NewResult("PREPARE TRANSACTION", 0))
+		ordersMock.ExpectCommit()
+		ordersMock.ExpectExec("COMMIT PREPARED").WillReturnError(errors.New("connection reset"))
+
+		decisions := newMemoryDecisionLog()
+		coordinator := NewTwoPhaseCoordinator(decisions)
+
+		err := coordinator.Execute(context.Background(),
+			TwoPhaseParticipant{Name: "orders", Pool: orders, Fn: func(tx Tx) error { return nil }},
+		)
+		if !IsConnection(err) {
+			t.Fatalf("Execute() error = %v, want connection error", err)
+		}
+		if decisions.len() != 1 {
+			t.Error("expected decision to be kept for recovery")
+		}
+	})
+
+	t.Run("fails when recovery aborted first", func(t *testing.T) {
+		t.Parallel()
+		orders, ordersMock := newMockPool(t)
+		defer ordersMock.Close()
+		ledger, ledgerMock := newMockPool(t)
+		defer ledgerMock.Close()
+
+		for _, mock := range []pgxmock.PgxPoolIface{ordersMock, ledgerMock} {
+			mock.ExpectBegin()
+			mock.ExpectExec("PREPARE TRANSACTION").WillReturnResult(pgxmock.NewResult("PREPARE TRANSACTION", 0))
+			mock.ExpectCommit()
+			mock.ExpectExec("ROLLBACK PREPARED").WillReturnResult(pgxmock.NewResult("ROLLBACK PREPARED", 0))
+		}
+
+		decisions := newMemoryDecisionLog()
+		decisions.beforeCommit = func(gid string) {
+			_, _ = decisions.RecordAbort(context.Background(), gid)
+		}
+		coordinator := NewTwoPhaseCoordinator(decisions)
+		fn := func(tx Tx) error { return nil }
+
+		err := coordinator.Execute(context.Background(),
+			TwoPhaseParticipant{Name: "orders", Pool: orders, Fn: fn},
+			TwoPhaseParticipant{Name: "ledger", Pool: ledger, Fn: fn},
+		)
+		if !IsDuplicate(err) {
+			t.Fatalf("Execute() error = %v, want duplicate", err)
+		}
+
+		for _, mock := range []pgxmock.PgxPoolIface{ordersMock, ledgerMock} {
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unfulfilled expectations: %v", err)
+			}
+		}
+	})
+
+	t.Run("participant without two-phase support", func(t *testing.T) {
+		t.Parallel()
+		orders, ordersMock := newMockPool(t)
+		defer ordersMock.Close()
+
+		ordersMock.ExpectBegin()
+		ordersMock.ExpectRollback()
+
+		called := false
+		coordinator := NewTwoPhaseCoordinator(newMemoryDecisionLog())
+		err := coordinator.Execute(context.Background(),
+			TwoPhaseParticipant{Name: "orders", Pool: unpreparedTxPool{orders}, Fn: func(tx Tx) error {
+				called = true
+				return nil
+			}},
+		)
+		if !IsCode(err, CodeInvalidInput) {
+			t.Fatalf("Execute() error = %v, want invalid input", err)
+		}
+		if called {
+			t.Error("participant function should not run")
+		}
+		if err := ordersMock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unfulfilled expectations: %v", err)
+		}
+	})
+
+	t.Run("invalid participants", func(t *testing.T) {
+		t.Parallel()
+		pool, mock := newMockPool(t)
+		defer mock.Close()
+
+		coordinator := NewTwoPhaseCoordinator(newMemoryDecisionLog())
+		fn := func(tx Tx) error { return nil }
+
+		cases := [][]TwoPhaseParticipant{
+			nil,
+			{{Name: "bad name", Pool: pool, Fn: fn}},
+			{{Name: "a", Pool: pool, Fn: fn}, {Name: "a", Pool: pool, Fn: fn}},
+			{{Name: "a", Pool: pool}},
+			{{Name: "a", Pool: pool, Fn: fn}, {Name: strings.Repeat("b", 180), Pool: pool, Fn: fn}},
+		}
+		for _, participants := range cases {
+			if err := coordinator.Execute(context.Background(), participants...); !IsCode(err, CodeInvalidInput) {
+				t.Errorf("Execute(%v) error = %v, want invalid input", participants, err)
+			}
+		}
+	})
+}
+
+func TestTwoPhaseCoordinator_Recover(t *testing.T) {
+	t.Parallel()
+
+	pool, mock := newMockPool(t)
+	defer mock.Close()
+
+	decisions := newMemoryDecisionLog()
+	_ = decisions.RecordCommit(context.Background(), "txova2pc_decided")
+	coordinator := NewTwoPhaseCoordinator(decisions)
+
+	mock.ExpectQuery("FROM pg_prepared_xacts").
+		WithArgs(DefaultTwoPhaseGIDPrefix, DefaultTwoPhaseRecoveryMinAge.Seconds()).
+		WillReturnRows(pgxmock.NewRows([]string{"gid", "stale"}).
+			AddRow("txova2pc_decided:orders", false).
+			AddRow("txova2pc_undecided:orders", true).
+			AddRow("txova2pc_fresh:orders", false))
+	mock.ExpectExec("COMMIT PREPARED 'txova2pc_decided:orders'").
+		WillReturnResult(pgxmock.NewResult("COMMIT PREPARED", 0))
+	mock.ExpectExec("ROLLBACK PREPARED 'txova2pc_undecided:orders'").
+		WillReturnResult(pgxmock.NewResult("ROLLBACK PREPARED", 0))
+
+	result, err := coordinator.Recover(context.Background(), map[string]Pool{"orders": pool})
+	if err != nil {
+		t.Fatalf("Recover() error = %v", err)
+	}
+	if len(result.Committed) != 1 || result.Committed[0] != "txova2pc_decided:orders" {
+		t.Errorf("Committed = %v", result.Committed)
+	}
+	if len(result.RolledBack) != 1 || result.RolledBack[0] != "txova2pc_undecided:orders" {
+		t.Errorf("RolledBack = %v", result.RolledBack)
+	}
+	if committed, _ := decisions.IsCommitted(context.Background(), "txova2pc_undecided"); committed || decisions.len() != 1 {
+		t.Error("expected commit decisions to be forgotten and the abort to be kept")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestTwoPhaseCoordinator_Recover_KeepsDecisions(t *testing.T) {
+	t.Parallel()
+
+	expectList := func(mock pgxmock.PgxPoolIface) *pgxmock.ExpectedQuery {
+		return mock.ExpectQuery("FROM pg_prepared_xacts").
+			WithArgs(DefaultTwoPhaseGIDPrefix, DefaultTwoPhaseRecoveryMinAge.Seconds())
+	}
+
+	tests := []struct {
+		name  string
+		setup func(orders, ledger pgxmock.PgxPoolIface)
+	}{
+		{
+			name: "listing fails",
+			setup: func(orders, ledger pgxmock.PgxPoolIface) {
+				expectList(orders).
+					WillReturnRows(pgxmock.NewRows([]string{"gid", "stale"}).AddRow("txova2pc_decided:orders", false))
+				orders.ExpectExec("COMMIT PREPARED 'txova2pc_decided:orders'").
+					WillReturnResult(pgxmock.NewResult("COMMIT PREPARED", 0))
+				expectList(ledger).WillReturnError(errors.New("connection refused"))
+			},
+		},
+		{
+			name: "participant without pool",
+			setup: func(orders, ledger pgxmock.PgxPoolIface) {
+				expectList(orders).
+					WillReturnRows(pgxmock.NewRows([]string{"gid", "stale"}).
+						AddRow("txova2pc_decided:orders", false).
+						AddRow("txova2pc_decided:billing", false))
+				orders.ExpectExec("COMMIT PREPARED 'txova2pc_decided:orders'").
+					WillReturnResult(pgxmock.NewResult("COMMIT PREPARED", 0))
+				expectList(ledger).WillReturnRows(pgxmock.NewRows([]string{"gid", "stale"}))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			orders, ordersMock := newMockPool(t)
+			defer ordersMock.Close()
+			ledger, ledgerMock := newMockPool(t)
+			defer ledgerMock.Close()
+			tt.setup(ordersMock, ledgerMock)
+
+			decisions := newMemoryDecisionLog()
+			_ = decisions.RecordCommit(context.Background(), "txova2pc_decided")
+			coordinator := NewTwoPhaseCoordinator(decisions)
+
+			result, err := coordinator.Recover(context.Background(), map[string]Pool{"orders": orders, "ledger": ledger})
+			if !IsCode(err, CodeInternal) {
+				t.Errorf("Recover() error = %v, want recovery incomplete", err)
+			}
+			if len(result.Committed) != 1 {
+				t.Errorf("Committed = %v", result.Committed)
+			}
+			if decisions.len() != 1 {
+				t.Error("expected decision to be kept while a participant may be unresolved")
+			}
+
+			for _, mock := range []pgxmock.PgxPoolIface{ordersMock, ledgerMock} {
+				if err := mock.ExpectationsWereMet(); err != nil {
+					t.Errorf("unfulfilled expectations: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestPostgresDecisionLog(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	pool, mock := newMockPool(t)
+	defer mock.Close()
+
+	decisions, err := NewPostgresDecisionLog(pool, "twophase_decisions")
+	if err != nil {
+		t.Fatalf("NewPostgresDecisionLog() error = %v", err)
+	}
+
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO twophase_decisions (gid, committed) VALUES ($1, TRUE)")).
+		WithArgs("txova2pc_a").
+		WillReturnError(&pgconn.PgError{Code: "23505"})
+	if err := decisions.RecordCommit(ctx, "txova2pc_a"); !IsDuplicate(err) {
+		t.Errorf("RecordCommit() error = %v, want duplicate", err)
+	}
+
+	abortSQL := regexp.QuoteMeta("INSERT INTO twophase_decisions (gid, committed) VALUES ($1, FALSE) ON CONFLICT (gid) DO NOTHING")
+	mock.ExpectExec(abortSQL).WithArgs("txova2pc_b").WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	if committed, err := decisions.RecordAbort(ctx, "txova2pc_b"); err != nil || committed {
+		t.Errorf("RecordAbort() = %v, %v, want aborted", committed, err)
+	}
+
+	mock.ExpectExec(abortSQL).WithArgs("txova2pc_c").WillReturnResult(pgxmock.NewResult("INSERT", 0))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT committed FROM twophase_decisions WHERE gid = $1")).
+		WithArgs("txova2pc_c").
+		WillReturnRows(pgxmock.NewRows([]string{"committed"}).AddRow(true))
+	if committed, err := decisions.RecordAbort(ctx, "txova2pc_c"); err != nil || !committed {
+		t.Errorf("RecordAbort() = %v, %v, want committed", committed, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
@@ -15,6 +15,7 @@ type pgxTx struct {
 	tx                 pgx.Tx
 	logger             *logging.Logger
 	slowQueryThreshold time.Duration
+	nested             bool
 }
 
 // Exec executes a query that doesn't return rows.
@@ -66,6 +67,32 @@ func (t *pgxTx) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
 	return row
 }
 
+// SendBatch sends the queued queries of a batch in one round trip.
+// Query errors are returned by the methods of the BatchResults.
+func (t *pgxTx) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	return t.tx.SendBatch(ctx, b)
+}
+
+// CopyFrom copies rows into the table using the COPY protocol.
+func (t *pgxTx) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	start := time.Now()
+	n, err := t.tx.CopyFrom(ctx, tableName, columnNames, rowSrc)
+	duration := time.Since(start)
+
+	copySQL := "COPY " + tableName.Sanitize()
+	t.logSlowQuery(ctx, copySQL, duration)
+
+	if err != nil {
+		t.logger.ErrorContext(ctx, "copy failed",
+			"sql", copySQL,
+			"duration_ms", duration.Milliseconds(),
+			"error", err.Error(),
+		)
+		return n, FromPgError(err)
+	}
+	return n, nil
+}
+
 // logSlowQuery logs a warning if the query duration exceeds the threshold.
 func (t *pgxTx) logSlowQuery(ctx context.Context, sql string, duration time.Duration) {
 	if t.slowQueryThreshold > 0 && duration >= t.slowQueryThreshold {
@@ -83,7 +110,7 @@ func (t *pgxTx) Begin(ctx context.Context) (Tx, error) {
 	if err != nil {
 		return nil, Wrap(CodeConnection, "failed to begin nested transaction", err)
 	}
-	return &pgxTx{tx: nestedTx, logger: t.logger, slowQueryThreshold: t.slowQueryThreshold}, nil
+	return &pgxTx{tx: nestedTx, logger: t.logger, slowQueryThreshold: t.slowQueryThreshold, nested: true}, nil
 }
 
 // Commit commits the transaction.
@@ -102,6 +129,32 @@ func (t *pgxTx) Rollback(ctx context.Context) error {
 	return nil
 }
 
+// Prepare prepares the transaction for two-phase commit.
+func (t *pgxTx) Prepare(ctx context.Context, gid string) error {
+	if t.nested {
+		return New(CodeInvalidInput, "cannot prepare a nested transaction")
+	}
+	if err := validateGID(gid); err != nil {
+		return Wrap(CodeInvalidInput, "invalid global transaction identifier", err)
+	}
+
+	tag, err := t.Exec(ctx, "PREPARE TRANSACTION '"+gid+"'")
+	if err != nil {
+		return err
+	}
+	// An aborted transaction is rolled back instead of prepared.
+	if tag.String() == "ROLLBACK" {
+		return New(CodeInternal, "transaction was rolled back instead of prepared")
+	}
+
+	// The session is no longer in a transaction; finish the pgx transaction
+	// so the connection is released.
+	if err := t.tx.Commit(ctx); err != nil {
+		return Wrap(CodeConnection, "failed to release prepared transaction", err)
+	}
+	return nil
+}
+
 // Conn returns the underlying connection.
 func (t *pgxTx) Conn() *pgx.Conn {
 	return t.tx.Conn()
@@ -3,8 +3,6 @@ package postgres
 
 import (
 	"context"
-	"errors"
-	"math/rand/v2"
 	"time"
 
 	"github.com/Dorico-Dynamics/txova-go-core/logging"
@@ -21,6 +19,16 @@ func TxFromContext(ctx context.Context) (Tx, bool) {
 	return tx, ok
 }
 
+// QuerierFromContext returns the active transaction from the context,
+// or fallback if there is none. Use it so data access code joins the
+// transaction started by TxManager.WithTx.
+func QuerierFromContext(ctx context.Context, fallback Querier) Querier {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx
+	}
+	return fallback
+}
+
 // ContextWithTx returns a new context with the transaction stored in it.
 func ContextWithTx(ctx context.Context, tx Tx) context.Context {
 	return context.WithValue(ctx, txContextKey{}, tx)
@@ -41,6 +49,28 @@ type TxManagerConfig struct {
 	// Default: 2s.
 	RetryMaxDelay time.Duration
 
+	// RetryPolicy decides which errors are retried, how long to wait between
+	// attempts, and the retry budget shared with other transactions.
+	// If nil, serialization failures and deadlocks are retried using
+	// RetryBaseDelay and RetryMaxDelay with no budget.
+	RetryPolicy RetryPolicy
+
+	// OnAttempt is called after every transaction attempt, successful or not.
+	// It must not block, since it runs on the transaction's goroutine.
+	OnAttempt func(ctx context.Context, attempt TxAttempt)
+
+	// LockDiagnostics enables capturing a snapshot of pg_locks and pg_stat_activity
+	// on a separate connection when an attempt fails with a deadlock or lock timeout.
+	// The snapshot is logged and attached to the returned *Error.
+	// Default: false.
+	LockDiagnostics bool
+
+	// SlowTxThreshold is the attempt duration after which lock diagnostics are
+	// captured while the transaction is still running. The snapshot is logged and
+	// attached to the returned *Error if the attempt fails.
+	// Default: 0 (disabled).
+	SlowTxThreshold time.Duration
+
 	// Logger for transaction events.
 	Logger *logging.Logger
 }
@@ -79,6 +109,35 @@ func WithRetryMaxDelay(d time.Duration) TxManagerOption {
 	}
 }
 
+// WithRetryPolicy sets the retry policy for failed transaction attempts.
+func WithRetryPolicy(policy RetryPolicy) TxManagerOption {
+	return func(c *TxManagerConfig) {
+		c.RetryPolicy = policy
+	}
+}
+
+// WithOnTxAttempt sets a callback invoked after every transaction attempt.
+func WithOnTxAttempt(fn func(ctx context.Context, attempt TxAttempt)) TxManagerOption {
+	return func(c *TxManagerConfig) {
+		c.OnAttempt = fn
+	}
+}
+
+// WithLockDiagnostics enables lock diagnostics capture on deadlocks and lock timeouts.
+func WithLockDiagnostics(enabled bool) TxManagerOption {
+	return func(c *TxManagerConfig) {
+		c.LockDiagnostics = enabled
+	}
+}
+
+// WithSlowTxThreshold sets the duration after which lock diagnostics are
+// captured for a running transaction attempt.
+func WithSlowTxThreshold(d time.Duration) TxManagerOption {
+	return func(c *TxManagerConfig) {
+		c.SlowTxThreshold = d
+	}
+}
+
 // WithTxLogger sets the logger for transaction events.
 func WithTxLogger(logger *logging.Logger) TxManagerOption {
 	return func(c *TxManagerConfig) {
@@ -86,7 +145,7 @@ func WithTxLogger(logger *logging.Logger) TxManagerOption {
 	}
 }
 
-// txManager implements the TxManager interface.
+// txManager implements the TxManager and TxSettingsManager interfaces.
 type txManager struct {
 	pool   Pool
 	config TxManagerConfig
@@ -116,6 +175,15 @@ func (m *txManager) WithTx(ctx context.Context, fn func(tx Tx) error) error {
 // If fn returns an error or panics, the transaction is rolled back.
 // Serialization failures and deadlocks are automatically retried.
 func (m *txManager) WithTxOptions(ctx context.Context, opts pgx.TxOptions, fn func(tx Tx) error) error {
+	return m.WithTxSettings(ctx, TxSettings{Options: opts}, fn)
+}
+
+// WithTxSettings executes fn within a transaction with the specified settings.
+// Timeouts are applied with SET LOCAL after BEGIN, and MaxDuration bounds every
+// statement and commit of all attempts, including retries, with one deadline.
+// If a transaction already exists in the context, it uses the existing transaction
+// (settings are ignored for existing transactions).
+func (m *txManager) WithTxSettings(ctx context.Context, settings TxSettings, fn func(tx Tx) error) error {
 	// Check if there's an existing transaction in the context.
 	if existingTx, ok := TxFromContext(ctx); ok {
 		// Use the existing transaction (no commit/rollback, caller controls it).
@@ -123,56 +191,147 @@ func (m *txManager) WithTxOptions(ctx context.Context, opts pgx.TxOptions, fn fu
 	}
 
 	// Execute with retry for retryable errors.
-	return m.executeWithRetry(ctx, opts, fn)
+	return m.executeWithRetry(ctx, settings, fn)
 }
 
 // executeWithRetry executes the transaction function with retry logic.
-func (m *txManager) executeWithRetry(ctx context.Context, opts pgx.TxOptions, fn func(tx Tx) error) error {
-	var lastErr error
-
-	for attempt := 0; attempt <= m.config.MaxRetries; attempt++ {
-		if attempt > 0 {
-			// Wait before retry with exponential backoff and jitter.
-			delay := m.calculateRetryDelay(attempt)
-			m.config.Logger.InfoContext(ctx, "retrying transaction",
-				"attempt", attempt+1,
-				"max_retries", m.config.MaxRetries+1,
-				"delay_ms", delay.Milliseconds(),
-			)
+func (m *txManager) executeWithRetry(ctx context.Context, settings TxSettings, fn func(tx Tx) error) error {
+	policy := m.retryPolicy()
+	budget := policy.Budget()
+
+	// The deadline covers the whole transaction, so retries do not extend it.
+	var deadline time.Time
+	if settings.MaxDuration > 0 {
+		deadline = time.Now().Add(settings.MaxDuration)
+	}
 
-			select {
-			case <-ctx.Done():
-				return Wrap(CodeTimeout, "context cancelled during retry", ctx.Err())
-			case <-time.After(delay):
-			}
+	for attempt := 1; ; attempt++ {
+		start := time.Now()
+		watch := m.watchSlowTx(ctx)
+		phase, err := m.executeTx(ctx, settings, deadline, fn)
+		m.diagnose(ctx, err, watch.stop())
+		result := TxAttempt{
+			Attempt:  attempt,
+			Phase:    phase,
+			Err:      err,
+			Duration: time.Since(start),
 		}
 
-		err := m.executeTx(ctx, opts, fn)
 		if err == nil {
+			if budget != nil {
+				budget.Deposit()
+			}
+			m.notifyAttempt(ctx, result)
 			return nil
 		}
 
-		lastErr = err
-
-		// Only retry on serialization failures or deadlocks.
-		if !isRetryable(err) {
+		if !policy.ShouldRetry(phase, err) {
+			m.notifyAttempt(ctx, result)
 			return err
 		}
 
 		m.config.Logger.WarnContext(ctx, "retryable transaction error",
-			"attempt", attempt+1,
+			"attempt", attempt,
+			"phase", string(phase),
 			"error", err.Error(),
 		)
+
+		if attempt > m.config.MaxRetries {
+			m.notifyAttempt(ctx, result)
+			return Wrap(GetCode(err), "transaction failed after max retries", err)
+		}
+
+		if budget != nil && !budget.Withdraw() {
+			m.config.Logger.WarnContext(ctx, "retry budget exhausted, not retrying transaction",
+				"attempt", attempt,
+			)
+			m.notifyAttempt(ctx, result)
+			return Wrap(GetCode(err), "transaction failed after retry budget exhausted", err)
+		}
+
+		// Wait before retry using the policy's backoff strategy.
+		delay := policy.Backoff(attempt)
+		if !deadline.IsZero() && !time.Now().Add(delay).Before(deadline) {
+			m.notifyAttempt(ctx, result)
+			return Wrap(CodeTimeout, "transaction exceeded maximum duration", err)
+		}
+		result.WillRetry = true
+		result.Delay = delay
+		m.notifyAttempt(ctx, result)
+
+		m.config.Logger.InfoContext(ctx, "retrying transaction",
+			"attempt", attempt+1,
+			"max_retries", m.config.MaxRetries+1,
+			"delay_ms", result.Delay.Milliseconds(),
+		)
+
+		select {
+		case <-ctx.Done():
+			return Wrap(CodeTimeout, "context cancelled during retry", ctx.Err())
+		case <-time.After(result.Delay):
+		}
+	}
+}
+
+// diagnose attaches lock diagnostics to a failed attempt's error.
+// Diagnostics captured by the slow transaction watch are preferred, since they
+// were taken while the attempt was still waiting on its locks.
+func (m *txManager) diagnose(ctx context.Context, err error, diag *LockDiagnostics) {
+	if err == nil {
+		return
 	}
+	if diag == nil && m.config.LockDiagnostics {
+		if trigger, ok := lockDiagnosticsTrigger(err); ok {
+			diag = m.captureLockDiagnostics(ctx, trigger)
+		}
+	}
+	attachLockDiagnostics(err, diag)
+}
 
-	return Wrap(CodeSerialization, "transaction failed after max retries", lastErr)
+// retryPolicy returns the configured retry policy, or one built from the
+// legacy MaxRetries/RetryBaseDelay/RetryMaxDelay settings.
+func (m *txManager) retryPolicy() RetryPolicy {
+	if m.config.RetryPolicy != nil {
+		return m.config.RetryPolicy
+	}
+	return NewRetryPolicy(DefaultRetryClassifier, m.calculateRetryDelay, nil)
+}
+
+// notifyAttempt invokes the OnAttempt callback if one is configured.
+func (m *txManager) notifyAttempt(ctx context.Context, attempt TxAttempt) {
+	if m.config.OnAttempt != nil {
+		m.config.OnAttempt(ctx, attempt)
+	}
 }
 
-// executeTx executes a single transaction attempt.
-func (m *txManager) executeTx(ctx context.Context, opts pgx.TxOptions, fn func(tx Tx) error) (err error) {
-	tx, err := m.pool.BeginTx(ctx, opts)
+// executeTx executes a single transaction attempt, bounded by deadline unless it is zero.
+// It returns the phase that was reached, which is the failing phase on error.
+func (m *txManager) executeTx(ctx context.Context, settings TxSettings, deadline time.Time, fn func(tx Tx) error) (phase TxPhase, err error) {
+	beginCtx := ctx
+	if !deadline.IsZero() {
+		var cancel context.CancelFunc
+		beginCtx, cancel = context.WithDeadline(ctx, deadline)
+		defer cancel()
+	}
+
+	tx, err := m.pool.BeginTx(beginCtx, settings.Options)
 	if err != nil {
-		return err
+		return TxPhaseBegin, err
+	}
+
+	// Bound all statements by the transaction deadline.
+	if !deadline.IsZero() {
+		dtx := newDeadlineTx(tx, deadline)
+		defer dtx.release()
+		tx = dtx
+	}
+
+	// Apply server-side timeouts for the lifetime of the transaction.
+	if stmt := settings.setLocalSQL(); stmt != "" {
+		if _, err = tx.Exec(ctx, stmt); err != nil {
+			_ = tx.Rollback(ctx) //nolint:errcheck // Best-effort rollback, original error is returned.
+			return TxPhaseBegin, err
+		}
 	}
 
 	// Store transaction in context for nested access.
@@ -200,54 +359,19 @@ func (m *txManager) executeTx(ctx context.Context, opts pgx.TxOptions, fn func(t
 				"rollback_error", rbErr.Error(),
 			)
 		}
-		return err
+		return TxPhaseExec, err
 	}
 
 	// Commit on success.
 	if err = tx.Commit(txCtx); err != nil {
-		return err
+		return TxPhaseCommit, err
 	}
 
-	return nil
+	return TxPhaseCommit, nil
 }
 
 // calculateRetryDelay calculates the delay before the next retry attempt.
 // Uses exponential backoff with jitter.
 func (m *txManager) calculateRetryDelay(attempt int) time.Duration {
-	// Bound attempt to prevent overflow (max 30 gives ~1 billion multiplier)
-	if attempt < 1 {
-		attempt = 1
-	}
-	if attempt > 30 {
-		attempt = 30
-	}
-
-	// Exponential backoff: baseDelay * 2^(attempt-1)
-	delay := m.config.RetryBaseDelay * time.Duration(1<<(attempt-1))
-
-	// Cap at max delay.
-	if delay > m.config.RetryMaxDelay {
-		delay = m.config.RetryMaxDelay
-	}
-
-	// Add jitter (±25%) using math/rand which is acceptable for non-security purposes.
-	// Guard against zero/very-small delays that would cause rand.Int64N to panic.
-	var jitter time.Duration
-	halfWindow := int64(delay) / 2
-	if halfWindow > 0 {
-		jitter = time.Duration(rand.Int64N(halfWindow)) // #nosec G404 -- not security-sensitive
-	}
-	delay = delay - delay/4 + jitter
-
-	return delay
-}
-
-// isRetryable checks if the error is retryable (serialization failure or deadlock).
-func isRetryable(err error) bool {
-	var dbErr *Error
-	if errors.As(err, &dbErr) {
-		code := dbErr.Code()
-		return code == CodeSerialization || code == CodeDeadlock
-	}
-	return false
+	return ExponentialBackoff(m.config.RetryBaseDelay, m.config.RetryMaxDelay)(attempt)
 }
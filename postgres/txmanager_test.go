@@ -65,6 +65,26 @@ func TestTxManagerConfigOptions(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "WithRetryPolicy",
+			opt:  WithRetryPolicy(NewRetryPolicy(nil, nil, nil)),
+			validate: func(t *testing.T, cfg TxManagerConfig) {
+				t.Helper()
+				if cfg.RetryPolicy == nil {
+					t.Error("RetryPolicy should not be nil")
+				}
+			},
+		},
+		{
+			name: "WithOnTxAttempt",
+			opt:  WithOnTxAttempt(func(context.Context, TxAttempt) {}),
+			validate: func(t *testing.T, cfg TxManagerConfig) {
+				t.Helper()
+				if cfg.OnAttempt == nil {
+					t.Error("OnAttempt should not be nil")
+				}
+			},
+		},
 		{
 			name: "WithTxLogger",
 			opt:  WithTxLogger(logging.Default()),
@@ -0,0 +1,194 @@
+// Package postgres provides PostgreSQL database utilities for the Txova platform.
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// TxSettings holds per-transaction options for TxSettingsManager.WithTxSettings.
+// Server-side timeouts are applied with SET LOCAL when the transaction begins,
+// so they only last until commit or rollback.
+type TxSettings struct {
+	// Options are the pgx transaction options (isolation level, access mode, deferrable).
+	Options pgx.TxOptions
+
+	// StatementTimeout aborts any single statement running longer than this.
+	// Applied as SET LOCAL statement_timeout. Zero leaves the server setting unchanged.
+	StatementTimeout time.Duration
+
+	// LockTimeout aborts any statement waiting longer than this for a lock.
+	// Applied as SET LOCAL lock_timeout. Zero leaves the server setting unchanged.
+	LockTimeout time.Duration
+
+	// IdleInTransactionSessionTimeout terminates the session if the transaction
+	// stays idle longer than this. Applied as SET LOCAL idle_in_transaction_session_timeout.
+	// Zero leaves the server setting unchanged.
+	IdleInTransactionSessionTimeout time.Duration
+
+	// MaxDuration is the maximum wall-clock duration of the whole transaction,
+	// from the first BEGIN to the final COMMIT, including retries and their backoff.
+	// It is enforced with a context deadline on every statement; once exceeded,
+	// statements fail with CodeTimeout and the transaction is rolled back without
+	// further retries. Zero means no limit.
+	MaxDuration time.Duration
+}
+
+// setLocalSQL returns the SET LOCAL statements for the configured timeouts,
+// or an empty string if none are set.
+func (s TxSettings) setLocalSQL() string {
+	settings := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"statement_timeout", s.StatementTimeout},
+		{"lock_timeout", s.LockTimeout},
+		{"idle_in_transaction_session_timeout", s.IdleInTransactionSessionTimeout},
+	}
+
+	var statements []string
+	for _, setting := range settings {
+		if setting.value <= 0 {
+			continue
+		}
+		statements = append(statements, fmt.Sprintf("SET LOCAL %s = %d", setting.name, durationMillis(setting.value)))
+	}
+	return strings.Join(statements, "; ")
+}
+
+// durationMillis converts a positive duration to whole milliseconds, rounding up
+// so that sub-millisecond values do not become 0 (which disables the timeout).
+func durationMillis(d time.Duration) int64 {
+	ms := d.Milliseconds()
+	if d%time.Millisecond != 0 {
+		ms++
+	}
+	return ms
+}
+
+// deadlineTx wraps a Tx and bounds every statement by the transaction deadline.
+type deadlineTx struct {
+	Tx
+	deadline time.Time
+	cancels  *cancelList
+}
+
+// cancelList collects cancel functions for contexts that must outlive a single call,
+// such as the context of rows returned by Query.
+type cancelList struct {
+	mu      sync.Mutex
+	cancels []context.CancelFunc
+}
+
+// add registers a cancel function to be called on release.
+func (l *cancelList) add(cancel context.CancelFunc) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.cancels = append(l.cancels, cancel)
+}
+
+// release cancels all registered contexts.
+func (l *cancelList) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, cancel := range l.cancels {
+		cancel()
+	}
+	l.cancels = nil
+}
+
+// newDeadlineTx wraps tx so that all statements are bounded by deadline.
+// The caller must call release once the transaction has ended.
+func newDeadlineTx(tx Tx, deadline time.Time) *deadlineTx {
+	return &deadlineTx{Tx: tx, deadline: deadline, cancels: &cancelList{}}
+}
+
+// release cancels all contexts derived from the transaction deadline.
+func (t *deadlineTx) release() {
+	t.cancels.release()
+}
+
+// withDeadline derives a context bounded by the transaction deadline.
+func (t *deadlineTx) withDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithDeadline(ctx, t.deadline)
+}
+
+// mapDeadlineError converts errors caused by the transaction deadline into timeout errors.
+func (t *deadlineTx) mapDeadlineError(parent context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	if parent.Err() == nil && !time.Now().Before(t.deadline) {
+		return Wrap(CodeTimeout, "transaction exceeded maximum duration", err)
+	}
+	return err
+}
+
+// Exec executes a query bounded by the transaction deadline.
+func (t *deadlineTx) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	callCtx, cancel := t.withDeadline(ctx)
+	defer cancel()
+	tag, err := t.Tx.Exec(callCtx, sql, args...)
+	return tag, t.mapDeadlineError(ctx, err)
+}
+
+// Query executes a query bounded by the transaction deadline.
+// The rows remain readable until the deadline or the end of the transaction.
+func (t *deadlineTx) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	callCtx, cancel := t.withDeadline(ctx)
+	rows, err := t.Tx.Query(callCtx, sql, args...)
+	if err != nil {
+		cancel()
+		return nil, t.mapDeadlineError(ctx, err)
+	}
+	t.cancels.add(cancel)
+	return rows, nil
+}
+
+// QueryRow executes a query bounded by the transaction deadline.
+func (t *deadlineTx) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	callCtx, cancel := t.withDeadline(ctx)
+	t.cancels.add(cancel)
+	return t.Tx.QueryRow(callCtx, sql, args...)
+}
+
+// Begin starts a savepoint bounded by the same deadline.
+func (t *deadlineTx) Begin(ctx context.Context) (Tx, error) {
+	callCtx, cancel := t.withDeadline(ctx)
+	defer cancel()
+	nested, err := t.Tx.Begin(callCtx)
+	if err != nil {
+		return nil, t.mapDeadlineError(ctx, err)
+	}
+	return &deadlineTx{Tx: nested, deadline: t.deadline, cancels: t.cancels}, nil
+}
+
+// Commit commits the transaction.
+// If the deadline has passed, the transaction is rolled back instead.
+func (t *deadlineTx) Commit(ctx context.Context) error {
+	if !time.Now().Before(t.deadline) {
+		_ = t.Tx.Rollback(ctx) //nolint:errcheck // Best-effort rollback, timeout error is returned.
+		return Wrap(CodeTimeout, "transaction exceeded maximum duration", context.DeadlineExceeded)
+	}
+	callCtx, cancel := t.withDeadline(ctx)
+	defer cancel()
+	return t.mapDeadlineError(ctx, t.Tx.Commit(callCtx))
+}
+
+// Prepare prepares the transaction for two-phase commit, bounded by the deadline.
+// Returns a CodeInvalidInput error if the wrapped transaction is not a Preparer.
+func (t *deadlineTx) Prepare(ctx context.Context, gid string) error {
+	preparer, ok := t.Tx.(Preparer)
+	if !ok {
+		return New(CodeInvalidInput, "transaction does not support two-phase commit")
+	}
+	callCtx, cancel := t.withDeadline(ctx)
+	defer cancel()
+	return t.mapDeadlineError(ctx, preparer.Prepare(callCtx, gid))
+}
@@ -0,0 +1,77 @@
+package postgres
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTxSettings_SetLocalSQL(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		settings TxSettings
+		want     string
+	}{
+		{
+			name:     "no timeouts",
+			settings: TxSettings{MaxDuration: time.Second},
+			want:     "",
+		},
+		{
+			name:     "statement timeout only",
+			settings: TxSettings{StatementTimeout: 5 * time.Second},
+			want:     "SET LOCAL statement_timeout = 5000",
+		},
+		{
+			name: "all timeouts",
+			settings: TxSettings{
+				StatementTimeout:                2 * time.Second,
+				LockTimeout:                     500 * time.Millisecond,
+				IdleInTransactionSessionTimeout: time.Minute,
+			},
+			want: "SET LOCAL statement_timeout = 2000; SET LOCAL lock_timeout = 500; " +
+				"SET LOCAL idle_in_transaction_session_timeout = 60000",
+		},
+		{
+			name:     "negative timeouts are ignored",
+			settings: TxSettings{StatementTimeout: -time.Second, LockTimeout: time.Second},
+			want:     "SET LOCAL lock_timeout = 1000",
+		},
+		{
+			name:     "sub-millisecond timeout rounds up",
+			settings: TxSettings{LockTimeout: 100 * time.Microsecond},
+			want:     "SET LOCAL lock_timeout = 1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tt.settings.setLocalSQL(); got != tt.want {
+				t.Errorf("setLocalSQL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDurationMillis(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in   time.Duration
+		want int64
+	}{
+		{time.Millisecond, 1},
+		{1500 * time.Microsecond, 2},
+		{time.Second, 1000},
+		{time.Nanosecond, 1},
+	}
+
+	for _, tt := range tests {
+		if got := durationMillis(tt.in); got != tt.want {
+			t.Errorf("durationMillis(%v) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
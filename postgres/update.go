@@ -9,10 +9,13 @@ import (
 // UpdateBuilder builds UPDATE queries.
 type UpdateBuilder struct {
 	*QueryBuilder
+	with      withClause
 	table     string
+	from      []join
 	sets      []setClause
 	where     []whereClause
-	returning []string
+	returning []columnRef
+	structErr error
 }
 
 // Update creates a new UpdateBuilder for the specified table.
@@ -22,7 +25,7 @@ func Update(table string) *UpdateBuilder {
 		table:        table,
 		sets:         []setClause{},
 		where:        []whereClause{},
-		returning:    []string{},
+		returning:    []columnRef{},
 	}
 }
 
@@ -33,7 +36,7 @@ func UpdateWithAllowlist(table string, allowedColumns ...string) *UpdateBuilder
 		table:        table,
 		sets:         []setClause{},
 		where:        []whereClause{},
-		returning:    []string{},
+		returning:    []columnRef{},
 	}
 }
 
@@ -68,43 +71,69 @@ func (u *UpdateBuilder) WhereIn(column string, values ...any) *UpdateBuilder {
 	if len(values) == 0 {
 		return u
 	}
-	placeholders := make([]string, len(values))
-	for i := range values {
-		placeholders[i] = "?"
-	}
-	condition := fmt.Sprintf("%s IN (%s)", column, strings.Join(placeholders, ", "))
-	u.where = append(u.where, whereClause{condition: condition, args: values, isOr: false})
+	u.where = append(u.where, newInClause(column, values, false, &u.arrayThreshold))
+	return u
+}
+
+// From adds a table to the FROM clause: UPDATE t SET ... FROM table WHERE condition.
+// The join condition is ANDed with the WHERE conditions and may be empty.
+// Use ? as placeholder for arguments.
+func (u *UpdateBuilder) From(table, condition string, args ...any) *UpdateBuilder {
+	u.from = append(u.from, join{table: table, condition: condition, args: args})
 	return u
 }
 
 // Returning specifies columns to return after update.
 func (u *UpdateBuilder) Returning(columns ...string) *UpdateBuilder {
-	u.returning = append(u.returning, columns...)
+	u.returning = append(u.returning, columnRefs(columns)...)
+	return u
+}
+
+// ReturningRaw adds expressions to return after update.
+func (u *UpdateBuilder) ReturningRaw(exprs ...Raw) *UpdateBuilder {
+	u.returning = append(u.returning, rawColumnRefs(exprs)...)
 	return u
 }
 
+// validate implements Builder.
+func (u *UpdateBuilder) validate() error {
+	return u.validateUpdate()
+}
+
 // validateUpdate checks that the update has valid table and SET clause.
 func (u *UpdateBuilder) validateUpdate() error {
+	if u.structErr != nil {
+		return u.structErr
+	}
+	if err := u.with.validate(); err != nil {
+		return err
+	}
 	if err := validateTableName(u.table); err != nil {
 		return err
 	}
+	if err := validateJoinTables(u.from, "from"); err != nil {
+		return err
+	}
+	if err := validateConditionClauses(u.where); err != nil {
+		return err
+	}
 	if len(u.sets) == 0 {
 		return fmt.Errorf("no columns specified for update")
 	}
 	for _, s := range u.sets {
+		if s.err != nil {
+			return s.err
+		}
 		if err := u.validateColumnName(s.column); err != nil {
 			return err
 		}
-	}
-
-	// Validate RETURNING columns
-	for _, col := range u.returning {
-		if err := u.validateColumnName(col); err != nil {
-			return fmt.Errorf("invalid returning column: %q", col)
+		// SET targets are columns of the updated table and cannot be qualified.
+		if _, err := parseIdentifier(s.column, 1); err != nil {
+			return fmt.Errorf("invalid set column: %s", s.column)
 		}
 	}
 
-	return nil
+	return validateReturningColumns(u.QueryBuilder, u.returning)
 }
 
 // buildSetClause generates the SET portion and returns args and next arg index.
@@ -112,35 +141,24 @@ func (u *UpdateBuilder) buildSetClause(argIndex int) (string, []any, int) {
 	args := make([]any, 0, len(u.sets))
 	setParts := make([]string, len(u.sets))
 	for i, s := range u.sets {
-		setParts[i] = fmt.Sprintf("%s = $%d", s.column, argIndex)
+		if s.expr != "" {
+			var expr string
+			expr, argIndex = replacePlaceholders(s.expr, argIndex)
+			setParts[i] = quoteIdent(s.column) + " = " + expr
+			args = append(args, s.args...)
+			continue
+		}
+		setParts[i] = fmt.Sprintf("%s = $%d", quoteIdent(s.column), argIndex)
 		args = append(args, s.value)
 		argIndex++
 	}
 	return strings.Join(setParts, ", "), args, argIndex
 }
 
-// buildWhereClause generates the WHERE portion and returns args and next arg index.
+// buildWhereClause generates the WHERE portion, including FROM join conditions,
+// and returns args and next arg index.
 func (u *UpdateBuilder) buildWhereClause(argIndex int) (string, []any, int) {
-	if len(u.where) == 0 {
-		return "", nil, argIndex
-	}
-	var sb strings.Builder
-	var args []any
-	sb.WriteString(" WHERE ")
-	for i, w := range u.where {
-		if i > 0 {
-			if w.isOr {
-				sb.WriteString(" OR ")
-			} else {
-				sb.WriteString(" AND ")
-			}
-		}
-		condition, newIndex := replacePlaceholders(w.condition, argIndex)
-		sb.WriteString(condition)
-		args = append(args, w.args...)
-		argIndex = newIndex
-	}
-	return sb.String(), args, argIndex
+	return buildJoinedWhere(u.from, u.where, argIndex)
 }
 
 // Build generates the SQL query and returns it with the arguments.
@@ -148,28 +166,39 @@ func (u *UpdateBuilder) Build() (string, []any, error) {
 	if err := u.validateUpdate(); err != nil {
 		return "", nil, err
 	}
+	sql, args, _ := u.build(1)
+	return sql, args, nil
+}
 
+// build generates the SQL with placeholders numbered from argIndex,
+// returning the arguments and the next arg index.
+// The builder must have been validated.
+func (u *UpdateBuilder) build(argIndex int) (string, []any, int) {
 	var sb strings.Builder
+	withClause, withArgs, argIndex := u.with.build(argIndex)
+	sb.WriteString(withClause)
 	sb.WriteString("UPDATE ")
-	sb.WriteString(u.table)
+	sb.WriteString(quoteIdent(u.table))
 	sb.WriteString(" SET ")
 
-	setClause, setArgs, argIndex := u.buildSetClause(1)
+	setClause, setArgs, argIndex := u.buildSetClause(argIndex)
 	sb.WriteString(setClause)
+	sb.WriteString(buildJoinTableList(u.from, "FROM"))
 
-	whereClause, whereArgs, _ := u.buildWhereClause(argIndex)
+	whereClause, whereArgs, argIndex := u.buildWhereClause(argIndex)
 	sb.WriteString(whereClause)
 
-	args := make([]any, 0, len(setArgs)+len(whereArgs))
+	args := make([]any, 0, len(withArgs)+len(setArgs)+len(whereArgs))
+	args = append(args, withArgs...)
 	args = append(args, setArgs...)
 	args = append(args, whereArgs...)
 
 	if len(u.returning) > 0 {
 		sb.WriteString(" RETURNING ")
-		sb.WriteString(strings.Join(u.returning, ", "))
+		sb.WriteString(joinColumnRefs(u.returning))
 	}
 
-	return sb.String(), args, nil
+	return sb.String(), args, argIndex
 }
 
 // MustBuild generates the SQL query and panics on error.